@@ -0,0 +1,156 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+
+	"shin/internal/store"
+)
+
+// runBench 生成一个大库并统计详情页和重要订阅查询的耗时
+// 用法: shin bench [-db path] [-items 1000000] [-per-post 1000] [-feeds 200] [-runs 200]
+func runBench(args []string) error {
+	fs := flag.NewFlagSet("bench", flag.ExitOnError)
+	dsn := fs.String("db", filepath.Join(os.TempDir(), "shin_bench.db"), "benchmark database, reused when it already has items")
+	items := fs.Int("items", 1000000, "number of post items to generate")
+	perPost := fs.Int("per-post", 1000, "items per post")
+	feeds := fs.Int("feeds", 200, "number of distinct feeds")
+	runs := fs.Int("runs", 200, "queries per measurement")
+	fs.Parse(args)
+	if *runs < 1 || *perPost < 1 || *feeds < 1 {
+		return fmt.Errorf("runs, per-post and feeds must be positive")
+	}
+
+	st, err := store.Open(*dsn)
+	if err != nil {
+		return err
+	}
+	defer st.Close()
+
+	existing, err := countItems(st)
+	if err != nil {
+		return err
+	}
+	if existing < *items {
+		start := time.Now()
+		if err := seedBench(st, *items-existing, *perPost, *feeds); err != nil {
+			return err
+		}
+		fmt.Printf("seeded %d items in %s\n", *items-existing, time.Since(start).Round(time.Millisecond))
+	}
+	total, err := countItems(st)
+	if err != nil {
+		return err
+	}
+	posts, _, err := st.ListPosts(store.Page{Number: 1, Size: 100})
+	if err != nil {
+		return err
+	}
+	if len(posts) == 0 {
+		return fmt.Errorf("no posts in %s", *dsn)
+	}
+	fmt.Printf("database %s: %d items\n", *dsn, total)
+
+	// 详情页：摘要、条目和笔记
+	report("detail", *runs, func(i int) error {
+		post, err := st.GetPost(posts[i%len(posts)].ID)
+		if err != nil {
+			return err
+		}
+		postItems, err := st.PostItems(post.ID)
+		if err != nil {
+			return err
+		}
+		itemIDs := make([]string, len(postItems))
+		for j, item := range postItems {
+			itemIDs[j] = item.ID
+		}
+		_, err = st.NotesByItemIDs(itemIDs)
+		return err
+	})
+
+	important := []string{benchFeedTitle(0), benchFeedTitle(1), benchFeedTitle(2)}
+	report("important feeds, page 1", *runs, func(int) error {
+		_, _, err := st.ItemsByFeeds(important, store.Page{Number: 1, Size: 20})
+		return err
+	})
+	report("important feeds, page 50", *runs, func(int) error {
+		_, _, err := st.ItemsByFeeds(important, store.Page{Number: 50, Size: 20})
+		return err
+	})
+	return nil
+}
+
+func countItems(st store.Store) (int, error) {
+	feeds, err := st.FeedCounts()
+	if err != nil {
+		return 0, err
+	}
+	total := 0
+	for _, feed := range feeds {
+		total += feed.ItemCount
+	}
+	return total, nil
+}
+
+func benchFeedTitle(n int) string {
+	return fmt.Sprintf("Bench Feed %03d", n)
+}
+
+// seedBench 按每期 perPost 条写入条目并切分成期
+func seedBench(st store.Store, count, perPost, feeds int) error {
+	for written := 0; written < count; {
+		batch := make([]store.PostItem, 0, perPost)
+		for len(batch) < perPost && written < count {
+			id := store.NewID()
+			content, _ := json.Marshal(store.PostItemContent{
+				CnTitle: "测试标题 " + strconv.Itoa(written),
+				Title:   "Bench title " + strconv.Itoa(written),
+				Link:    "https://example.com/" + id,
+			})
+			batch = append(batch, store.PostItem{
+				ID:        id,
+				FeedTitle: benchFeedTitle(rand.Intn(feeds)),
+				Content:   string(content),
+			})
+			written++
+		}
+		if err := st.InsertPostItems(batch); err != nil {
+			return err
+		}
+		if _, err := st.CutPost(store.NewID(), "Bench post"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func report(name string, runs int, query func(i int) error) {
+	durations := make([]time.Duration, 0, runs)
+	for i := 0; i < runs; i++ {
+		start := time.Now()
+		if err := query(i); err != nil {
+			fmt.Printf("%-26s error: %v\n", name, err)
+			return
+		}
+		durations = append(durations, time.Since(start))
+	}
+	sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+
+	var sum time.Duration
+	for _, d := range durations {
+		sum += d
+	}
+	fmt.Printf("%-26s avg %-10s p50 %-10s p95 %-10s max %s\n", name,
+		(sum / time.Duration(len(durations))).Round(time.Microsecond),
+		durations[len(durations)/2].Round(time.Microsecond),
+		durations[len(durations)*95/100].Round(time.Microsecond),
+		durations[len(durations)-1].Round(time.Microsecond))
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"shin/internal/backup"
+	"shin/internal/store"
+)
+
+// runExport 把全部数据导出为 JSONL，可同时把订阅导出为 OPML
+// 用法: shin export [-o shin.jsonl] [-opml subscriptions.opml]
+func runExport(args []string) error {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	output := fs.String("o", "", "output file, stdout when empty")
+	opmlPath := fs.String("opml", "", "also write subscriptions as OPML to this file")
+	fs.Parse(args)
+
+	st, err := store.Open(DB_PATH)
+	if err != nil {
+		return err
+	}
+	defer st.Close()
+
+	var w io.Writer = os.Stdout
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	counts, err := backup.Export(st, w)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(os.Stderr, "exported", counts)
+
+	if *opmlPath != "" {
+		feeds, err := st.ListFeeds()
+		if err != nil {
+			return err
+		}
+		f, err := os.Create(*opmlPath)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		if err := backup.WriteOPML(f, feeds); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "exported %d subscriptions to %s\n", len(feeds), *opmlPath)
+	}
+	return nil
+}
+
+// runImport 导入 shin export 的输出，可以导入到另一种数据库；-opml 时导入 OPML 订阅列表
+// 用法: shin import [-opml] [shin.jsonl]，不指定文件时读取标准输入
+func runImport(args []string) error {
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	isOPML := fs.Bool("opml", false, "the input is an OPML subscription list")
+	fs.Parse(args)
+
+	st, err := store.Open(DB_PATH)
+	if err != nil {
+		return err
+	}
+	defer st.Close()
+
+	var r io.Reader = os.Stdin
+	if fs.NArg() > 0 {
+		f, err := os.Open(fs.Arg(0))
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+	if *isOPML {
+		added, total, err := backup.ImportOPML(st, r)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "imported %d of %d subscriptions\n", added, total)
+		return nil
+	}
+	counts, err := backup.Import(st, r)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(os.Stderr, "imported", counts)
+	return nil
+}
@@ -0,0 +1,264 @@
+// Package backup 把摘要、条目、已读状态、保存记录、订阅和规则导出为带版本号的 JSONL，并能导入到任意 Store
+package backup
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"shin/internal/ingest"
+	"shin/internal/store"
+)
+
+// Version 是导出格式的版本，导入时拒绝更高的版本
+const Version = 1
+
+const batchSize = 500
+
+// ErrInvalidExport 表示导入的文件格式不对或版本不支持
+var ErrInvalidExport = errors.New("invalid export")
+
+// Record 是导出文件中的一行，第一行是 meta
+type Record struct {
+	Type       string `json:"type"` // meta, post, item, feed, rewrite_rules, tag_rules
+	Version    int    `json:"version,omitempty"`
+	ExportedAt int64  `json:"exported_at,omitempty"`
+
+	Post *store.Post     `json:"post,omitempty"`
+	Item *store.PostItem `json:"item,omitempty"`
+	Feed *store.Feed     `json:"feed,omitempty"`
+	// 条目保存到各目标的外部 ID，旧的 memos 链接在 item.memo_id
+	Saved map[string]string `json:"saved,omitempty"`
+
+	RewriteRules []ingest.RewriteRule `json:"rewrite_rules,omitempty"`
+	TagRules     []ingest.TagRule     `json:"tag_rules,omitempty"`
+}
+
+// Counts 是导出或导入的数量
+type Counts struct {
+	Posts int `json:"posts"`
+	Items int `json:"items"`
+	Notes int `json:"notes"`
+	Saved int `json:"saved"`
+	Feeds int `json:"feeds"`
+	Rules int `json:"rules"`
+}
+
+func (c Counts) String() string {
+	return fmt.Sprintf("%d posts, %d items, %d notes, %d saved, %d feeds, %d rules", c.Posts, c.Items, c.Notes, c.Saved, c.Feeds, c.Rules)
+}
+
+// Export 把全部数据写为 JSONL
+func Export(st store.Store, w io.Writer) (Counts, error) {
+	var counts Counts
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+	if err := enc.Encode(Record{Type: "meta", Version: Version, ExportedAt: time.Now().Unix()}); err != nil {
+		return counts, err
+	}
+
+	page := store.Page{Number: 1, Size: batchSize}
+	for {
+		posts, info, err := st.ListPosts(page)
+		if err != nil {
+			return counts, err
+		}
+		for i := range posts {
+			if err := enc.Encode(Record{Type: "post", Post: &posts[i]}); err != nil {
+				return counts, err
+			}
+			counts.Posts++
+		}
+		if !info.HasMore {
+			break
+		}
+		if page.Cursor, err = store.DecodeCursor(info.NextCursor); err != nil {
+			return counts, err
+		}
+	}
+
+	page = store.Page{Number: 1, Size: batchSize}
+	for {
+		items, info, err := st.ListItems(page)
+		if err != nil {
+			return counts, err
+		}
+		itemIDs := make([]string, len(items))
+		for i, item := range items {
+			itemIDs[i] = item.ID
+		}
+		notes, err := st.NotesByItemIDs(itemIDs)
+		if err != nil {
+			return counts, err
+		}
+		for i := range items {
+			item := &items[i]
+			item.Notes = notes[item.ID]
+			record := Record{Type: "item", Item: item}
+			for _, sink := range item.Saved {
+				externalID, err := st.GetSavedID(item.ID, sink)
+				if err != nil {
+					return counts, err
+				}
+				if record.Saved == nil {
+					record.Saved = map[string]string{}
+				}
+				record.Saved[sink] = externalID
+			}
+			if err := enc.Encode(record); err != nil {
+				return counts, err
+			}
+			counts.Items++
+			counts.Notes += len(item.Notes)
+			counts.Saved += len(record.Saved)
+		}
+		if !info.HasMore {
+			break
+		}
+		if page.Cursor, err = store.DecodeCursor(info.NextCursor); err != nil {
+			return counts, err
+		}
+	}
+
+	// 已删除的上游订阅也导出，导入后同步时同样跳过
+	feeds, err := st.ListFeeds()
+	if err != nil {
+		return counts, err
+	}
+	deleted, err := st.ListDeletedFeeds()
+	if err != nil {
+		return counts, err
+	}
+	feeds = append(feeds, deleted...)
+	for i := range feeds {
+		if err := enc.Encode(Record{Type: "feed", Feed: &feeds[i]}); err != nil {
+			return counts, err
+		}
+		counts.Feeds++
+	}
+
+	// 只导出保存在库里的改写规则，WITH_CONTENT_FEEDS 生成的规则由环境变量提供
+	rewriteRules := []ingest.RewriteRule{}
+	if value, err := st.GetKeyValue(ingest.REWRITE_RULES_KEY); err == nil && value != "" {
+		if err := json.Unmarshal([]byte(value), &rewriteRules); err != nil {
+			return counts, fmt.Errorf("invalid rewrite rules: %w", err)
+		}
+	}
+	tagRules := ingest.GetTagRules(st)
+	if err := enc.Encode(Record{Type: "rewrite_rules", RewriteRules: rewriteRules}); err != nil {
+		return counts, err
+	}
+	if err := enc.Encode(Record{Type: "tag_rules", TagRules: tagRules}); err != nil {
+		return counts, err
+	}
+	counts.Rules = len(rewriteRules) + len(tagRules)
+
+	return counts, bw.Flush()
+}
+
+// Import 读取 Export 写出的 JSONL。ID 和时间保持不变，已存在的摘要覆盖、条目只更新状态、订阅按 URL 跳过，可以重复导入
+func Import(st store.Store, r io.Reader) (Counts, error) {
+	var counts Counts
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 1024*1024), 64*1024*1024)
+
+	var items []store.PostItem
+	saved := map[string]map[string]string{}
+	flush := func() error {
+		if len(items) == 0 {
+			return nil
+		}
+		if err := st.ImportPostItems(items); err != nil {
+			return err
+		}
+		for itemID, sinks := range saved {
+			for sink, externalID := range sinks {
+				if err := st.InsertSaved(itemID, sink, externalID); err != nil {
+					return err
+				}
+				counts.Saved++
+			}
+		}
+		items = nil
+		saved = map[string]map[string]string{}
+		return nil
+	}
+
+	line, version := 0, 0
+	for scanner.Scan() {
+		line++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var record Record
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return counts, fmt.Errorf("%w: line %d: %v", ErrInvalidExport, line, err)
+		}
+		if version == 0 {
+			if record.Type != "meta" {
+				return counts, fmt.Errorf("%w: line %d: missing meta record", ErrInvalidExport, line)
+			}
+			if record.Version < 1 || record.Version > Version {
+				return counts, fmt.Errorf("%w: unsupported version %d", ErrInvalidExport, record.Version)
+			}
+			version = record.Version
+			continue
+		}
+
+		switch record.Type {
+		case "post":
+			if record.Post == nil {
+				return counts, fmt.Errorf("%w: line %d: post record without post", ErrInvalidExport, line)
+			}
+			if err := st.ImportPost(*record.Post); err != nil {
+				return counts, fmt.Errorf("line %d: %w", line, err)
+			}
+			counts.Posts++
+		case "item":
+			if record.Item == nil {
+				return counts, fmt.Errorf("%w: line %d: item record without item", ErrInvalidExport, line)
+			}
+			items = append(items, *record.Item)
+			if len(record.Saved) > 0 {
+				saved[record.Item.ID] = record.Saved
+			}
+			counts.Items++
+			counts.Notes += len(record.Item.Notes)
+			if len(items) >= batchSize {
+				if err := flush(); err != nil {
+					return counts, fmt.Errorf("line %d: %w", line, err)
+				}
+			}
+		case "feed":
+			if record.Feed == nil {
+				return counts, fmt.Errorf("%w: line %d: feed record without feed", ErrInvalidExport, line)
+			}
+			if _, err := st.AddFeed(*record.Feed); err != nil {
+				return counts, fmt.Errorf("line %d: %w", line, err)
+			}
+			counts.Feeds++
+		case "rewrite_rules":
+			if err := ingest.SaveRewriteRules(st, record.RewriteRules); err != nil {
+				return counts, fmt.Errorf("line %d: %w", line, err)
+			}
+			counts.Rules += len(record.RewriteRules)
+		case "tag_rules":
+			if err := ingest.SaveTagRules(st, record.TagRules); err != nil {
+				return counts, fmt.Errorf("line %d: %w", line, err)
+			}
+			counts.Rules += len(record.TagRules)
+		default:
+			return counts, fmt.Errorf("%w: line %d: unknown record type %q", ErrInvalidExport, line, record.Type)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return counts, err
+	}
+	if version == 0 {
+		return counts, fmt.Errorf("%w: empty file", ErrInvalidExport)
+	}
+	return counts, flush()
+}
@@ -0,0 +1,144 @@
+package backup
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"shin/internal/ingest"
+	"shin/internal/store"
+)
+
+// testItem 构造一个未归入摘要的条目
+func testItem(title string) store.PostItem {
+	content, _ := json.Marshal(store.PostItemContent{CnTitle: title, Title: title, Link: "https://example.com/" + title})
+	return store.PostItem{ID: store.NewID(), FeedTitle: "Feed", Content: string(content), GUID: title}
+}
+
+// exportLines 导出 st 并去掉带导出时间的 meta 行
+func exportLines(t *testing.T, st store.Store) ([]string, Counts) {
+	t.Helper()
+	var buf bytes.Buffer
+	counts, err := Export(st, &buf)
+	if err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	return lines[1:], counts
+}
+
+func TestExportImportRoundTrip(t *testing.T) {
+	eachStore(t, func(t *testing.T, open func(t *testing.T) *store.SQLStore) {
+		src := open(t)
+		saved, noted, later := testItem("saved"), testItem("noted"), testItem("later")
+		saved.MemoID, saved.Starred = "memos/1", true
+		if err := src.InsertPostItems([]store.PostItem{saved, noted}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := src.CutPost("post-1", "Post 1"); err != nil {
+			t.Fatal(err)
+		}
+		if err := src.UpdatePostSummary("post-1", "summary", []string{"go"}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := src.MarkPostRead("post-1"); err != nil {
+			t.Fatal(err)
+		}
+		if err := src.InsertPostItems([]store.PostItem{later}); err != nil {
+			t.Fatal(err)
+		}
+		if err := src.InsertSaved(saved.ID, "notion", "page-1"); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := src.InsertNote(noted.ID, "a note"); err != nil {
+			t.Fatal(err)
+		}
+		if err := src.AddItemTag(noted.ID, "later"); err != nil {
+			t.Fatal(err)
+		}
+
+		deleted := store.Feed{ID: store.NewID(), URL: "https://example.com/deleted.xml", Title: "Deleted", Enabled: true, UpstreamID: "feed/1"}
+		for _, feed := range []store.Feed{
+			{URL: "https://example.com/a.xml", Title: "A", Category: "News", Enabled: true},
+			{URL: "https://example.com/b.xml", Title: "B", Enabled: false, Translate: store.TranslateNone},
+			deleted,
+		} {
+			if _, err := src.AddFeed(feed); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := src.DeleteFeed(deleted.ID); err != nil {
+			t.Fatal(err)
+		}
+		if err := ingest.SaveRewriteRules(src, []ingest.RewriteRule{{Feed: "HN", Preset: "hn"}}); err != nil {
+			t.Fatal(err)
+		}
+		if err := ingest.SaveTagRules(src, []ingest.TagRule{{Tag: "go", Keyword: "golang"}}); err != nil {
+			t.Fatal(err)
+		}
+
+		want, exported := exportLines(t, src)
+		if exported != (Counts{Posts: 1, Items: 3, Notes: 1, Saved: 1, Feeds: 3, Rules: 2}) {
+			t.Errorf("Export() counts = %v", exported)
+		}
+
+		var buf bytes.Buffer
+		if _, err := Export(src, &buf); err != nil {
+			t.Fatal(err)
+		}
+		dst := open(t)
+		imported, err := Import(dst, bytes.NewReader(buf.Bytes()))
+		if err != nil {
+			t.Fatalf("Import: %v", err)
+		}
+		if imported != exported {
+			t.Errorf("Import() counts = %v, want %v", imported, exported)
+		}
+
+		// 导入后再导出，内容和原库一致
+		got, _ := exportLines(t, dst)
+		if strings.Join(got, "\n") != strings.Join(want, "\n") {
+			t.Errorf("export after import:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+		}
+		if feeds, _ := dst.ListDeletedFeeds(); len(feeds) != 1 || feeds[0].URL != deleted.URL {
+			t.Errorf("ListDeletedFeeds() after import = %+v", feeds)
+		}
+
+		// 重复导入不重复写入，导出文件里没有 memo_id 时保留已有的
+		if _, err := Import(dst, bytes.NewReader(buf.Bytes())); err != nil {
+			t.Fatalf("Import again: %v", err)
+		}
+		item, err := dst.GetPostItem(saved.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		item.MemoID = ""
+		record, _ := json.Marshal(Record{Type: "item", Item: item})
+		if _, err := Import(dst, strings.NewReader(`{"type":"meta","version":1}`+"\n"+string(record)+"\n")); err != nil {
+			t.Fatal(err)
+		}
+		if got, _ = exportLines(t, dst); strings.Join(got, "\n") != strings.Join(want, "\n") {
+			t.Errorf("export after importing again:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+		}
+		if item, err := dst.GetPostItem(saved.ID); err != nil || item.MemoID != "memos/1" {
+			t.Errorf("memo_id after import = %+v, %v", item, err)
+		}
+	})
+}
+
+func TestImportRejectsInvalidExport(t *testing.T) {
+	st := openTestStore(t)
+	for _, input := range []string{
+		"",
+		`{"type":"post","post":{"id":"p"}}`,
+		`{"type":"meta","version":99}`,
+		`{"type":"meta","version":1}` + "\n" + `{"type":"item"}`,
+		`{"type":"meta","version":1}` + "\n" + `{"type":"unknown"}`,
+		`{"type":"meta","version":1}` + "\n" + `not json`,
+	} {
+		if _, err := Import(st, strings.NewReader(input)); err == nil || !strings.Contains(err.Error(), ErrInvalidExport.Error()) {
+			t.Errorf("Import(%q) = %v, want ErrInvalidExport", input, err)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package backup
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html/charset"
+
+	"shin/internal/store"
+)
+
+type opml struct {
+	XMLName xml.Name  `xml:"opml"`
+	Version string    `xml:"version,attr"`
+	Title   string    `xml:"head>title"`
+	Created string    `xml:"head>dateCreated"`
+	Body    []outline `xml:"body>outline"`
+}
+
+type outline struct {
+	Text     string    `xml:"text,attr"`
+	Title    string    `xml:"title,attr,omitempty"`
+	Type     string    `xml:"type,attr,omitempty"`
+	XMLURL   string    `xml:"xmlUrl,attr,omitempty"`
+	HTMLURL  string    `xml:"htmlUrl,attr,omitempty"`
+	Outlines []outline `xml:"outline"`
+}
+
+// WriteOPML 把订阅写为 OPML，按分类分组，feeds 应已按分类排序
+func WriteOPML(w io.Writer, feeds []store.Feed) error {
+	doc := opml{Version: "2.0", Title: "Shin subscriptions", Created: time.Now().UTC().Format(time.RFC1123Z)}
+
+	categories := map[string]int{}
+	for _, feed := range feeds {
+		entry := outline{Text: feed.Title, Title: feed.Title, Type: "rss", XMLURL: feed.URL}
+		if feed.Category == "" {
+			doc.Body = append(doc.Body, entry)
+			continue
+		}
+		i, ok := categories[feed.Category]
+		if !ok {
+			i = len(doc.Body)
+			categories[feed.Category] = i
+			doc.Body = append(doc.Body, outline{Text: feed.Category, Title: feed.Category})
+		}
+		doc.Body[i].Outlines = append(doc.Body[i].Outlines, entry)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// ReadOPML 读取 OPML 中的订阅，嵌套的分类取最外层的名称
+func ReadOPML(r io.Reader) ([]store.Feed, error) {
+	var doc opml
+	decoder := xml.NewDecoder(r)
+	decoder.CharsetReader = charset.NewReaderLabel
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("%w: opml: %v", ErrInvalidExport, err)
+	}
+
+	var feeds []store.Feed
+	var walk func(outlines []outline, category string)
+	walk = func(outlines []outline, category string) {
+		for _, o := range outlines {
+			if o.XMLURL != "" {
+				title := o.Title
+				if title == "" {
+					title = o.Text
+				}
+				feeds = append(feeds, store.Feed{
+					URL:       strings.TrimSpace(o.XMLURL),
+					Title:     title,
+					Category:  category,
+					Enabled:   true,
+					Translate: store.TranslateTitle,
+				})
+				continue
+			}
+			name := category
+			if name == "" {
+				name = o.Text
+				if name == "" {
+					name = o.Title
+				}
+			}
+			walk(o.Outlines, name)
+		}
+	}
+	walk(doc.Body, "")
+	return feeds, nil
+}
+
+// ImportOPML 把 OPML 中的订阅加入 shin_feed，已有的 URL 跳过，返回新增数量和总数
+func ImportOPML(st store.Store, r io.Reader) (added, total int, err error) {
+	feeds, err := ReadOPML(r)
+	if err != nil {
+		return 0, 0, err
+	}
+	for _, feed := range feeds {
+		created, err := st.AddFeed(feed)
+		if err != nil {
+			return added, len(feeds), err
+		}
+		if created {
+			added++
+		}
+	}
+	return added, len(feeds), nil
+}
@@ -0,0 +1,149 @@
+package backup
+
+import (
+	"bytes"
+	"database/sql"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"shin/internal/store"
+)
+
+// testStores 让同一个测试中多次打开的库互不相同
+var testStores int
+
+func testStoreName(t *testing.T) string {
+	testStores++
+	name := strings.NewReplacer("/", "_", " ", "_", "#", "_").Replace(t.Name())
+	return fmt.Sprintf("%s_%d", strings.ToLower(name), testStores)
+}
+
+// openTestStore 打开一个新的内存 SQLite 库
+func openTestStore(t *testing.T) *store.SQLStore {
+	t.Helper()
+	s, err := store.OpenSQLite(fmt.Sprintf("file:%s?mode=memory&cache=shared", testStoreName(t)))
+	if err != nil {
+		t.Fatalf("OpenSQLite: %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+// openPostgresTestStore 在 SHIN_TEST_PG_DSN 指向的库中建一个新的 schema，测试结束后删除
+func openPostgresTestStore(t *testing.T) *store.SQLStore {
+	t.Helper()
+	dsn := os.Getenv("SHIN_TEST_PG_DSN")
+	if dsn == "" {
+		t.Skip("SHIN_TEST_PG_DSN is not set")
+	}
+	schema := "shin_test_" + testStoreName(t)
+	admin, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { admin.Close() })
+	if _, err := admin.Exec(`DROP SCHEMA IF EXISTS ` + schema + ` CASCADE; CREATE SCHEMA ` + schema); err != nil {
+		t.Fatalf("create schema: %v", err)
+	}
+	t.Cleanup(func() { admin.Exec(`DROP SCHEMA IF EXISTS ` + schema + ` CASCADE`) })
+
+	if strings.Contains(dsn, "://") {
+		sep := "?"
+		if strings.Contains(dsn, "?") {
+			sep = "&"
+		}
+		dsn += sep + "search_path=" + schema
+	} else {
+		dsn += " search_path=" + schema
+	}
+	s, err := store.OpenPostgres(dsn)
+	if err != nil {
+		t.Fatalf("OpenPostgres: %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+// eachStore 在 SQLite 和 PostgreSQL 上各跑一遍 fn，open 每次打开一个新的空库
+func eachStore(t *testing.T, fn func(t *testing.T, open func(t *testing.T) *store.SQLStore)) {
+	t.Run("sqlite", func(t *testing.T) { fn(t, openTestStore) })
+	t.Run("postgres", func(t *testing.T) { fn(t, openPostgresTestStore) })
+}
+
+func TestReadOPML(t *testing.T) {
+	doc := `<?xml version="1.0" encoding="UTF-8"?>
+<opml version="2.0"><head><title>Feeds</title></head><body>
+	<outline text="Top" xmlUrl=" https://example.com/top.xml "/>
+	<outline text="Tech">
+		<outline text="Go Blog" title="The Go Blog" xmlUrl="https://go.dev/blog/feed.atom"/>
+		<outline text="Nested"><outline text="Deep" xmlUrl="https://example.com/deep.xml"/></outline>
+	</outline>
+</body></opml>`
+	feeds, err := ReadOPML(strings.NewReader(doc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []store.Feed{
+		{URL: "https://example.com/top.xml", Title: "Top"},
+		{URL: "https://go.dev/blog/feed.atom", Title: "The Go Blog", Category: "Tech"},
+		{URL: "https://example.com/deep.xml", Title: "Deep", Category: "Tech"},
+	}
+	if len(feeds) != len(want) {
+		t.Fatalf("ReadOPML() = %+v", feeds)
+	}
+	for i, w := range want {
+		got := feeds[i]
+		if got.URL != w.URL || got.Title != w.Title || got.Category != w.Category || !got.Enabled || got.Translate != store.TranslateTitle {
+			t.Errorf("feed %d = %+v, want %+v", i, got, w)
+		}
+	}
+
+	if _, err := ReadOPML(strings.NewReader("not xml")); err == nil {
+		t.Error("ReadOPML accepted invalid input")
+	}
+}
+
+func TestOPMLRoundTrip(t *testing.T) {
+	eachStore(t, func(t *testing.T, open func(t *testing.T) *store.SQLStore) {
+		s := open(t)
+		for _, feed := range []store.Feed{
+			{URL: "https://example.com/a.xml", Title: "A", Category: "News", Enabled: true},
+			{URL: "https://example.com/b.xml", Title: "B & Co", Category: "News", Enabled: true},
+			{URL: "https://example.com/c.xml", Title: "C", Enabled: true},
+		} {
+			if _, err := s.AddFeed(feed); err != nil {
+				t.Fatal(err)
+			}
+		}
+		feeds, err := s.ListFeeds()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var buf bytes.Buffer
+		if err := WriteOPML(&buf, feeds); err != nil {
+			t.Fatal(err)
+		}
+
+		dst := open(t)
+		added, total, err := ImportOPML(dst, bytes.NewReader(buf.Bytes()))
+		if err != nil || added != 3 || total != 3 {
+			t.Fatalf("ImportOPML() = %d, %d, %v", added, total, err)
+		}
+		imported, _ := dst.ListFeeds()
+		if len(imported) != 3 {
+			t.Fatalf("imported feeds = %+v", imported)
+		}
+		for i := range feeds {
+			if imported[i].URL != feeds[i].URL || imported[i].Title != feeds[i].Title || imported[i].Category != feeds[i].Category {
+				t.Errorf("feed %d = %+v, want %+v", i, imported[i], feeds[i])
+			}
+		}
+
+		// 已有的 URL 跳过
+		if added, total, err := ImportOPML(dst, bytes.NewReader(buf.Bytes())); err != nil || added != 0 || total != 3 {
+			t.Errorf("ImportOPML() again = %d, %d, %v", added, total, err)
+		}
+	})
+}
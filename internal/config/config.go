@@ -0,0 +1,17 @@
+// Package config 在导入时加载 data/.env。各包在初始化时读取环境变量，
+// 所以需要环境变量的包都要导入 config，保证 .env 先于它们加载。
+package config
+
+import (
+	"log"
+	"os"
+
+	"github.com/joho/godotenv"
+)
+
+// Logger 是所有包共用的日志
+var Logger = log.New(os.Stdout, "", log.LstdFlags)
+
+func init() {
+	_ = godotenv.Load("data/.env")
+}
@@ -0,0 +1,386 @@
+package ingest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"shin/internal/store"
+)
+
+const (
+	StateRead    = "user/-/state/com.google/read"
+	StateStarred = "user/-/state/com.google/starred"
+	editTagBatch = 100
+	// streamPageSize 是 stream/items/ids 每页的条目数
+	streamPageSize = 1000
+)
+
+// FreshRSS 通过 Google Reader API 读取 FreshRSS 中的订阅，也负责把已读和星标同步回去
+type FreshRSS struct {
+	AuthURL          string
+	ListURL          string
+	ContentURLPrefix string
+	FilteredLabel    string // 只拉取该分类下的订阅，空表示全部
+	APIURL           string
+	Client           *http.Client
+}
+
+// NewFreshRSS 按 FRESHRSS_* 环境变量创建
+func NewFreshRSS() *FreshRSS {
+	return &FreshRSS{
+		AuthURL:          os.Getenv("FRESHRSS_AUTH_URL"),
+		ListURL:          os.Getenv("FRESHRSS_LIST_SUBSCRIPTION_URL"),
+		ContentURLPrefix: os.Getenv("FRESHRSS_CONTENT_URL_PREFIX"),
+		FilteredLabel:    os.Getenv("FRESHRSS_FILTERED_LABEL"),
+		APIURL:           os.Getenv("FRESHRSS_API_URL"),
+		Client:           &http.Client{Timeout: 60 * time.Second},
+	}
+}
+
+func (s *FreshRSS) Auth() string {
+	response, err := s.Client.Get(s.AuthURL)
+	if err != nil {
+		logger.Println("Error during RSS auth:", err)
+		return ""
+	}
+	defer response.Body.Close()
+
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		logger.Println("Error reading response body:", err)
+		return ""
+	}
+
+	re := regexp.MustCompile(`SID=([^\n]+)`)
+	match := re.FindStringSubmatch(string(body))
+	if len(match) > 1 {
+		return match[1]
+	}
+	logger.Println("SID not found")
+	return ""
+}
+
+// listSubscriptions 返回 FreshRSS 中的全部订阅，不按分类过滤
+func (s *FreshRSS) listSubscriptions(authToken string) []map[string]interface{} {
+	var subs []map[string]interface{}
+	req, err := http.NewRequest("GET", s.ListURL, nil)
+	if err != nil {
+		logger.Println("Failed to create request:", err)
+		return subs
+	}
+	req.Header.Add("Authorization", fmt.Sprintf("GoogleLogin auth=%s", authToken))
+
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		logger.Println("Error during list subscription:", err)
+		return subs
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == 200 {
+		body, _ := io.ReadAll(resp.Body)
+		var data map[string]interface{}
+		if err := json.Unmarshal(body, &data); err == nil {
+			subscriptions, _ := data["subscriptions"].([]interface{})
+			for _, sub := range subscriptions {
+				if item, ok := sub.(map[string]interface{}); ok {
+					subs = append(subs, item)
+				}
+			}
+		} else {
+			logger.Println("Failed to parse JSON:", err)
+		}
+	}
+	return subs
+}
+
+// subscriptionLabels 返回订阅所属的分类
+func subscriptionLabels(sub map[string]interface{}) []string {
+	var labels []string
+	categories, _ := sub["categories"].([]interface{})
+	for _, category := range categories {
+		if m, ok := category.(map[string]interface{}); ok {
+			if label, _ := m["label"].(string); label != "" {
+				labels = append(labels, label)
+			}
+		}
+	}
+	return labels
+}
+
+func (s *FreshRSS) inFilteredLabel(sub map[string]interface{}) bool {
+	if s.FilteredLabel == "" {
+		return true
+	}
+	for _, label := range subscriptionLabels(sub) {
+		if label == s.FilteredLabel {
+			return true
+		}
+	}
+	return false
+}
+
+// Subscriptions 返回 FilteredLabel 分类下的订阅，未设置时返回全部
+func (s *FreshRSS) Subscriptions(authToken string) []map[string]interface{} {
+	var enSub []map[string]interface{}
+	for _, sub := range s.listSubscriptions(authToken) {
+		if s.inFilteredLabel(sub) {
+			enSub = append(enSub, sub)
+		}
+	}
+	return enSub
+}
+
+// SyncFeeds 把 FreshRSS 的全部订阅写入 shin_feed。新订阅只有在 FilteredLabel 分类下时才启用，
+// 已有的订阅只补上 FreshRSS 的订阅 ID，不覆盖在 Shin 中修改过的标题、分类和开关。
+// 在 Shin 中删除过的订阅只有 restore 为 true 时才重新加入
+func (s *FreshRSS) SyncFeeds(st store.Store, authToken string, restore bool) (int, error) {
+	subs := s.listSubscriptions(authToken)
+	if len(subs) == 0 {
+		return 0, fmt.Errorf("no subscriptions from FreshRSS")
+	}
+
+	deleted := make(map[string]bool)
+	if !restore {
+		feeds, err := st.ListDeletedFeeds()
+		if err != nil {
+			return 0, err
+		}
+		for _, feed := range feeds {
+			deleted[feed.URL] = true
+		}
+	}
+
+	added := 0
+	for _, sub := range subs {
+		id, _ := sub["id"].(string)
+		title, _ := sub["title"].(string)
+		feedURL, _ := sub["url"].(string)
+		if feedURL == "" {
+			feedURL = strings.TrimPrefix(id, "feed/")
+		}
+		var category string
+		if labels := subscriptionLabels(sub); len(labels) > 0 {
+			category = labels[0]
+		}
+		if deleted[feedURL] {
+			continue
+		}
+
+		created, err := st.AddFeed(store.Feed{
+			URL:        feedURL,
+			Title:      title,
+			Category:   category,
+			Enabled:    s.inFilteredLabel(sub),
+			Translate:  store.TranslateTitle,
+			UpstreamID: id,
+		})
+		if err != nil {
+			return added, err
+		}
+		if created {
+			added++
+		}
+	}
+	return added, nil
+}
+
+func (s *FreshRSS) FeedItems(authToken, feedID, ot string) []interface{} {
+	url := fmt.Sprintf("%s%s?ot=%s", s.ContentURLPrefix, feedID, ot)
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		logger.Println("Failed to create request:", err)
+		return nil
+	}
+	req.Header.Add("Authorization", fmt.Sprintf("GoogleLogin auth=%s", authToken))
+
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		logger.Println("Failed to fetch feed:", err)
+		return nil
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == 200 {
+		body, _ := io.ReadAll(resp.Body)
+		var data map[string]interface{}
+		if err := json.Unmarshal(body, &data); err == nil {
+			items, _ := data["items"].([]interface{})
+			return items
+		}
+	}
+	return nil
+}
+
+// APIBase 返回 Google Reader API 的根地址，例如 https://rss.example.com/api/greader.php，
+// 未设置 APIURL 时从 ContentURLPrefix 推出
+func (s *FreshRSS) APIBase() string {
+	if s.APIURL != "" {
+		return strings.TrimSuffix(s.APIURL, "/")
+	}
+	if i := strings.Index(s.ContentURLPrefix, "/reader/api/0/"); i >= 0 {
+		return s.ContentURLPrefix[:i]
+	}
+	return ""
+}
+
+func (s *FreshRSS) request(method, path, authToken string, form url.Values) ([]byte, error) {
+	base := s.APIBase()
+	if base == "" {
+		return nil, fmt.Errorf("FRESHRSS_API_URL is not configured")
+	}
+
+	var body io.Reader
+	if form != nil {
+		body = strings.NewReader(form.Encode())
+	}
+	req, err := http.NewRequest(method, base+path, body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Add("Authorization", fmt.Sprintf("GoogleLogin auth=%s", authToken))
+	if form != nil {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("freshrss: %d %s", resp.StatusCode, string(respBody))
+	}
+	return respBody, nil
+}
+
+// EditTag 调用 edit-tag 给上游条目添加或移除状态，写操作需要先取得 T token
+func (s *FreshRSS) EditTag(authToken string, upstreamIDs []string, add, remove string) error {
+	token, err := s.request("GET", "/reader/api/0/token", authToken, nil)
+	if err != nil {
+		return err
+	}
+
+	for start := 0; start < len(upstreamIDs); start += editTagBatch {
+		end := start + editTagBatch
+		if end > len(upstreamIDs) {
+			end = len(upstreamIDs)
+		}
+		form := url.Values{"i": upstreamIDs[start:end], "T": {strings.TrimSpace(string(token))}}
+		if add != "" {
+			form.Set("a", add)
+		}
+		if remove != "" {
+			form.Set("r", remove)
+		}
+		if _, err := s.request("POST", "/reader/api/0/edit-tag", authToken, form); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LongItemID 把 stream/items/ids 返回的十进制 ID 转为 stream/contents 中的长格式
+func LongItemID(id string) string {
+	if strings.HasPrefix(id, "tag:") {
+		return id
+	}
+	v, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return id
+	}
+	return fmt.Sprintf("tag:google.com,2005:reader/item/%016x", uint64(v))
+}
+
+// streamItemIDs 返回 stream 中最新的条目 ID，all 为 true 时用 continuation 翻完整个 stream
+func (s *FreshRSS) streamItemIDs(authToken, stream string, all bool) ([]string, error) {
+	var ids []string
+	continuation := ""
+	for {
+		path := "/reader/api/0/stream/items/ids?n=" + strconv.Itoa(streamPageSize) + "&s=" + url.QueryEscape(stream)
+		if continuation != "" {
+			path += "&c=" + url.QueryEscape(continuation)
+		}
+		body, err := s.request("GET", path, authToken, nil)
+		if err != nil {
+			return nil, err
+		}
+		var data struct {
+			ItemRefs []struct {
+				ID string `json:"id"`
+			} `json:"itemRefs"`
+			Continuation string `json:"continuation"`
+		}
+		if err := json.Unmarshal(body, &data); err != nil {
+			return nil, fmt.Errorf("failed to parse item ids: %w", err)
+		}
+		for _, ref := range data.ItemRefs {
+			ids = append(ids, LongItemID(ref.ID))
+		}
+		if !all || data.Continuation == "" || len(data.ItemRefs) == 0 {
+			return ids, nil
+		}
+		if data.Continuation == continuation {
+			return nil, fmt.Errorf("freshrss: continuation %s repeated", continuation)
+		}
+		continuation = data.Continuation
+	}
+}
+
+// PullState 拉取 FreshRSS 中最近的已读条目和全部星标条目，更新到本地。
+// 先重试推送失败的星标，本地还没同步上去的星标不会被上游的状态覆盖
+func (s *FreshRSS) PullState(st store.Store, authToken string) error {
+	if err := s.PushStars(st, authToken); err != nil {
+		logger.Println("PushStars:", err)
+	}
+	readIDs, err := s.streamItemIDs(authToken, StateRead, false)
+	if err != nil {
+		return err
+	}
+	// 星标列表必须完整，否则不在列表中的条目会被误取消星标
+	starredIDs, err := s.streamItemIDs(authToken, StateStarred, true)
+	if err != nil {
+		return err
+	}
+	return st.ApplyUpstreamState(readIDs, starredIDs)
+}
+
+// SetStarred 把星标推送到 FreshRSS，成功后记录为已同步
+func (s *FreshRSS) SetStarred(st store.Store, authToken string, upstreamIDs []string, starred bool) error {
+	if len(upstreamIDs) == 0 {
+		return nil
+	}
+	add, remove := StateStarred, ""
+	if !starred {
+		add, remove = "", StateStarred
+	}
+	if err := s.EditTag(authToken, upstreamIDs, add, remove); err != nil {
+		return err
+	}
+	return st.MarkStarSynced(upstreamIDs, starred)
+}
+
+// PushStars 推送本地还没同步到 FreshRSS 的星标变更
+func (s *FreshRSS) PushStars(st store.Store, authToken string) error {
+	starred, unstarred, err := st.UnsyncedStars()
+	if err != nil {
+		return err
+	}
+	if err := s.SetStarred(st, authToken, starred, true); err != nil {
+		return err
+	}
+	return s.SetStarred(st, authToken, unstarred, false)
+}
@@ -0,0 +1,282 @@
+package ingest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"shin/internal/store"
+)
+
+// fakeFreshRSS 模拟 FreshRSS 的 Google Reader API：登录、订阅列表、条目内容、状态 stream 和 edit-tag
+type fakeFreshRSS struct {
+	mu            sync.Mutex
+	subscriptions []map[string]interface{}
+	items         map[string][]map[string]interface{} // 订阅 ID -> 条目
+	streams       map[string][]string                 // 状态 -> 短格式条目 ID，新的在前
+	pageSize      int
+	editTags      []url.Values
+	failEditTag   bool
+}
+
+func newFakeFreshRSS(t *testing.T) (*fakeFreshRSS, *FreshRSS) {
+	t.Helper()
+	f := &fakeFreshRSS{
+		items:    map[string][]map[string]interface{}{},
+		streams:  map[string][]string{},
+		pageSize: 2,
+	}
+	server := httptest.NewServer(f)
+	t.Cleanup(server.Close)
+	api := server.URL + "/api/greader.php"
+	return f, &FreshRSS{
+		AuthURL:          api + "/accounts/ClientLogin?Email=u&Passwd=p",
+		ListURL:          api + "/reader/api/0/subscription/list?output=json",
+		ContentURLPrefix: api + "/reader/api/0/stream/contents/",
+		Client:           server.Client(),
+	}
+}
+
+func (f *fakeFreshRSS) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	path := strings.TrimPrefix(r.URL.Path, "/api/greader.php")
+	if path == "/accounts/ClientLogin" {
+		fmt.Fprint(w, "SID=u/sid\nLSID=\nAuth=u/sid\n")
+		return
+	}
+	if r.Header.Get("Authorization") != "GoogleLogin auth=u/sid" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	switch {
+	case path == "/reader/api/0/subscription/list":
+		json.NewEncoder(w).Encode(map[string]interface{}{"subscriptions": f.subscriptions})
+	case strings.HasPrefix(path, "/reader/api/0/stream/contents/"):
+		feedID := strings.TrimPrefix(path, "/reader/api/0/stream/contents/")
+		ot, _ := strconv.ParseInt(r.URL.Query().Get("ot"), 10, 64)
+		var items []map[string]interface{}
+		for _, item := range f.items[feedID] {
+			if published, _ := item["published"].(float64); int64(published) >= ot {
+				items = append(items, item)
+			}
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"items": items})
+	case path == "/reader/api/0/token":
+		fmt.Fprint(w, "T-token\n")
+	case path == "/reader/api/0/stream/items/ids":
+		ids := f.streams[r.URL.Query().Get("s")]
+		start, _ := strconv.Atoi(r.URL.Query().Get("c"))
+		n, _ := strconv.Atoi(r.URL.Query().Get("n"))
+		if n > f.pageSize {
+			n = f.pageSize
+		}
+		end := start + n
+		data := map[string]interface{}{}
+		if end < len(ids) {
+			data["continuation"] = strconv.Itoa(end)
+		} else {
+			end = len(ids)
+		}
+		var refs []map[string]string
+		for _, id := range ids[start:end] {
+			refs = append(refs, map[string]string{"id": id})
+		}
+		data["itemRefs"] = refs
+		json.NewEncoder(w).Encode(data)
+	case path == "/reader/api/0/edit-tag" && r.Method == http.MethodPost:
+		r.ParseForm()
+		if f.failEditTag || r.PostForm.Get("T") != "T-token" {
+			http.Error(w, "edit-tag failed", http.StatusInternalServerError)
+			return
+		}
+		f.editTags = append(f.editTags, r.PostForm)
+		fmt.Fprint(w, "OK")
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// openTestStore 打开测试独占的内存 SQLite 库
+func openTestStore(t *testing.T) *store.SQLStore {
+	t.Helper()
+	name := strings.NewReplacer("/", "_", " ", "_").Replace(t.Name())
+	s, err := store.OpenSQLite(fmt.Sprintf("file:ingest_%s?mode=memory&cache=shared", name))
+	if err != nil {
+		t.Fatalf("OpenSQLite: %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+// insertUpstreamItems 写入带上游 ID 的条目，upstream ID 是十进制短格式转换后的长格式
+func insertUpstreamItems(t *testing.T, st store.Store, shortIDs ...int) map[int]string {
+	t.Helper()
+	itemIDs := map[int]string{}
+	var items []store.PostItem
+	for _, id := range shortIDs {
+		item := store.PostItem{ID: store.NewID(), FeedTitle: "Feed", Content: `{"title": "t"}`, UpstreamID: LongItemID(strconv.Itoa(id))}
+		itemIDs[id] = item.ID
+		items = append(items, item)
+	}
+	if err := st.InsertPostItems(items); err != nil {
+		t.Fatal(err)
+	}
+	return itemIDs
+}
+
+func starredState(t *testing.T, st store.Store, itemID string) bool {
+	t.Helper()
+	item, err := st.GetPostItem(itemID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return item.Starred
+}
+
+func TestPullStatePagesStarredStream(t *testing.T) {
+	st := openTestStore(t)
+	f, freshrss := newFakeFreshRSS(t)
+	ids := insertUpstreamItems(t, st, 1, 2, 3, 4, 5, 6)
+	for _, id := range []int{1, 6} {
+		if _, err := st.SetItemStarred(ids[id], true); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := st.MarkStarSynced([]string{LongItemID("1"), LongItemID("6")}, true); err != nil {
+		t.Fatal(err)
+	}
+	// 星标分三页返回，第一页之后的星标也要保留
+	f.streams[StateStarred] = []string{"5", "4", "3", "2", "1"}
+	f.streams[StateRead] = []string{"6", "5", "4", "3", "2", "1"}
+
+	if err := freshrss.PullState(st, freshrss.Auth()); err != nil {
+		t.Fatalf("PullState() error = %v", err)
+	}
+	for id, want := range map[int]bool{1: true, 2: true, 3: true, 4: true, 5: true, 6: false} {
+		if got := starredState(t, st, ids[id]); got != want {
+			t.Errorf("item %d starred = %v, want %v", id, got, want)
+		}
+	}
+	// 已读只取第一页
+	for id, want := range map[int]bool{6: true, 5: true, 4: false} {
+		item, _ := st.GetPostItem(ids[id])
+		if item.Read != want {
+			t.Errorf("item %d read = %v, want %v", id, item.Read, want)
+		}
+	}
+}
+
+func TestPullStateKeepsUnsyncedStars(t *testing.T) {
+	st := openTestStore(t)
+	f, freshrss := newFakeFreshRSS(t)
+	ids := insertUpstreamItems(t, st, 1, 2)
+	f.streams[StateStarred] = []string{"2"}
+	if err := freshrss.PullState(st, freshrss.Auth()); err != nil {
+		t.Fatal(err)
+	}
+
+	// 本地星标 1、取消星标 2，推送失败
+	f.failEditTag = true
+	st.SetItemStarred(ids[1], true)
+	st.SetItemStarred(ids[2], false)
+	if err := freshrss.SetStarred(st, freshrss.Auth(), []string{LongItemID("1")}, true); err == nil {
+		t.Fatal("SetStarred() error = nil, want the edit-tag failure")
+	}
+	if err := freshrss.PullState(st, freshrss.Auth()); err != nil {
+		t.Fatal(err)
+	}
+	if !starredState(t, st, ids[1]) || starredState(t, st, ids[2]) {
+		t.Fatal("PullState() reverted stars that were not pushed yet")
+	}
+
+	// 推送恢复后，下一轮先推送再拉取
+	f.failEditTag = false
+	if err := freshrss.PullState(st, freshrss.Auth()); err != nil {
+		t.Fatal(err)
+	}
+	if len(f.editTags) != 2 || f.editTags[0].Get("a") != StateStarred || f.editTags[1].Get("r") != StateStarred {
+		t.Fatalf("edit-tag requests = %v", f.editTags)
+	}
+	if starred, unstarred, _ := st.UnsyncedStars(); len(starred)+len(unstarred) != 0 {
+		t.Errorf("UnsyncedStars() = %v, %v after pushing", starred, unstarred)
+	}
+
+	// 同步之后以上游为准
+	f.streams[StateStarred] = nil
+	if err := freshrss.PullState(st, freshrss.Auth()); err != nil {
+		t.Fatal(err)
+	}
+	if starredState(t, st, ids[1]) {
+		t.Error("item 1 is still starred after being unstarred upstream")
+	}
+}
+
+// subscription 构造 FreshRSS 订阅列表中的一项
+func subscription(id, title, label string) map[string]interface{} {
+	return map[string]interface{}{
+		"id":         id,
+		"title":      title,
+		"url":        "https://example.com/" + title + ".xml",
+		"categories": []interface{}{map[string]interface{}{"label": label}},
+	}
+}
+
+func TestFeedsSyncsEveryRound(t *testing.T) {
+	st := openTestStore(t)
+	f, freshrss := newFakeFreshRSS(t)
+	freshrss.FilteredLabel = "news"
+	in := New(st, freshrss, nil)
+	authToken := freshrss.Auth()
+
+	feedTitles := func() []string {
+		var titles []string
+		for _, feed := range in.feeds(authToken) {
+			titles = append(titles, feed.Title)
+		}
+		return titles
+	}
+
+	f.subscriptions = []map[string]interface{}{subscription("feed/1", "a", "news"), subscription("feed/2", "b", "other")}
+	if got := feedTitles(); strings.Join(got, ",") != "a" {
+		t.Fatalf("first round feeds = %v, want [a]", got)
+	}
+
+	// 上游新增的订阅下一轮自动加入
+	f.subscriptions = append(f.subscriptions, subscription("feed/3", "c", "news"))
+	if got := feedTitles(); strings.Join(got, ",") != "a,c" {
+		t.Fatalf("second round feeds = %v, want [a c]", got)
+	}
+
+	// 在 Shin 中删除的订阅不会被自动同步加回来
+	feeds, _ := st.ListFeeds()
+	for _, feed := range feeds {
+		if feed.UpstreamID == "feed/1" {
+			if err := st.DeleteFeed(feed.ID); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if got := feedTitles(); strings.Join(got, ",") != "c" {
+		t.Fatalf("feeds after delete = %v, want [c]", got)
+	}
+	if feeds, _ := st.ListFeeds(); len(feeds) != 2 {
+		t.Errorf("ListFeeds() after sync = %+v, want b and c", feeds)
+	}
+
+	// 手动同步时重新加入
+	if added, err := freshrss.SyncFeeds(st, authToken, true); err != nil || added != 1 {
+		t.Fatalf("SyncFeeds(restore) = %d, %v", added, err)
+	}
+	if got := feedTitles(); strings.Join(got, ",") != "a,c" {
+		t.Errorf("feeds after restore = %v, want [a c]", got)
+	}
+}
@@ -0,0 +1,346 @@
+// Package ingest 定时从 Source 拉取新条目，翻译标题、应用改写和打标签规则后写入 Store。
+// 其他 Go 服务可以用自己的 Store、Source 和 Translator 嵌入拉取流程。
+package ingest
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"os"
+	"strconv"
+	"time"
+
+	"shin/internal/config"
+	"shin/internal/store"
+	"shin/internal/translate"
+)
+
+var logger = config.Logger
+
+const OT_MAP_KEY = "otMap"
+
+// Source 是条目的来源，默认实现是 FreshRSS 的 Google Reader API
+type Source interface {
+	Auth() string
+	Subscriptions(authToken string) []map[string]interface{}
+	FeedItems(authToken, feedID, ot string) []interface{}
+}
+
+// FeedSyncer 是能把订阅列表同步到 shin_feed 的 Source。restore 为 false 时跳过在 Shin 中删除过的订阅
+type FeedSyncer interface {
+	SyncFeeds(st store.Store, authToken string, restore bool) (added int, err error)
+}
+
+// Ingester 定时从 Source 拉取新条目，翻译后写入 Store
+type Ingester struct {
+	store      store.Store
+	source     Source
+	translator translate.Translator
+	// Direct 抓取 shin_feed 中没有 UpstreamID 的订阅
+	Direct Source
+
+	DefaultOT    string
+	PollInterval time.Duration
+	ItemDelay    time.Duration // 每条之间随机等待的上限，避免翻译接口限流
+
+	onInsert   []func([]store.PostItem)
+	afterRound []func(authToken string, items []store.PostItem)
+
+	otMap        map[string]string
+	newOTMap     map[string]string
+	rewriteRules []compiledRewriteRule
+	tagRules     []TagRule
+}
+
+// New 按 DEFAULT_OT 和 POLL_INTERVAL_SECONDS 创建 Ingester
+func New(st store.Store, source Source, translator translate.Translator) *Ingester {
+	ot := os.Getenv("DEFAULT_OT")
+	if ot == "" {
+		// 默认从2小时前拉取
+		ot = strconv.FormatInt(time.Now().Add(-2*time.Hour).Unix(), 10)
+	}
+	pollIntervalSeconds, _ := strconv.Atoi(os.Getenv("POLL_INTERVAL_SECONDS"))
+	return &Ingester{
+		store:        st,
+		source:       source,
+		translator:   translator,
+		Direct:       NewRSS(),
+		DefaultOT:    ot,
+		PollInterval: time.Duration(pollIntervalSeconds) * time.Second,
+		ItemDelay:    10 * time.Second,
+		newOTMap:     make(map[string]string),
+	}
+}
+
+// OnInsert 注册条目入库后的回调，例如通知和正文抽取
+func (in *Ingester) OnInsert(fn func([]store.PostItem)) {
+	in.onInsert = append(in.onInsert, fn)
+}
+
+// AfterRound 注册每轮拉取结束后的回调，items 是本轮入库的全部条目
+func (in *Ingester) AfterRound(fn func(authToken string, items []store.PostItem)) {
+	in.afterRound = append(in.afterRound, fn)
+}
+
+// Run 循环拉取，每轮之间等待 PollInterval
+func (in *Ingester) Run() {
+	logger.Println("Starting loop...")
+	logger.Println("pollInterval", in.PollInterval)
+	in.otMap = in.loadOTMap()
+	logger.Printf("Start otMap: %v newOTMap: %v defaultOT: %s", in.otMap, in.newOTMap, in.DefaultOT)
+
+	for {
+		in.RunOnce()
+
+		// Sleep 不需要捕获异常，放在循环外
+		time.Sleep(in.PollInterval)
+	}
+}
+
+// RunOnce 执行一轮拉取，panic 只记录日志
+func (in *Ingester) RunOnce() {
+	defer func() {
+		if r := recover(); r != nil {
+			logger.Printf("Recovered from panic: %v", r)
+		}
+	}()
+
+	authToken := in.source.Auth()
+	logger.Println("rssAuth = ", authToken)
+	postItems := in.FetchNews(authToken)
+	if len(postItems) > 0 {
+		in.otMap = in.newOTMap
+		in.saveOTMap(in.otMap)
+	} else {
+		logger.Println("No updates.")
+	}
+	for _, fn := range in.afterRound {
+		fn(authToken, postItems)
+	}
+	logger.Println("End current loop.")
+}
+
+func (in *Ingester) loadOTMap() map[string]string {
+	otMapJSON, err := in.store.GetKeyValue(OT_MAP_KEY)
+	if err != nil || otMapJSON == "" {
+		logger.Println("GetOtMap:", err)
+		return make(map[string]string)
+	}
+
+	var otMap map[string]string
+	if err := json.Unmarshal([]byte(otMapJSON), &otMap); err != nil {
+		logger.Println("Unmarshl otMap err:", err)
+		return make(map[string]string)
+	}
+	return otMap
+}
+
+func (in *Ingester) saveOTMap(otMap map[string]string) {
+	valueJSON, err := json.Marshal(otMap)
+	if err != nil {
+		logger.Println("valueJSON err:", err)
+		return
+	}
+	logger.Println("UpdateOtMap:", string(valueJSON))
+	if err := in.store.SetKeyValue(OT_MAP_KEY, string(valueJSON)); err != nil {
+		logger.Println("failed to execute statement:", err)
+	}
+}
+
+// FetchNews 拉取并保存新条目，条目在切分摘要前不属于任何一期
+func (in *Ingester) FetchNews(authToken string) []store.PostItem {
+	logger.Println("fetchNews authToken", authToken)
+	in.rewriteRules = LoadRewriteRules(in.store)
+	in.tagRules = LoadTagRules(in.store)
+
+	var allPostItems []store.PostItem
+	for _, feed := range in.feeds(authToken) {
+		postItems := in.fetchFeed(feed, authToken)
+		if len(postItems) > 0 {
+			if err := in.store.InsertPostItems(postItems); err != nil {
+				logger.Println("InsertPostItems:", err)
+				continue
+			}
+			for _, fn := range in.onInsert {
+				fn(postItems)
+			}
+			allPostItems = append(allPostItems, postItems...)
+		} else {
+			logger.Println("No updates from", feedKey(feed), feed.Title)
+		}
+	}
+	return allPostItems
+}
+
+// feeds 返回本轮要拉取的订阅，即 shin_feed 中启用的订阅。Source 支持同步时每轮先同步一次，
+// 上游新增的订阅自动加入，在 Shin 中删除的订阅留有记录，不会被加回来
+func (in *Ingester) feeds(authToken string) []store.Feed {
+	if syncer, ok := in.source.(FeedSyncer); ok {
+		if added, err := syncer.SyncFeeds(in.store, authToken, false); err != nil {
+			logger.Println("SyncFeeds:", err)
+		} else if added > 0 {
+			logger.Println("SyncFeeds added:", added)
+		}
+	}
+	return in.listFeeds(authToken)
+}
+
+// listFeeds 返回 shin_feed 中启用的订阅，不写库。shin_feed 中没有任何订阅（包括已删除的）时返回 Source 的订阅列表
+func (in *Ingester) listFeeds(authToken string) []store.Feed {
+	feeds, err := in.store.ListFeeds()
+	if err != nil {
+		logger.Println("ListFeeds:", err)
+	}
+	if len(feeds) == 0 && !in.hasDeletedFeeds() {
+		for _, sub := range in.source.Subscriptions(authToken) {
+			feedID, _ := sub["id"].(string)
+			feedTitle, _ := sub["title"].(string)
+			if feedID == "" {
+				continue
+			}
+			feeds = append(feeds, store.Feed{Title: feedTitle, Enabled: true, Translate: store.TranslateTitle, UpstreamID: feedID})
+		}
+	}
+
+	var enabled []store.Feed
+	for _, feed := range feeds {
+		if feed.Enabled {
+			enabled = append(enabled, feed)
+		}
+	}
+	return enabled
+}
+
+func (in *Ingester) hasDeletedFeeds() bool {
+	deleted, err := in.store.ListDeletedFeeds()
+	if err != nil {
+		logger.Println("ListDeletedFeeds:", err)
+	}
+	return len(deleted) > 0
+}
+
+// feedKey 是订阅在 otMap 和规则中的 ID：FreshRSS 的订阅 ID，直接抓取的订阅按 Google Reader 的格式用 feed/URL
+func feedKey(feed store.Feed) string {
+	if feed.UpstreamID != "" {
+		return feed.UpstreamID
+	}
+	return "feed/" + feed.URL
+}
+
+// itemHref 返回条目的 canonical 链接，格式不对时返回空字符串
+func itemHref(item map[string]interface{}) string {
+	canonical, _ := item["canonical"].([]interface{})
+	if len(canonical) == 0 {
+		return ""
+	}
+	link, _ := canonical[0].(map[string]interface{})
+	href, _ := link["href"].(string)
+	return href
+}
+
+// feedItems 从 Source 或直接抓取读取订阅中 ot 之后的条目
+func (in *Ingester) feedItems(feed store.Feed, authToken, ot string) []interface{} {
+	if feed.UpstreamID != "" {
+		return in.source.FeedItems(authToken, feed.UpstreamID, ot)
+	}
+	if in.Direct != nil {
+		return in.Direct.FeedItems("", feed.URL, ot)
+	}
+	return nil
+}
+
+func (in *Ingester) fetchFeed(feed store.Feed, authToken string) []store.PostItem {
+	feedID, feedTitle := feedKey(feed), feed.Title
+	ot := in.otMap[feedID]
+	logger.Printf("feedID: %s feedTitle: %s ot: %s defaultOT: %s", feedID, feedTitle, ot, in.DefaultOT)
+	if ot == "" {
+		ot = in.DefaultOT
+	}
+
+	var items []interface{}
+	if feed.UpstreamID != "" {
+		items = in.feedItems(feed, authToken, ot)
+	} else {
+		// 抓取过的订阅不再按发布时间过滤，只按 guid 去重
+		if _, fetched := in.otMap[feedID]; fetched {
+			ot = "0"
+		}
+		items = in.dropKnownItems(in.feedItems(feed, authToken, ot))
+	}
+	if len(items) == 0 {
+		return nil
+	}
+
+	var postItems []store.PostItem
+	crawlTimeStr := items[0].(map[string]interface{})["crawlTimeMsec"].(string)
+	crawlTimeInt, err := strconv.ParseInt(crawlTimeStr, 10, 64)
+	if err != nil {
+		fmt.Println("crawlTimeStr conversion err:", err)
+	} else {
+		newOT := strconv.FormatInt((crawlTimeInt/1000)+1, 10)
+		logger.Printf("crawlTimeInt: %d newOT: %s", crawlTimeInt, newOT)
+		in.newOTMap[feedID] = newOT
+	}
+
+	for _, raw := range items {
+		item := raw.(map[string]interface{})
+		title := item["title"].(string)
+		cnTitle := title
+		if feed.Translate != store.TranslateNone {
+			cnTitle = in.translator.Translate(title)
+		}
+		href := applyRewriteRules(in.rewriteRules, feedID, feedTitle, item, itemHref(item))
+
+		postItemContent := store.PostItemContent{
+			CnTitle: cnTitle,
+			Title:   title,
+			Link:    href,
+		}
+
+		upstreamID, _ := item["id"].(string)
+		postItemContentJSON, _ := json.Marshal(postItemContent)
+		postItemContentJSONString := string(postItemContentJSON)
+
+		guid, _ := item["guid"].(string)
+		postItems = append(postItems, store.PostItem{
+			ID:         store.NewID(),
+			PostID:     "",
+			FeedTitle:  feedTitle,
+			Content:    postItemContentJSONString,
+			MemoID:     "",
+			UpstreamID: upstreamID,
+			GUID:       guid,
+			Tags:       ApplyTagRules(in.tagRules, feedID, feedTitle, postItemContent),
+		})
+
+		if in.ItemDelay > 0 && feed.Translate != store.TranslateNone {
+			time.Sleep(time.Duration(rand.Int63n(int64(in.ItemDelay))))
+		}
+	}
+
+	return postItems
+}
+
+// dropKnownItems 去掉 guid 已经入库或在本次结果中重复的条目
+func (in *Ingester) dropKnownItems(items []interface{}) []interface{} {
+	var guids []string
+	for _, raw := range items {
+		guid, _ := raw.(map[string]interface{})["guid"].(string)
+		guids = append(guids, guid)
+	}
+	known, err := in.store.KnownGUIDs(guids)
+	if err != nil {
+		logger.Println("KnownGUIDs:", err)
+		return nil
+	}
+
+	var fresh []interface{}
+	for i, raw := range items {
+		if guids[i] == "" || known[guids[i]] {
+			continue
+		}
+		known[guids[i]] = true
+		fresh = append(fresh, raw)
+	}
+	return fresh
+}
@@ -0,0 +1,258 @@
+package ingest
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"shin/internal/store"
+)
+
+const REWRITE_RULES_KEY = "rewriteRules"
+
+// ErrInvalidRule 表示改写或打标签规则无效
+var ErrInvalidRule = errors.New("invalid rule")
+
+var withContentFeeds = os.Getenv("WITH_CONTENT_FEEDS")
+
+// RewriteRule 用 Pattern 匹配条目的某个字段，并把 Replacement 展开后的结果作为新的链接。
+// Feed 可以是订阅 ID、订阅标题或 "*"（所有订阅）；Preset 不为空时，未填写的字段取预设值。
+type RewriteRule struct {
+	Feed        string `json:"feed"`
+	Preset      string `json:"preset,omitempty"`
+	Field       string `json:"field,omitempty"` // summary, content, link, title
+	Pattern     string `json:"pattern,omitempty"`
+	Replacement string `json:"replacement,omitempty"`
+}
+
+type compiledRewriteRule struct {
+	RewriteRule
+	re *regexp.Regexp
+}
+
+// RewritePresets 是内置预设
+var RewritePresets = map[string]RewriteRule{
+	// Hacker News 使用评论页链接
+	"hn": {
+		Field:       "summary",
+		Pattern:     `https://news\.ycombinator\.com/item\?id=\d+`,
+		Replacement: "$0",
+	},
+	// Lobsters 使用讨论页链接
+	"lobsters": {
+		Field:       "summary",
+		Pattern:     `https://lobste\.rs/s/[0-9a-z]+(/[\w-]*)?`,
+		Replacement: "$0",
+	},
+	// Reddit 使用帖子里的 [link] 原文链接，而不是评论页
+	"reddit": {
+		Field:       "summary",
+		Pattern:     `<a href="([^"]+)">\[link\]</a>`,
+		Replacement: "${1}",
+	},
+}
+
+func (r RewriteRule) resolve() (RewriteRule, error) {
+	if r.Preset != "" {
+		preset, ok := RewritePresets[r.Preset]
+		if !ok {
+			return r, fmt.Errorf("unknown preset: %s", r.Preset)
+		}
+		if r.Field == "" {
+			r.Field = preset.Field
+		}
+		if r.Pattern == "" {
+			r.Pattern = preset.Pattern
+		}
+		if r.Replacement == "" {
+			r.Replacement = preset.Replacement
+		}
+	}
+	if r.Feed == "" {
+		return r, fmt.Errorf("feed is required")
+	}
+	switch r.Field {
+	case "summary", "content", "link", "title":
+	default:
+		return r, fmt.Errorf("unknown field: %q", r.Field)
+	}
+	if r.Pattern == "" {
+		return r, fmt.Errorf("pattern is required")
+	}
+	if r.Replacement == "" {
+		r.Replacement = "$0"
+	}
+	return r, nil
+}
+
+func compileRewriteRule(rule RewriteRule) (compiledRewriteRule, error) {
+	resolved, err := rule.resolve()
+	if err != nil {
+		return compiledRewriteRule{}, err
+	}
+	re, err := regexp.Compile(resolved.Pattern)
+	if err != nil {
+		return compiledRewriteRule{}, fmt.Errorf("invalid pattern %q: %w", resolved.Pattern, err)
+	}
+	return compiledRewriteRule{RewriteRule: resolved, re: re}, nil
+}
+
+func GetRewriteRules(st store.Store) []RewriteRule {
+	var rules []RewriteRule
+	value, err := st.GetKeyValue(REWRITE_RULES_KEY)
+	if err == nil && value != "" {
+		if err := json.Unmarshal([]byte(value), &rules); err != nil {
+			logger.Println("Unmarshal rewriteRules err:", err)
+		}
+	}
+
+	// 兼容旧的 WITH_CONTENT_FEEDS 配置，按订阅 ID 精确匹配
+	for _, feedID := range strings.Split(withContentFeeds, ",") {
+		feedID = strings.TrimSpace(feedID)
+		if feedID != "" {
+			rules = append(rules, RewriteRule{Feed: feedID, Preset: "hn"})
+		}
+	}
+	return rules
+}
+
+// LoadRewriteRules 返回编译好的规则，跳过无效的
+func LoadRewriteRules(st store.Store) []compiledRewriteRule {
+	var compiled []compiledRewriteRule
+	for _, rule := range GetRewriteRules(st) {
+		c, err := compileRewriteRule(rule)
+		if err != nil {
+			logger.Println("Skip rewrite rule:", err)
+			continue
+		}
+		compiled = append(compiled, c)
+	}
+	return compiled
+}
+
+func rewriteSource(item map[string]interface{}, field, href string) string {
+	switch field {
+	case "link":
+		return href
+	case "title":
+		title, _ := item["title"].(string)
+		return title
+	case "summary", "content":
+		if m, ok := item[field].(map[string]interface{}); ok {
+			content, _ := m["content"].(string)
+			return content
+		}
+	}
+	return ""
+}
+
+// applyRewrite 返回改写后的链接，规则不匹配时返回空字符串
+func (r compiledRewriteRule) applyRewrite(item map[string]interface{}, href string) string {
+	src := rewriteSource(item, r.Field, href)
+	match := r.re.FindStringSubmatchIndex(src)
+	if match == nil {
+		return ""
+	}
+	result := string(r.re.ExpandString(nil, r.Replacement, src, match))
+	if r.Field == "summary" || r.Field == "content" {
+		result = html.UnescapeString(result)
+	}
+	return strings.TrimSpace(result)
+}
+
+func (r compiledRewriteRule) matchFeed(feedID, feedTitle string) bool {
+	return r.Feed == "*" || r.Feed == feedID || r.Feed == feedTitle
+}
+
+// applyRewriteRules 按顺序应用规则，后面的规则看到的是前面改写后的链接
+func applyRewriteRules(rules []compiledRewriteRule, feedID, feedTitle string, item map[string]interface{}, href string) string {
+	for _, rule := range rules {
+		if !rule.matchFeed(feedID, feedTitle) {
+			continue
+		}
+		if rewritten := rule.applyRewrite(item, href); rewritten != "" {
+			href = rewritten
+		}
+	}
+	return href
+}
+
+// SaveRewriteRules 校验并保存规则，规则无效时返回 ErrInvalidRule
+func SaveRewriteRules(st store.Store, rules []RewriteRule) error {
+	for _, rule := range rules {
+		if _, err := compileRewriteRule(rule); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidRule, err)
+		}
+	}
+
+	if rules == nil {
+		rules = []RewriteRule{}
+	}
+	valueJSON, _ := json.Marshal(rules)
+	return st.SetKeyValue(REWRITE_RULES_KEY, string(valueJSON))
+}
+
+type DryRunResult struct {
+	FeedID    string `json:"feed_id"`
+	FeedTitle string `json:"feed_title"`
+	Title     string `json:"title"`
+	Link      string `json:"link"`
+	Rewritten string `json:"rewritten"`
+	Matched   bool   `json:"matched"`
+}
+
+// DryRunRewriteRule 拉取匹配订阅最近 hours 小时的条目，返回规则改写前后的链接，不写库也不翻译。
+// 订阅列表和 FetchNews 相同，直接抓取的订阅也能预览；格式不对的条目跳过
+func (in *Ingester) DryRunRewriteRule(input RewriteRule, hours, limit int) ([]DryRunResult, error) {
+	if hours <= 0 {
+		hours = 24
+	}
+	if limit <= 0 {
+		limit = 20
+	}
+
+	rule, err := compileRewriteRule(input)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidRule, err)
+	}
+
+	authToken := in.source.Auth()
+	ot := strconv.FormatInt(time.Now().Add(-time.Duration(hours)*time.Hour).Unix(), 10)
+	results := []DryRunResult{}
+	for _, feed := range in.listFeeds(authToken) {
+		feedID, feedTitle := feedKey(feed), feed.Title
+		if !rule.matchFeed(feedID, feedTitle) {
+			continue
+		}
+		for _, raw := range in.feedItems(feed, authToken, ot) {
+			if len(results) >= limit {
+				break
+			}
+			item, ok := raw.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			href := itemHref(item)
+			if href == "" {
+				continue
+			}
+			title, _ := item["title"].(string)
+			rewritten := rule.applyRewrite(item, href)
+			results = append(results, DryRunResult{
+				FeedID:    feedID,
+				FeedTitle: feedTitle,
+				Title:     title,
+				Link:      href,
+				Rewritten: rewritten,
+				Matched:   rewritten != "",
+			})
+		}
+	}
+	return results, nil
+}
@@ -0,0 +1,144 @@
+package ingest
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"shin/internal/store"
+)
+
+// readerItem 构造 Google Reader 格式的条目
+func readerItem(title, href, summary string) map[string]interface{} {
+	return map[string]interface{}{
+		"title":     title,
+		"canonical": []interface{}{map[string]interface{}{"href": href}},
+		"summary":   map[string]interface{}{"content": summary},
+	}
+}
+
+func TestRewritePresets(t *testing.T) {
+	tests := []struct {
+		preset, summary, want string
+	}{
+		{"hn", `<a href="https://example.com/a">Article</a> <a href="https://news.ycombinator.com/item?id=4242">Comments</a>`, "https://news.ycombinator.com/item?id=4242"},
+		{"hn", `<a href="https://example.com/a">Article</a>`, "https://example.com/a"},
+		{"lobsters", `<a href="https://lobste.rs/s/ab12cd/some_story">Comments</a>`, "https://lobste.rs/s/ab12cd/some_story"},
+		{"reddit", `submitted by /u/gopher <a href="https://example.com/a?x=1&amp;y=2">[link]</a> <a href="https://www.reddit.com/r/golang/comments/1/">[comments]</a>`, "https://example.com/a?x=1&y=2"},
+	}
+	for _, tt := range tests {
+		rule, err := compileRewriteRule(RewriteRule{Feed: "*", Preset: tt.preset})
+		if err != nil {
+			t.Fatalf("compileRewriteRule(%s): %v", tt.preset, err)
+		}
+		item := readerItem("Title", "https://example.com/a", tt.summary)
+		if got := applyRewriteRules([]compiledRewriteRule{rule}, "feed/1", "Feed", item, itemHref(item)); got != tt.want {
+			t.Errorf("preset %s on %q = %q, want %q", tt.preset, tt.summary, got, tt.want)
+		}
+	}
+
+	if _, err := compileRewriteRule(RewriteRule{Feed: "*", Preset: "slashdot"}); err == nil {
+		t.Error("unknown preset compiled")
+	}
+}
+
+func TestWithContentFeeds(t *testing.T) {
+	st := openTestStore(t)
+	old := withContentFeeds
+	withContentFeeds = " feed/1 ,,feed/2"
+	t.Cleanup(func() { withContentFeeds = old })
+
+	if err := SaveRewriteRules(st, []RewriteRule{{Feed: "Blog", Field: "title", Pattern: `^https://\S+`}}); err != nil {
+		t.Fatal(err)
+	}
+	rules := GetRewriteRules(st)
+	want := []RewriteRule{
+		{Feed: "Blog", Field: "title", Pattern: `^https://\S+`},
+		{Feed: "feed/1", Preset: "hn"},
+		{Feed: "feed/2", Preset: "hn"},
+	}
+	if len(rules) != len(want) {
+		t.Fatalf("GetRewriteRules() = %+v, want %+v", rules, want)
+	}
+	for i := range want {
+		if rules[i] != want[i] {
+			t.Errorf("rule %d = %+v, want %+v", i, rules[i], want[i])
+		}
+	}
+
+	// WITH_CONTENT_FEEDS 只匹配订阅 ID
+	compiled := LoadRewriteRules(st)
+	item := readerItem("Title", "https://example.com/a", `<a href="https://news.ycombinator.com/item?id=1">Comments</a>`)
+	for feedID, want := range map[string]string{
+		"feed/1": "https://news.ycombinator.com/item?id=1",
+		"feed/2": "https://news.ycombinator.com/item?id=1",
+		"feed/3": "https://example.com/a",
+	} {
+		if got := applyRewriteRules(compiled, feedID, "HN", item, itemHref(item)); got != want {
+			t.Errorf("%s: link = %q, want %q", feedID, got, want)
+		}
+	}
+}
+
+// stubSource 返回固定的订阅和条目，用来模拟格式不对的上游数据
+type stubSource struct {
+	subs  []map[string]interface{}
+	items map[string][]interface{}
+}
+
+func (s stubSource) Auth() string                                  { return "token" }
+func (s stubSource) Subscriptions(string) []map[string]interface{} { return s.subs }
+func (s stubSource) FeedItems(_, feedID, _ string) []interface{}   { return s.items[feedID] }
+
+func TestDryRunRewriteRule(t *testing.T) {
+	st := openTestStore(t)
+	source := stubSource{
+		subs: []map[string]interface{}{
+			{"id": 42, "title": "Bad ID"},
+			{"title": "No ID"},
+			{"id": "feed/hn", "title": "HN"},
+		},
+		items: map[string][]interface{}{
+			"feed/hn": {
+				"not an item",
+				map[string]interface{}{"title": "No link"},
+				map[string]interface{}{"title": "Bad link", "canonical": []interface{}{"https://example.com"}},
+				readerItem("Story", "https://example.com/story", `<a href="https://news.ycombinator.com/item?id=7">Comments</a>`),
+				readerItem("Ask HN", "https://example.com/ask", ""),
+			},
+		},
+	}
+	in := New(st, source, nil)
+
+	results, err := in.DryRunRewriteRule(RewriteRule{Feed: "HN", Preset: "hn"}, 24, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 || !results[0].Matched || results[0].Rewritten != "https://news.ycombinator.com/item?id=7" || results[1].Matched || results[1].Link != "https://example.com/ask" {
+		t.Errorf("upstream dry run = %+v", results)
+	}
+	if _, err := in.DryRunRewriteRule(RewriteRule{Feed: "HN", Field: "link", Pattern: "("}, 24, 10); err == nil {
+		t.Error("invalid pattern accepted")
+	}
+
+	// shin_feed 中有订阅时按它预览，直接抓取的订阅也包括在内
+	feed := &feedServer{}
+	server := httptest.NewServer(feed)
+	t.Cleanup(server.Close)
+	feed.set(rssEntry("Direct story", "g1", "http://m.example.com/direct", time.Now().Add(-time.Hour)))
+	if _, err := st.AddFeed(store.Feed{URL: server.URL, Title: "Direct", Enabled: true, Translate: store.TranslateNone}); err != nil {
+		t.Fatal(err)
+	}
+	in.Direct = &RSS{Client: server.Client()}
+
+	results, err = in.DryRunRewriteRule(RewriteRule{Feed: "*", Field: "link", Pattern: `^http://m\.(.*)$`, Replacement: "https://$1"}, 24, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].FeedID != "feed/"+server.URL || results[0].Rewritten != "https://example.com/direct" {
+		t.Errorf("direct dry run = %+v", results)
+	}
+	if feeds, _ := st.ListFeeds(); len(feeds) != 1 {
+		t.Errorf("dry run changed shin_feed: %+v", feeds)
+	}
+}
@@ -0,0 +1,166 @@
+package ingest
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html/charset"
+)
+
+// RSS 直接抓取订阅地址，解析 RSS 和 Atom，用于不在 FreshRSS 中的订阅
+type RSS struct {
+	Client *http.Client
+}
+
+func NewRSS() *RSS {
+	return &RSS{Client: &http.Client{Timeout: 60 * time.Second}}
+}
+
+// feedDocument 同时兼容 RSS 2.0（channel/item）、RSS 1.0（根下的 item）和 Atom（entry）
+type feedDocument struct {
+	Channel struct {
+		Items []rssItem `xml:"item"`
+	} `xml:"channel"`
+	Items   []rssItem   `xml:"item"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type rssItem struct {
+	Title       string `xml:"title"`
+	Link        string `xml:"link"`
+	GUID        string `xml:"guid"`
+	PubDate     string `xml:"pubDate"`
+	Date        string `xml:"http://purl.org/dc/elements/1.1/ date"`
+	Description string `xml:"description"`
+	Content     string `xml:"http://purl.org/rss/1.0/modules/content/ encoded"`
+}
+
+type atomEntry struct {
+	ID    string `xml:"id"`
+	Title string `xml:"title"`
+	Links []struct {
+		Href string `xml:"href,attr"`
+		Rel  string `xml:"rel,attr"`
+	} `xml:"link"`
+	Published string `xml:"published"`
+	Updated   string `xml:"updated"`
+	Summary   string `xml:"summary"`
+	Content   string `xml:"content"`
+}
+
+func (r *RSS) Auth() string {
+	return ""
+}
+
+func (r *RSS) Subscriptions(authToken string) []map[string]interface{} {
+	return nil
+}
+
+// FeedItems 返回 feedURL 中的条目，格式与 Google Reader API 相同，guid 放在 "guid" 中，没有时用链接。
+// ot（Unix 秒）不为 0 时只返回发布时间晚于 ot 的条目，用于第一次抓取；之后传 0 由 Ingester 按 guid 去重，
+// 发布时间没有时区或者早于上次抓取才出现的条目也不会漏掉
+func (r *RSS) FeedItems(authToken, feedURL, ot string) []interface{} {
+	since, _ := strconv.ParseInt(ot, 10, 64)
+	doc, err := r.fetch(feedURL)
+	if err != nil {
+		logger.Println("Failed to fetch feed:", feedURL, err)
+		return nil
+	}
+
+	now := time.Now()
+	crawlTimeMsec := strconv.FormatInt(now.UnixMilli(), 10)
+	var items []interface{}
+	add := func(guid, title, link, published, summary, content string) {
+		t := parseFeedTime(published)
+		link = strings.TrimSpace(link)
+		if since > 0 && (t.IsZero() || t.Unix() < since) || link == "" {
+			return
+		}
+		if guid = strings.TrimSpace(guid); guid == "" {
+			guid = link
+		}
+		if t.IsZero() {
+			t = now
+		}
+		items = append(items, map[string]interface{}{
+			"guid":          guid,
+			"title":         strings.TrimSpace(title),
+			"canonical":     []interface{}{map[string]interface{}{"href": link}},
+			"published":     float64(t.Unix()),
+			"crawlTimeMsec": crawlTimeMsec,
+			"summary":       map[string]interface{}{"content": summary},
+			"content":       map[string]interface{}{"content": content},
+		})
+	}
+	for _, item := range append(doc.Channel.Items, doc.Items...) {
+		published := item.PubDate
+		if published == "" {
+			published = item.Date
+		}
+		link := item.Link
+		if link == "" && strings.HasPrefix(item.GUID, "http") {
+			link = item.GUID
+		}
+		add(item.GUID, item.Title, link, published, item.Description, item.Content)
+	}
+	for _, entry := range doc.Entries {
+		var link string
+		for _, l := range entry.Links {
+			if l.Rel == "" || l.Rel == "alternate" {
+				link = l.Href
+				break
+			}
+		}
+		published := entry.Published
+		if published == "" {
+			published = entry.Updated
+		}
+		add(entry.ID, entry.Title, link, published, entry.Summary, entry.Content)
+	}
+	return items
+}
+
+func (r *RSS) fetch(feedURL string) (*feedDocument, error) {
+	resp, err := r.Client.Get(feedURL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("status %d", resp.StatusCode)
+	}
+
+	var doc feedDocument
+	decoder := xml.NewDecoder(resp.Body)
+	decoder.CharsetReader = charset.NewReaderLabel
+	decoder.Strict = false
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("invalid feed: %w", err)
+	}
+	return &doc, nil
+}
+
+var feedTimeLayouts = []string{
+	time.RFC1123Z,
+	time.RFC1123,
+	time.RFC3339,
+	"Mon, 2 Jan 2006 15:04:05 -0700",
+	"Mon, 2 Jan 2006 15:04:05 MST",
+	"2 Jan 2006 15:04:05 -0700",
+	"2006-01-02T15:04:05",
+	"2006-01-02",
+}
+
+func parseFeedTime(s string) time.Time {
+	s = strings.TrimSpace(s)
+	for _, layout := range feedTimeLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
@@ -0,0 +1,113 @@
+package ingest
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"shin/internal/store"
+)
+
+// feedServer 返回 entries 拼成的 RSS 2.0 文档
+type feedServer struct {
+	mu      sync.Mutex
+	entries []string
+}
+
+func (f *feedServer) set(entries ...string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.entries = entries
+}
+
+func (f *feedServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	w.Header().Set("Content-Type", "application/rss+xml")
+	fmt.Fprintf(w, `<?xml version="1.0"?><rss version="2.0"><channel><title>Direct</title>%s</channel></rss>`, strings.Join(f.entries, ""))
+}
+
+func rssEntry(title, guid, link string, published time.Time) string {
+	entry := "<item><title>" + title + "</title><link>" + link + "</link>"
+	if guid != "" {
+		entry += `<guid isPermaLink="false">` + guid + "</guid>"
+	}
+	if !published.IsZero() {
+		entry += "<pubDate>" + published.Format(time.RFC1123Z) + "</pubDate>"
+	}
+	return entry + "</item>"
+}
+
+// noSource 是没有上游订阅的 Source，只测试直接抓取
+type noSource struct{}
+
+func (noSource) Auth() string                                         { return "" }
+func (noSource) Subscriptions(string) []map[string]interface{}        { return nil }
+func (noSource) FeedItems(authToken, feedID, ot string) []interface{} { return nil }
+
+func itemGUIDs(t *testing.T, st store.Store) []string {
+	t.Helper()
+	items, _, err := st.ListItems(store.Page{Number: 1, Size: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var titles []string
+	for _, item := range items {
+		titles = append(titles, item.FeedTitle+":"+item.GUID)
+	}
+	return titles
+}
+
+func TestDirectFeedDedupesByGUID(t *testing.T) {
+	st := openTestStore(t)
+	feed := &feedServer{}
+	server := httptest.NewServer(feed)
+	t.Cleanup(server.Close)
+	if _, err := st.AddFeed(store.Feed{URL: server.URL, Title: "Direct", Enabled: true, Translate: store.TranslateNone}); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	in := New(st, noSource{}, nil)
+	in.Direct = &RSS{Client: server.Client()}
+	in.DefaultOT = fmt.Sprint(now.Add(-3 * time.Hour).Unix())
+	in.otMap = map[string]string{}
+
+	// 第一次抓取按 DefaultOT 过滤
+	feed.set(
+		rssEntry("A", "a-guid", "https://example.com/a", now.Add(-time.Hour)),
+		rssEntry("Old", "old-guid", "https://example.com/old", now.Add(-24*time.Hour)),
+	)
+	in.RunOnce()
+	if got := itemGUIDs(t, st); strings.Join(got, ",") != "Direct:a-guid" {
+		t.Fatalf("items after first fetch = %q", got)
+	}
+
+	// 之后只按 guid 去重：发布时间早于上次抓取的新条目和没有发布时间的条目都要拉取
+	feed.set(
+		rssEntry("A", "a-guid", "https://example.com/a", now.Add(-time.Hour)),
+		rssEntry("Late", "late-guid", "https://example.com/late", now.Add(-2*time.Hour)),
+		rssEntry("Undated", "", "https://example.com/undated", time.Time{}),
+		rssEntry("Undated again", "", "https://example.com/undated", time.Time{}),
+	)
+	in.RunOnce()
+	got := itemGUIDs(t, st)
+	want := []string{"Direct:a-guid", "Direct:https://example.com/undated", "Direct:late-guid"}
+	if len(got) != len(want) {
+		t.Fatalf("items after second fetch = %q, want %q", got, want)
+	}
+	for _, w := range want {
+		if !strings.Contains(strings.Join(got, ","), w) {
+			t.Errorf("items after second fetch = %q, missing %q", got, w)
+		}
+	}
+
+	in.RunOnce()
+	if got := itemGUIDs(t, st); len(got) != len(want) {
+		t.Errorf("items after an unchanged fetch = %q", got)
+	}
+}
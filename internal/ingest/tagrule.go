@@ -0,0 +1,109 @@
+package ingest
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"shin/internal/store"
+)
+
+const TAG_RULES_KEY = "tagRules"
+
+// TagRule 在抓取时给条目自动打标签：Feed 匹配订阅 ID、订阅标题或 "*"，
+// Keyword 不区分大小写匹配原标题和译文标题，两者都填写时需要同时满足
+type TagRule struct {
+	Tag     string `json:"tag"`
+	Feed    string `json:"feed,omitempty"`
+	Keyword string `json:"keyword,omitempty"`
+}
+
+// NormalizeTag 去掉首尾空白和开头的 "#"，标签里不能有逗号
+func NormalizeTag(name string) (string, error) {
+	name = strings.TrimPrefix(strings.TrimSpace(name), "#")
+	if name == "" {
+		return "", fmt.Errorf("tag is required")
+	}
+	if strings.Contains(name, ",") {
+		return "", fmt.Errorf("tag must not contain ','")
+	}
+	return name, nil
+}
+
+func (r TagRule) validate() error {
+	if _, err := NormalizeTag(r.Tag); err != nil {
+		return err
+	}
+	if r.Feed == "" && r.Keyword == "" {
+		return fmt.Errorf("feed or keyword is required")
+	}
+	return nil
+}
+
+func (r TagRule) match(feedID, feedTitle string, content store.PostItemContent) bool {
+	if r.Feed != "" && r.Feed != "*" && r.Feed != feedID && r.Feed != feedTitle {
+		return false
+	}
+	if r.Keyword != "" {
+		keyword := strings.ToLower(r.Keyword)
+		if !strings.Contains(strings.ToLower(content.Title), keyword) &&
+			!strings.Contains(strings.ToLower(content.CnTitle), keyword) {
+			return false
+		}
+	}
+	return true
+}
+
+func GetTagRules(st store.Store) []TagRule {
+	rules := []TagRule{}
+	value, err := st.GetKeyValue(TAG_RULES_KEY)
+	if err == nil && value != "" {
+		if err := json.Unmarshal([]byte(value), &rules); err != nil {
+			logger.Println("Unmarshal tagRules err:", err)
+		}
+	}
+	return rules
+}
+
+// LoadTagRules 返回有效的规则，标签已规范化
+func LoadTagRules(st store.Store) []TagRule {
+	var rules []TagRule
+	for _, rule := range GetTagRules(st) {
+		if err := rule.validate(); err != nil {
+			logger.Println("Skip tag rule:", err)
+			continue
+		}
+		rule.Tag, _ = NormalizeTag(rule.Tag)
+		rules = append(rules, rule)
+	}
+	return rules
+}
+
+// ApplyTagRules 返回条目命中的标签，按规则顺序去重
+func ApplyTagRules(rules []TagRule, feedID, feedTitle string, content store.PostItemContent) []string {
+	var tags []string
+	seen := make(map[string]bool)
+	for _, rule := range rules {
+		if seen[rule.Tag] || !rule.match(feedID, feedTitle, content) {
+			continue
+		}
+		seen[rule.Tag] = true
+		tags = append(tags, rule.Tag)
+	}
+	return tags
+}
+
+// SaveTagRules 校验并保存规则，规则无效时返回 ErrInvalidRule
+func SaveTagRules(st store.Store, rules []TagRule) error {
+	for _, rule := range rules {
+		if err := rule.validate(); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidRule, err)
+		}
+	}
+
+	if rules == nil {
+		rules = []TagRule{}
+	}
+	valueJSON, _ := json.Marshal(rules)
+	return st.SetKeyValue(TAG_RULES_KEY, string(valueJSON))
+}
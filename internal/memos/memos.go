@@ -0,0 +1,281 @@
+// Package memos 把条目按模板保存到 Memos，并提供模板数据给其他保存目标复用
+package memos
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+	"text/template"
+	"time"
+
+	"shin/internal/config"
+	"shin/internal/store"
+)
+
+var logger = config.Logger
+
+const defaultMemoTemplate = "{{.CnTitle}}\n{{.Title}}\n{{.Link}}\n{{if .Note}}{{.Note}}\n{{end}}{{.Hashtags}}"
+
+var (
+	memoVisibility = os.Getenv("MEMO_VISIBILITY")
+	memoTags       = os.Getenv("MEMO_TAGS")
+	memoFeedTag    = os.Getenv("MEMO_FEED_TAG") == "true"
+	memoTemplate   = parseMemoTemplate(os.Getenv("MEMO_TEMPLATE"))
+	memoTagRegex   = regexp.MustCompile(`[^\p{L}\p{N}_/-]+`)
+	httpClient     = &http.Client{Timeout: 60 * time.Second}
+)
+
+type Request struct {
+	Content    string `json:"content"`
+	Visibility string `json:"visibility"`
+}
+
+// Data 是 MEMO_TEMPLATE 中可以使用的字段
+type Data struct {
+	PostItemID string
+	PostID     string
+	FeedTitle  string
+	CnTitle    string
+	Title      string
+	Link       string
+	Tags       []string
+	Hashtags   string // Tags 拼接成 "#a #b"
+	Note       string // 随条目一起保存的笔记
+}
+
+func parseMemoTemplate(text string) *template.Template {
+	if text == "" {
+		text = defaultMemoTemplate
+	}
+	tmpl, err := template.New("memo").Funcs(template.FuncMap{"join": strings.Join}).Parse(text)
+	if err != nil {
+		logger.Println("Invalid MEMO_TEMPLATE, fallback to default:", err)
+		return template.Must(template.New("memo").Parse(defaultMemoTemplate))
+	}
+	return tmpl
+}
+
+// FeedTag 把订阅标题转换为 Memos 可以识别的标签
+func FeedTag(feedTitle string) string {
+	return strings.Trim(memoTagRegex.ReplaceAllString(feedTitle, "_"), "_")
+}
+
+func tagList(item store.PostItem) []string {
+	tags := []string{}
+	base := memoTags
+	if base == "" {
+		base = "rss"
+	}
+	for _, tag := range strings.Split(base, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	if memoFeedTag {
+		if tag := FeedTag(item.FeedTitle); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+func DataFor(item store.PostItem) (Data, error) {
+	var content store.PostItemContent
+	if err := json.Unmarshal([]byte(item.Content), &content); err != nil {
+		return Data{}, fmt.Errorf("failed to unmarshal content: %w", err)
+	}
+
+	notes := make([]string, len(item.Notes))
+	for i, note := range item.Notes {
+		notes[i] = note.Body
+	}
+
+	tags := tagList(item)
+	hashtags := make([]string, len(tags))
+	for i, tag := range tags {
+		hashtags[i] = "#" + tag
+	}
+
+	return Data{
+		PostItemID: item.ID,
+		PostID:     item.PostID,
+		FeedTitle:  item.FeedTitle,
+		CnTitle:    content.CnTitle,
+		Title:      content.Title,
+		Link:       content.Link,
+		Tags:       tags,
+		Hashtags:   strings.Join(hashtags, " "),
+		Note:       strings.Join(notes, "\n\n"),
+	}, nil
+}
+
+// Render 按 MEMO_TEMPLATE 生成 memo 内容
+func Render(item store.PostItem) (string, error) {
+	data, err := DataFor(item)
+	if err != nil {
+		return "", err
+	}
+
+	var sb strings.Builder
+	if err := memoTemplate.Execute(&sb, data); err != nil {
+		return "", fmt.Errorf("failed to render memo: %w", err)
+	}
+	return sb.String(), nil
+}
+
+func visibility() string {
+	if memoVisibility == "" {
+		return "PRIVATE"
+	}
+	return memoVisibility
+}
+
+// Client 把条目保存为 Memos 中的一条 memo，APIURL 是创建接口 .../api/v1/memos
+type Client struct {
+	APIURL string
+	Token  string
+}
+
+func (s Client) Name() string {
+	return "memos"
+}
+
+func (s Client) Save(item store.PostItem) (string, error) {
+	// 由服务端按模板生成 memo 内容
+	memoContent, err := Render(item)
+	if err != nil {
+		return "", err
+	}
+
+	// 构造要发送给外部 Memos API 的请求体
+	memo := Request{
+		Content:    memoContent,
+		Visibility: visibility(),
+	}
+
+	// 发起 HTTP 请求到 Memos API
+	logger.Printf("Memos apiURL: %s", s.APIURL)
+	body, status, err := s.do("POST", s.APIURL, memo)
+	if err != nil {
+		return "", err
+	}
+
+	// 创建成功可能返回 200 或 201
+	if status < 200 || status > 299 {
+		return "", fmt.Errorf("memos: %d %s", status, string(body))
+	}
+
+	var respData memoResponse
+	if err := json.Unmarshal(body, &respData); err != nil {
+		return "", fmt.Errorf("memos: failed to parse response: %w", err)
+	}
+	name := respData.resourceName()
+	if name == "" {
+		return "", fmt.Errorf("memos: no name or uid in response: %s", string(body))
+	}
+	return name, nil
+}
+
+// memoResponse 兼容不同版本的 Memos：v1 返回资源名 name（memos/{id}），旧版本只有 uid
+type memoResponse struct {
+	Name string `json:"name"`
+	UID  string `json:"uid"`
+}
+
+// resourceName 优先返回资源名；旧版本原样返回 uid，Delete 据此区分两种 ID
+func (r memoResponse) resourceName() string {
+	if isResourceName(r.Name) {
+		return r.Name
+	}
+	return r.UID
+}
+
+func isResourceName(memoID string) bool {
+	return strings.HasPrefix(memoID, "memos/")
+}
+
+// apiBase 由 MEMOS_CREATE_API（.../api/v1/memos）推出 API 根地址，也可以用 MEMOS_API_URL 指定
+func (s Client) apiBase() string {
+	if base := os.Getenv("MEMOS_API_URL"); base != "" {
+		return strings.TrimSuffix(base, "/")
+	}
+	return strings.TrimSuffix(strings.TrimSuffix(s.APIURL, "/"), "/memos")
+}
+
+// memoResourceURL 兼容旧数据里只保存了 uid 的 memo_id
+func (s Client) memoResourceURL(memoID string) string {
+	if !isResourceName(memoID) {
+		memoID = "memos/" + memoID
+	}
+	return s.apiBase() + "/" + memoID
+}
+
+func (s Client) do(method, url string, payload interface{}) ([]byte, int, error) {
+	var reqBody io.Reader
+	if payload != nil {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to encode request: %w", err)
+		}
+		reqBody = bytes.NewBuffer(data)
+	}
+
+	req, err := http.NewRequest(method, url, reqBody)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", s.Token))
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to send request to Memos API: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, resp.StatusCode, fmt.Errorf("failed to read response: %w", err)
+	}
+	return body, resp.StatusCode, nil
+}
+
+// Append 在已有 memo 下添加一条评论，返回评论的资源名
+func (s Client) Append(memoID, text string) (string, error) {
+	body, status, err := s.do("POST", s.memoResourceURL(memoID)+"/comments", Request{Content: text, Visibility: visibility()})
+	if err != nil {
+		return "", err
+	}
+	if status < 200 || status > 299 {
+		return "", fmt.Errorf("memos: %d %s", status, string(body))
+	}
+
+	var respData memoResponse
+	if err := json.Unmarshal(body, &respData); err != nil {
+		return "", fmt.Errorf("memos: failed to parse response: %w", err)
+	}
+	return respData.resourceName(), nil
+}
+
+// Delete 删除 memo，资源名形式的 memo 已不存在时也视为成功。
+// 只有 uid 的旧 memo_id 拼成的地址旧版本 Memos 不认识，也会返回 404，这时不能当作已删除
+func (s Client) Delete(memoID string) error {
+	body, status, err := s.do("DELETE", s.memoResourceURL(memoID), nil)
+	if err != nil {
+		return err
+	}
+	if status == http.StatusNotFound && isResourceName(memoID) {
+		logger.Println("Memo already deleted:", memoID)
+		return nil
+	}
+	if status < 200 || status > 299 {
+		return fmt.Errorf("memos: %d %s", status, string(body))
+	}
+	return nil
+}
@@ -0,0 +1,181 @@
+package memos
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"shin/internal/store"
+)
+
+// fakeMemos 模拟 Memos 的 memo 接口。legacy 为 true 时像旧版本一样只返回 uid，
+// 并且不认识 memos/{uid} 形式的地址
+type fakeMemos struct {
+	mu       sync.Mutex
+	legacy   bool
+	memos    map[string]string
+	comments map[string][]string
+	nextID   int
+}
+
+func newFakeMemos(t *testing.T, legacy bool) (*fakeMemos, Client) {
+	t.Helper()
+	t.Setenv("MEMOS_API_URL", "")
+	f := &fakeMemos{legacy: legacy, memos: map[string]string{}, comments: map[string][]string{}}
+	server := httptest.NewServer(f)
+	t.Cleanup(server.Close)
+	return f, Client{APIURL: server.URL + "/api/v1/memos", Token: "memos-token"}
+}
+
+func (f *fakeMemos) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if r.Header.Get("Authorization") != "Bearer memos-token" {
+		http.Error(w, `{"message": "unauthenticated"}`, http.StatusUnauthorized)
+		return
+	}
+	path := strings.TrimPrefix(r.URL.Path, "/api/v1/")
+	if f.legacy && strings.HasPrefix(path, "memos/") && path != "memos/" {
+		// 旧版本只有数字 ID 的路由
+		id := strings.TrimSuffix(strings.TrimPrefix(path, "memos/"), "/comments")
+		if _, err := strconv.Atoi(id); err != nil {
+			http.NotFound(w, r)
+			return
+		}
+	}
+
+	var req Request
+	if r.Body != nil {
+		json.NewDecoder(r.Body).Decode(&req)
+	}
+	switch {
+	case r.Method == http.MethodPost && path == "memos":
+		f.nextID++
+		id := strconv.Itoa(f.nextID)
+		f.memos["memos/"+id] = req.Content
+		if f.legacy {
+			json.NewEncoder(w).Encode(map[string]string{"uid": "uid" + id})
+		} else {
+			json.NewEncoder(w).Encode(map[string]string{"name": "memos/" + id})
+		}
+	case r.Method == http.MethodPost && strings.HasSuffix(path, "/comments"):
+		name := strings.TrimSuffix(path, "/comments")
+		if _, ok := f.memos[name]; !ok {
+			http.NotFound(w, r)
+			return
+		}
+		f.nextID++
+		f.comments[name] = append(f.comments[name], req.Content)
+		json.NewEncoder(w).Encode(map[string]string{"name": "memos/" + strconv.Itoa(f.nextID)})
+	case r.Method == http.MethodDelete:
+		if _, ok := f.memos[path]; !ok {
+			http.NotFound(w, r)
+			return
+		}
+		delete(f.memos, path)
+		w.Write([]byte(`{}`))
+	default:
+		http.Error(w, "unexpected request", http.StatusBadRequest)
+	}
+}
+
+func testItem() store.PostItem {
+	content, _ := json.Marshal(store.PostItemContent{CnTitle: "标题", Title: "Title", Link: "https://example.com/a"})
+	return store.PostItem{ID: "1", PostID: "p1", FeedTitle: "Example Feed", Content: string(content),
+		Notes: []store.Note{{Body: "my note"}}}
+}
+
+func TestSave(t *testing.T) {
+	f, client := newFakeMemos(t, false)
+
+	name, err := client.Save(testItem())
+	if err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	if name != "memos/1" {
+		t.Errorf("Save() = %q, want memos/1", name)
+	}
+	if want := "标题\nTitle\nhttps://example.com/a\nmy note\n#rss"; f.memos[name] != want {
+		t.Errorf("content = %q, want %q", f.memos[name], want)
+	}
+
+	client.Token = "wrong"
+	if _, err := client.Save(testItem()); err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("Save() with wrong token error = %v, want 401", err)
+	}
+}
+
+func TestSaveLegacyUID(t *testing.T) {
+	_, client := newFakeMemos(t, true)
+
+	name, err := client.Save(testItem())
+	if err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	if name != "uid1" {
+		t.Errorf("Save() = %q, want the bare uid", name)
+	}
+}
+
+func TestAppend(t *testing.T) {
+	f, client := newFakeMemos(t, false)
+	name, err := client.Save(testItem())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	comment, err := client.Append(name, "follow-up")
+	if err != nil {
+		t.Fatalf("Append() error = %v", err)
+	}
+	if comment != "memos/2" {
+		t.Errorf("Append() = %q, want memos/2", comment)
+	}
+	if got := f.comments[name]; len(got) != 1 || got[0] != "follow-up" {
+		t.Errorf("comments = %q", got)
+	}
+
+	if _, err := client.Append("memos/404", "lost"); err == nil {
+		t.Error("Append() to a missing memo error = nil")
+	}
+}
+
+func TestDelete(t *testing.T) {
+	f, client := newFakeMemos(t, false)
+	name, err := client.Save(testItem())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := client.Delete(name); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, ok := f.memos[name]; ok {
+		t.Error("memo still exists after Delete()")
+	}
+	// 资源名形式的 memo 已删除时视为成功
+	if err := client.Delete(name); err != nil {
+		t.Errorf("Delete() of a deleted memo error = %v", err)
+	}
+}
+
+func TestDeleteLegacyUIDNotFound(t *testing.T) {
+	f, client := newFakeMemos(t, true)
+	uid, err := client.Save(testItem())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 旧版本不认识 memos/{uid}，返回 404 时 memo 其实还在
+	if err := client.Delete(uid); err == nil {
+		t.Error("Delete() of a legacy uid answered with 404 error = nil")
+	}
+	if len(f.memos) != 1 {
+		t.Errorf("memos = %v, want the memo to be kept", f.memos)
+	}
+}
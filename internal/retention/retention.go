@@ -0,0 +1,181 @@
+// Package retention 按保留天数清理旧摘要，删除前可以把数据归档为按月压缩的 JSONL 文件
+package retention
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"shin/internal/store"
+)
+
+// Policy 是清理策略。ArchiveDir 为空时直接删除，不归档
+type Policy struct {
+	Days       int
+	ArchiveDir string
+}
+
+// PolicyFromEnv 读取 RETENTION_DAYS（0 表示不清理）、RETENTION_MODE（archive 或 delete）和 RETENTION_ARCHIVE_DIR
+func PolicyFromEnv() Policy {
+	days, _ := strconv.Atoi(os.Getenv("RETENTION_DAYS"))
+	policy := Policy{Days: days, ArchiveDir: os.Getenv("RETENTION_ARCHIVE_DIR")}
+	if policy.ArchiveDir == "" {
+		policy.ArchiveDir = "data/archive"
+	}
+	if os.Getenv("RETENTION_MODE") == "delete" {
+		policy.ArchiveDir = ""
+	}
+	return policy
+}
+
+func (p Policy) Enabled() bool {
+	return p.Days > 0
+}
+
+// Report 是一次清理的结果，dry run 时是将要清理的数量
+type Report struct {
+	Cutoff    time.Time
+	DryRun    bool
+	Posts     int
+	Items     int
+	KeptItems int
+	Archives  []string
+}
+
+func (r Report) String() string {
+	verb := "removed"
+	if r.DryRun {
+		verb = "would be removed"
+	}
+	s := fmt.Sprintf("before %s: %d posts and %d items %s, %d items kept",
+		r.Cutoff.Format("2006-01-02 15:04"), r.Posts, r.Items, verb, r.KeptItems)
+	for _, archive := range r.Archives {
+		s += "\n  archive: " + archive
+	}
+	return s
+}
+
+// record 是归档文件中的一行
+type record struct {
+	Type string          `json:"type"` // post, item
+	Post *store.Post     `json:"post,omitempty"`
+	Item *store.PostItem `json:"item,omitempty"`
+}
+
+// expired 是一期摘要中要清理的内容
+type expired struct {
+	post       store.Post
+	items      []store.PostItem
+	removePost bool
+}
+
+// Run 清理 policy.Days 天之前的摘要。保存过、星标、有笔记或标签的条目始终保留，
+// 摘要中还有保留的条目时摘要本身也保留。按月先写归档再删除，写归档失败时不删除
+func Run(st store.Store, policy Policy, dryRun bool) (Report, error) {
+	report := Report{Cutoff: time.Now().AddDate(0, 0, -policy.Days), DryRun: dryRun}
+	if !policy.Enabled() {
+		return report, fmt.Errorf("retention is disabled, set RETENTION_DAYS")
+	}
+
+	posts, err := st.ExpiredPosts(report.Cutoff.Unix())
+	if err != nil {
+		return report, err
+	}
+
+	var month string
+	var batch []expired
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		defer func() { batch = nil }()
+		if policy.ArchiveDir != "" {
+			path := filepath.Join(policy.ArchiveDir, "shin-"+month+".jsonl.gz")
+			report.Archives = append(report.Archives, path)
+			if !dryRun {
+				if err := writeArchive(path, batch); err != nil {
+					return err
+				}
+			}
+		}
+		for _, e := range batch {
+			if dryRun {
+				report.Items += len(e.items)
+				if e.removePost {
+					report.Posts++
+				}
+				continue
+			}
+			itemIDs := make([]string, len(e.items))
+			for i, item := range e.items {
+				itemIDs[i] = item.ID
+			}
+			deleted, postDeleted, err := st.PruneItems(e.post.ID, itemIDs)
+			if err != nil {
+				return err
+			}
+			report.Items += deleted
+			if postDeleted {
+				report.Posts++
+			}
+		}
+		return nil
+	}
+
+	for _, post := range posts {
+		items, kept, err := st.ExpiredItems(post.ID)
+		if err != nil {
+			return report, err
+		}
+		report.KeptItems += kept
+
+		postMonth := time.Unix(post.CreatedAt, 0).UTC().Format("2006-01")
+		if postMonth != month {
+			if err := flush(); err != nil {
+				return report, err
+			}
+			month = postMonth
+		}
+		batch = append(batch, expired{post: post, items: items, removePost: kept == 0})
+	}
+	if err := flush(); err != nil {
+		return report, err
+	}
+	return report, nil
+}
+
+// writeArchive 以新的 gzip 成员追加到当月文件，gzip 读取时会把多个成员连起来
+func writeArchive(path string, batch []expired) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	zw := gzip.NewWriter(f)
+	enc := json.NewEncoder(zw)
+	for _, e := range batch {
+		if e.removePost {
+			post := e.post
+			if err := enc.Encode(record{Type: "post", Post: &post}); err != nil {
+				return err
+			}
+		}
+		for i := range e.items {
+			if err := enc.Encode(record{Type: "item", Item: &e.items[i]}); err != nil {
+				return err
+			}
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return f.Sync()
+}
@@ -0,0 +1,140 @@
+package retention
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"shin/internal/store"
+)
+
+func openTestStore(t *testing.T) *store.SQLStore {
+	t.Helper()
+	name := strings.NewReplacer("/", "_", " ", "_").Replace(t.Name())
+	s, err := store.OpenSQLite(fmt.Sprintf("file:%s?mode=memory&cache=shared", name))
+	if err != nil {
+		t.Fatalf("OpenSQLite: %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+// addPost 写入一期 createdAt 创建的摘要，starred 个星标条目和 plain 个普通条目
+func addPost(t *testing.T, st store.Store, createdAt time.Time, starred, plain int) store.Post {
+	t.Helper()
+	post := store.Post{ID: store.NewID(), Title: createdAt.Format("2006-01-02"), CreatedAt: createdAt.Unix()}
+	if err := st.ImportPost(post); err != nil {
+		t.Fatal(err)
+	}
+	var items []store.PostItem
+	for i := 0; i < starred+plain; i++ {
+		content, _ := json.Marshal(store.PostItemContent{Title: fmt.Sprintf("%s #%d", post.Title, i)})
+		items = append(items, store.PostItem{ID: store.NewID(), PostID: post.ID, Content: string(content), Starred: i < starred, CreatedAt: post.CreatedAt})
+	}
+	if err := st.ImportPostItems(items); err != nil {
+		t.Fatal(err)
+	}
+	return post
+}
+
+// readArchive 读取归档中的全部记录，gzip 默认把多个成员连起来读
+func readArchive(t *testing.T, path string) []record {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var records []record
+	scanner := bufio.NewScanner(zr)
+	for scanner.Scan() {
+		var r record
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			t.Fatalf("archive line %q: %v", scanner.Text(), err)
+		}
+		records = append(records, r)
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return records
+}
+
+func TestRunDryRunMatchesRun(t *testing.T) {
+	st := openTestStore(t)
+	policy := Policy{Days: 30, ArchiveDir: t.TempDir()}
+	month := time.Date(2024, 1, 10, 0, 0, 0, 0, time.UTC)
+	addPost(t, st, month, 1, 2)
+	addPost(t, st, month.AddDate(0, 1, 0), 0, 3)
+	addPost(t, st, time.Now(), 0, 1)
+
+	dry, err := Run(st, policy, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dry.Posts != 1 || dry.Items != 5 || dry.KeptItems != 1 || len(dry.Archives) != 2 {
+		t.Fatalf("dry run = %+v", dry)
+	}
+	if _, err := os.Stat(dry.Archives[0]); !os.IsNotExist(err) {
+		t.Errorf("dry run wrote %s", dry.Archives[0])
+	}
+	if items, _, _ := st.ListItems(store.Page{Number: 1, Size: 20}); len(items) != 7 {
+		t.Errorf("dry run deleted items, %d left", len(items))
+	}
+
+	report, err := Run(st, policy, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Posts != dry.Posts || report.Items != dry.Items || report.KeptItems != dry.KeptItems ||
+		strings.Join(report.Archives, ",") != strings.Join(dry.Archives, ",") {
+		t.Errorf("run = %+v, dry run = %+v", report, dry)
+	}
+	if items, _, _ := st.ListItems(store.Page{Number: 1, Size: 20}); len(items) != 2 {
+		t.Errorf("%d items left, want the starred and the recent one", len(items))
+	}
+}
+
+func TestArchiveAppendsMonthlyFile(t *testing.T) {
+	st := openTestStore(t)
+	policy := Policy{Days: 30, ArchiveDir: t.TempDir()}
+	first := addPost(t, st, time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC), 0, 2)
+	if _, err := Run(st, policy, false); err != nil {
+		t.Fatal(err)
+	}
+
+	// 同一个月的摘要再次清理时追加到同一个文件
+	second := addPost(t, st, time.Date(2024, 3, 25, 0, 0, 0, 0, time.UTC), 1, 1)
+	report, err := Run(st, policy, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(policy.ArchiveDir, "shin-2024-03.jsonl.gz")
+	if len(report.Archives) != 1 || report.Archives[0] != path {
+		t.Fatalf("archives = %v, want %s", report.Archives, path)
+	}
+
+	var got []string
+	for _, r := range readArchive(t, path) {
+		switch r.Type {
+		case "post":
+			got = append(got, "post "+r.Post.ID)
+		case "item":
+			got = append(got, "item "+r.Item.PostID)
+		}
+	}
+	want := []string{"post " + first.ID, "item " + first.ID, "item " + first.ID, "item " + second.ID}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("archive records = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,71 @@
+package store
+
+import (
+	"crypto/rand"
+	"sync"
+	"time"
+)
+
+// crockford 是 ULID 使用的 Crockford Base32 字母表，按 ASCII 升序排列
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+var (
+	idMu      sync.Mutex
+	lastMilli int64
+	lastID    [16]byte
+)
+
+// NewID 返回 ULID：48 位毫秒时间戳加 80 位随机数，26 个字符，字符串顺序即创建顺序。
+// 同一毫秒内多次调用时随机部分递增，保证单调。早期版本的 ID 是 UnixNano 数字串，
+// 与 ULID 混在一起时字符串顺序没有意义，排序和翻页都要先比较 created_at
+func NewID() string {
+	idMu.Lock()
+	defer idMu.Unlock()
+
+	ms := time.Now().UnixMilli()
+	if ms > lastMilli {
+		rand.Read(lastID[6:])
+	} else {
+		// 同一毫秒或时钟回拨，沿用上次的时间戳；随机部分溢出时借用下一毫秒
+		ms = lastMilli
+		if incrementRandom(&lastID) {
+			ms++
+			rand.Read(lastID[6:])
+		}
+	}
+	lastMilli = ms
+	for i := 5; i >= 0; i-- {
+		lastID[i] = byte(ms)
+		ms >>= 8
+	}
+	return encodeULID(lastID)
+}
+
+// incrementRandom 把随机部分加一，溢出时返回 true
+func incrementRandom(id *[16]byte) bool {
+	for i := 15; i >= 6; i-- {
+		id[i]++
+		if id[i] != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// encodeULID 把 128 位按 5 位一组编码，最高位补两个 0 位
+func encodeULID(id [16]byte) string {
+	var out [26]byte
+	var acc uint
+	bits := 2 // 补齐到 130 位
+	n := 0
+	for _, b := range id {
+		acc = acc<<8 | uint(b)
+		bits += 8
+		for bits >= 5 {
+			bits -= 5
+			out[n] = crockford[(acc>>uint(bits))&31]
+			n++
+		}
+	}
+	return string(out[:])
+}
@@ -0,0 +1,44 @@
+package store
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewIDIsMonotonicULID(t *testing.T) {
+	before := time.Now().UnixMilli()
+	prev := NewID()
+	for i := 0; i < 10000; i++ {
+		id := NewID()
+		if len(id) != 26 || strings.Trim(id, crockford) != "" {
+			t.Fatalf("NewID() = %q, want a 26 character ULID", id)
+		}
+		if id <= prev {
+			t.Fatalf("NewID() = %q after %q, want increasing IDs", id, prev)
+		}
+		prev = id
+	}
+
+	// 前 10 个字符是毫秒时间戳
+	var ms int64
+	for _, c := range prev[:10] {
+		ms = ms<<5 | int64(strings.IndexRune(crockford, c))
+	}
+	if ms < before || ms > time.Now().UnixMilli()+1 {
+		t.Errorf("timestamp of %q = %d, want about %d", prev, ms, before)
+	}
+}
+
+func TestEncodeULID(t *testing.T) {
+	var max [16]byte
+	for i := range max {
+		max[i] = 0xff
+	}
+	if got := encodeULID(max); got != "7ZZZZZZZZZZZZZZZZZZZZZZZZZ" {
+		t.Errorf("encodeULID(max) = %q", got)
+	}
+	if got := encodeULID([16]byte{15: 1}); got != "00000000000000000000000001" {
+		t.Errorf("encodeULID(1) = %q", got)
+	}
+}
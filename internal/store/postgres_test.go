@@ -0,0 +1,29 @@
+package store
+
+import "testing"
+
+func TestRebindDollar(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"SELECT * FROM t WHERE a = ? AND b = ?", "SELECT * FROM t WHERE a = $1 AND b = $2"},
+		{"SELECT '?' || ?", "SELECT '?' || $1"},
+		{"SELECT 'it''s ?' || ?", "SELECT 'it''s ?' || $1"},
+		{`SELECT E'it\'s ?' || ?`, `SELECT E'it\'s ?' || $1`},
+		{`SELECT e'\\' || ?`, `SELECT e'\\' || $1`},
+		{`SELECT * FROM t WHERE a LIKE ? ESCAPE '\' AND b = ?`, `SELECT * FROM t WHERE a LIKE $1 ESCAPE '\' AND b = $2`},
+		{`SELECT "col?" FROM t WHERE a = ?`, `SELECT "col?" FROM t WHERE a = $1`},
+		{"SELECT $$it's ?$$ || ?", "SELECT $$it's ?$$ || $1"},
+		{"SELECT $fn$ ? $$ ? $fn$ || ?", "SELECT $fn$ ? $$ ? $fn$ || $1"},
+		{"SELECT $1::text || ?", "SELECT $1::text || $1"},
+		{"SELECT a$b$ FROM t WHERE a = ?", "SELECT a$b$ FROM t WHERE a = $1"},
+		{"SELECT '标题?' || ?", "SELECT '标题?' || $1"},
+		{"SELECT 'unterminated ?", "SELECT 'unterminated ?"},
+	}
+	for _, tt := range tests {
+		if got := rebindDollar(tt.query); got != tt.want {
+			t.Errorf("rebindDollar(%q) = %q, want %q", tt.query, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,172 @@
+package store
+
+import "fmt"
+
+type migration struct {
+	name string
+	sql  string
+}
+
+// schemaTables 是两种数据库共用的建表语句。时间列是 Unix 秒，read_at 为 NULL 表示未读
+var schemaTables = []migration{
+	{"create shin_post", `CREATE TABLE IF NOT EXISTS shin_post (
+		id TEXT PRIMARY KEY,
+		title TEXT,
+		created_at BIGINT NOT NULL DEFAULT 0,
+		read_at BIGINT,
+		summary TEXT DEFAULT '',
+		tags TEXT DEFAULT '[]'
+	);`},
+	{"create shin_post_item", `CREATE TABLE IF NOT EXISTS shin_post_item (
+		id TEXT PRIMARY KEY,
+		post_id TEXT,
+		feed_title TEXT,
+		content TEXT,
+		memo_id TEXT,
+		upstream_id TEXT DEFAULT '',
+		read INTEGER DEFAULT 0,
+		starred INTEGER DEFAULT 0,
+		star_synced INTEGER DEFAULT 1,
+		created_at BIGINT NOT NULL DEFAULT 0,
+		guid TEXT DEFAULT ''
+	);`},
+	{"create shin_key_value", `CREATE TABLE IF NOT EXISTS shin_key_value (
+		id TEXT PRIMARY KEY,
+		key TEXT,
+		value TEXT,
+		created_at BIGINT NOT NULL DEFAULT 0
+	);`},
+	{"create shin_article", `CREATE TABLE IF NOT EXISTS shin_article (
+		item_id TEXT PRIMARY KEY,
+		url TEXT,
+		title TEXT,
+		html TEXT,
+		text TEXT,
+		status TEXT,
+		error TEXT,
+		fetched_at BIGINT NOT NULL DEFAULT 0
+	);`},
+	{"create shin_saved", `CREATE TABLE IF NOT EXISTS shin_saved (
+		item_id TEXT,
+		sink TEXT,
+		external_id TEXT,
+		saved_at BIGINT NOT NULL DEFAULT 0,
+		PRIMARY KEY (item_id, sink)
+	);`},
+	{"create shin_outbox", `CREATE TABLE IF NOT EXISTS shin_outbox (
+		id TEXT PRIMARY KEY,
+		item_id TEXT,
+		sink TEXT,
+		idempotency_key TEXT UNIQUE,
+		status TEXT,
+		attempts INTEGER,
+		next_attempt_at BIGINT NOT NULL DEFAULT 0,
+		last_error TEXT,
+		external_id TEXT,
+		created_at BIGINT NOT NULL DEFAULT 0,
+		note_id TEXT DEFAULT ''
+	);`},
+	{"create shin_note", `CREATE TABLE IF NOT EXISTS shin_note (
+		id TEXT PRIMARY KEY,
+		item_id TEXT,
+		body TEXT,
+		created_at BIGINT NOT NULL DEFAULT 0,
+		updated_at BIGINT NOT NULL DEFAULT 0
+	);`},
+	{"create shin_tag", `CREATE TABLE IF NOT EXISTS shin_tag (
+		id TEXT PRIMARY KEY,
+		name TEXT UNIQUE,
+		created_at BIGINT NOT NULL DEFAULT 0
+	);`},
+	{"create shin_item_tag", `CREATE TABLE IF NOT EXISTS shin_item_tag (
+		item_id TEXT,
+		tag_id TEXT,
+		created_at BIGINT NOT NULL DEFAULT 0,
+		PRIMARY KEY (item_id, tag_id)
+	);`},
+	{"create shin_feed", `CREATE TABLE IF NOT EXISTS shin_feed (
+		id TEXT PRIMARY KEY,
+		url TEXT UNIQUE,
+		title TEXT,
+		category TEXT DEFAULT '',
+		enabled INTEGER DEFAULT 1,
+		translate TEXT DEFAULT 'translate',
+		upstream_id TEXT DEFAULT '',
+		created_at BIGINT NOT NULL DEFAULT 0,
+		updated_at BIGINT NOT NULL DEFAULT 0,
+		deleted_at BIGINT NOT NULL DEFAULT 0
+	);`},
+}
+
+// createTable 返回 table 的建表语句
+func createTable(table string) string {
+	for _, m := range schemaTables {
+		if m.name == "create "+table {
+			return m.sql
+		}
+	}
+	return ""
+}
+
+// timeColumns 是早期版本以 TEXT 保存的时间列，迁移时转为整数；旧库用 "0" 表示未读
+var timeColumns = map[string][]string{
+	"shin_post":      {"created_at", "read_at"},
+	"shin_key_value": {"created_at"},
+	"shin_article":   {"fetched_at"},
+	"shin_saved":     {"saved_at"},
+	"shin_outbox":    {"next_attempt_at", "created_at"},
+	"shin_note":      {"created_at", "updated_at"},
+	"shin_tag":       {"created_at"},
+	"shin_item_tag":  {"created_at"},
+}
+
+// nullableTimeColumn 的列用 NULL 表示没有时间，其余时间列默认为 0
+func nullableTimeColumn(table, column string) bool {
+	return table == "shin_post" && column == "read_at"
+}
+
+// timeColumnValue 是把旧的 TEXT 时间转为整数的表达式
+func timeColumnValue(table, column string) string {
+	if nullableTimeColumn(table, column) {
+		return "CAST(NULLIF(NULLIF(" + column + ", ''), '0') AS BIGINT)"
+	}
+	return "COALESCE(CAST(NULLIF(" + column + ", '') AS BIGINT), 0)"
+}
+
+// schemaColumns 是建表之后新增的列，新建的表已经包含，旧库按需补齐
+var schemaColumns = []struct{ table, column, definition string }{
+	{"shin_post_item", "upstream_id", "TEXT DEFAULT ''"},
+	{"shin_post_item", "read", "INTEGER DEFAULT 0"},
+	{"shin_post_item", "starred", "INTEGER DEFAULT 0"},
+	{"shin_post_item", "star_synced", "INTEGER DEFAULT 1"},
+	{"shin_post_item", "created_at", "BIGINT NOT NULL DEFAULT 0"},
+	{"shin_post_item", "guid", "TEXT DEFAULT ''"},
+	{"shin_outbox", "note_id", "TEXT DEFAULT ''"},
+	{"shin_post", "summary", "TEXT DEFAULT ''"},
+	{"shin_post", "tags", "TEXT DEFAULT '[]'"},
+	{"shin_feed", "deleted_at", "BIGINT NOT NULL DEFAULT 0"},
+}
+
+// schemaIndexes 覆盖详情、重要订阅、分页和关联子查询用到的过滤条件
+var schemaIndexes = []migration{
+	{"create shin_post_item_post_idx", `CREATE INDEX IF NOT EXISTS shin_post_item_post_idx ON shin_post_item (post_id, id);`},
+	{"create shin_post_item_feed_idx", `CREATE INDEX IF NOT EXISTS shin_post_item_feed_idx ON shin_post_item (feed_title, id);`},
+	{"create shin_post_item_created_idx", `CREATE INDEX IF NOT EXISTS shin_post_item_created_idx ON shin_post_item (created_at, id);`},
+	{"create shin_post_item_guid_idx", `CREATE INDEX IF NOT EXISTS shin_post_item_guid_idx ON shin_post_item (guid);`},
+	{"create shin_post_item_upstream_idx", `CREATE INDEX IF NOT EXISTS shin_post_item_upstream_idx ON shin_post_item (upstream_id);`},
+	{"create shin_post_created_idx", `CREATE INDEX IF NOT EXISTS shin_post_created_idx ON shin_post (created_at, id);`},
+	{"create shin_key_value_key_idx", `CREATE INDEX IF NOT EXISTS shin_key_value_key_idx ON shin_key_value (key);`},
+	{"create shin_note_item_idx", `CREATE INDEX IF NOT EXISTS shin_note_item_idx ON shin_note (item_id);`},
+	{"create shin_item_tag_tag_idx", `CREATE INDEX IF NOT EXISTS shin_item_tag_tag_idx ON shin_item_tag (tag_id);`},
+	{"create shin_outbox_item_idx", `CREATE INDEX IF NOT EXISTS shin_outbox_item_idx ON shin_outbox (item_id, status);`},
+	{"create shin_outbox_due_idx", `CREATE INDEX IF NOT EXISTS shin_outbox_due_idx ON shin_outbox (status, next_attempt_at);`},
+}
+
+func runMigrations(db sqlDB, migrations []migration) error {
+	for _, m := range migrations {
+		if _, err := db.Exec(m.sql); err != nil {
+			return fmt.Errorf("failed to %s: %w", m.name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,161 @@
+package store
+
+import (
+	"fmt"
+	"strings"
+
+	_ "modernc.org/sqlite" // SQLite driver
+)
+
+var sqliteDialect = dialect{
+	driver: "sqlite",
+	rebind: func(query string) string { return query },
+	// trigram 分词可以直接用 LIKE 匹配中文子串
+	search: func(keyword string) (string, []interface{}) {
+		return `SELECT item_id FROM shin_search WHERE text LIKE ? ESCAPE '\'`, []interface{}{containsPattern(keyword)}
+	},
+	migrate:      migrateSQLite,
+	singleWriter: true,
+}
+
+// sqlitePragmas 在每个连接打开时执行：WAL 让读写互不阻塞，busy_timeout 让写锁冲突时等待而不是立即报错
+var sqlitePragmas = []string{
+	"busy_timeout(5000)",
+	"journal_mode(WAL)",
+	"synchronous(NORMAL)",
+}
+
+// OpenSQLite 打开数据库并建表，旧版本的库会补齐缺少的列和索引
+func OpenSQLite(path string) (*SQLStore, error) {
+	sep := "?"
+	if strings.Contains(path, "?") {
+		sep = "&"
+	}
+	for _, pragma := range sqlitePragmas {
+		path += sep + "_pragma=" + pragma
+		sep = "&"
+	}
+	return openSQL(sqliteDialect, path)
+}
+
+func migrateSQLite(db sqlDB) error {
+	if err := runMigrations(db, schemaTables); err != nil {
+		return err
+	}
+	for _, col := range schemaColumns {
+		if err := addColumnIfNotExists(db, col.table, col.column, col.definition); err != nil {
+			return err
+		}
+	}
+	if err := migrateSQLiteTimeColumns(db); err != nil {
+		return err
+	}
+	if err := runMigrations(db, schemaIndexes); err != nil {
+		return err
+	}
+
+	return runMigrations(db, []migration{
+		// 已有的 memo_id 迁移为 memos 目标的保存记录
+		{"backfill shin_saved", `INSERT INTO shin_saved (item_id, sink, external_id, saved_at)
+			SELECT id, 'memos', memo_id, 0 FROM shin_post_item WHERE memo_id != ''
+			ON CONFLICT DO NOTHING;`},
+		// 旧条目的 id 是创建时的 UnixNano
+		{"backfill shin_post_item.created_at", `UPDATE shin_post_item SET created_at = CAST(id AS INTEGER) / 1000000000
+			WHERE created_at = 0 AND id != '' AND id NOT GLOB '*[^0-9]*';`},
+		// 全文索引，trigram 分词可以直接匹配中文子串
+		{"create shin_search", `CREATE VIRTUAL TABLE IF NOT EXISTS shin_search USING fts5(
+			item_id UNINDEXED,
+			kind UNINDEXED,
+			text,
+			tokenize = 'trigram'
+		);`},
+		// 为已有条目补建标题索引
+		{"backfill shin_search", `INSERT INTO shin_search (item_id, kind, text)
+			SELECT id, 'title', json_extract(content, '$.cnTitle') || char(10) || json_extract(content, '$.title') || char(10) || json_extract(content, '$.link')
+			FROM shin_post_item WHERE id NOT IN (SELECT item_id FROM shin_search WHERE kind = 'title');`},
+	})
+}
+
+func addColumnIfNotExists(db sqlDB, table, column, definition string) error {
+	var count int
+	err := db.QueryRow("SELECT COUNT(*) FROM pragma_table_info(?) WHERE name = ?", table, column).Scan(&count)
+	if err != nil {
+		return fmt.Errorf("failed to inspect %s: %w", table, err)
+	}
+	if count > 0 {
+		return nil
+	}
+	if _, err := db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition)); err != nil {
+		return fmt.Errorf("failed to add %s.%s: %w", table, column, err)
+	}
+	return nil
+}
+
+// migrateSQLiteTimeColumns 把旧库的 TEXT 时间列转为整数。SQLite 不能修改列类型，只能重建表
+func migrateSQLiteTimeColumns(db sqlDB) error {
+	for _, m := range schemaTables {
+		table := strings.TrimPrefix(m.name, "create ")
+		columns := timeColumns[table]
+		if len(columns) == 0 {
+			continue
+		}
+		var columnType string
+		err := db.QueryRow("SELECT type FROM pragma_table_info(?) WHERE name = ?", table, columns[0]).Scan(&columnType)
+		if err != nil {
+			return fmt.Errorf("failed to inspect %s: %w", table, err)
+		}
+		if !strings.EqualFold(columnType, "TEXT") {
+			continue
+		}
+		if err := rebuildSQLiteTable(db, table, columns); err != nil {
+			return fmt.Errorf("failed to migrate %s: %w", table, err)
+		}
+		logger.Printf("Migrated %s time columns to integers", table)
+	}
+	return nil
+}
+
+func rebuildSQLiteTable(db sqlDB, table string, timeColumns []string) error {
+	rows, err := db.Query("SELECT name FROM pragma_table_info(?) ORDER BY cid", table)
+	if err != nil {
+		return err
+	}
+	var columns, values []string
+	for rows.Next() {
+		var column string
+		if err := rows.Scan(&column); err != nil {
+			rows.Close()
+			return err
+		}
+		value := column
+		for _, timeColumn := range timeColumns {
+			if column == timeColumn {
+				value = timeColumnValue(table, column)
+			}
+		}
+		columns = append(columns, column)
+		values = append(values, value)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	// 旧表的索引随旧表一起删除，之后由 schemaIndexes 重建
+	for _, query := range []string{
+		"ALTER TABLE " + table + " RENAME TO " + table + "_old",
+		createTable(table),
+		"INSERT INTO " + table + " (" + strings.Join(columns, ", ") + ") SELECT " + strings.Join(values, ", ") + " FROM " + table + "_old",
+		"DROP TABLE " + table + "_old",
+	} {
+		if _, err := tx.Exec(query); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
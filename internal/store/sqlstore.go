@@ -0,0 +1,1255 @@
+package store
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"shin/internal/config"
+)
+
+var logger = config.Logger
+
+// dialect 是 SQLite 和 PostgreSQL 之间有差异的部分，其余 SQL 两者共用
+type dialect struct {
+	driver string
+	// rebind 把查询中的 ? 占位符转换为数据库的格式
+	rebind func(query string) string
+	// search 返回全文检索条目 ID 的子查询及参数
+	search  func(keyword string) (string, []interface{})
+	migrate func(db sqlDB) error
+	// singleWriter 为 true 时写操作走单独的单连接池，避免多个连接同时写库
+	singleWriter bool
+}
+
+// SQLStore 是基于 database/sql 的 Store 实现，SQLite 和 PostgreSQL 共用
+type SQLStore struct {
+	db      sqlDB
+	dialect dialect
+}
+
+var _ Store = (*SQLStore)(nil)
+
+// Open 按 DSN 打开存储：postgres:// 或 postgresql:// 开头时使用 PostgreSQL，否则视为 SQLite 文件路径
+func Open(dsn string) (*SQLStore, error) {
+	if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") {
+		return OpenPostgres(dsn)
+	}
+	return OpenSQLite(dsn)
+}
+
+// openSQL 打开数据库并执行迁移
+func openSQL(d dialect, dsn string) (*SQLStore, error) {
+	db, err := sql.Open(d.driver, dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect database: %w", err)
+	}
+	writer := db
+	if d.singleWriter {
+		if writer, err = sql.Open(d.driver, dsn); err != nil {
+			db.Close()
+			return nil, fmt.Errorf("failed to connect database: %w", err)
+		}
+		writer.SetMaxOpenConns(1)
+	}
+	s := &SQLStore{db: sqlDB{DB: db, writer: writer, rebind: d.rebind}, dialect: d}
+	if err := d.migrate(s.db); err != nil {
+		s.db.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *SQLStore) Close() error {
+	return s.db.Close()
+}
+
+// sqlDB 和 sqlTx 在执行前转换占位符，查询统一用 ? 书写
+// 读操作走 DB，写操作和事务走 writer
+type sqlDB struct {
+	*sql.DB
+	writer *sql.DB
+	rebind func(string) string
+}
+
+func (db sqlDB) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return db.writer.Exec(db.rebind(query), args...)
+}
+
+func (db sqlDB) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	return db.DB.Query(db.rebind(query), args...)
+}
+
+func (db sqlDB) QueryRow(query string, args ...interface{}) *sql.Row {
+	return db.DB.QueryRow(db.rebind(query), args...)
+}
+
+func (db sqlDB) Begin() (sqlTx, error) {
+	tx, err := db.writer.Begin()
+	return sqlTx{Tx: tx, rebind: db.rebind}, err
+}
+
+func (db sqlDB) Close() error {
+	if db.writer != db.DB {
+		db.writer.Close()
+	}
+	return db.DB.Close()
+}
+
+type sqlTx struct {
+	*sql.Tx
+	rebind func(string) string
+}
+
+func (tx sqlTx) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return tx.Tx.Exec(tx.rebind(query), args...)
+}
+
+func (tx sqlTx) QueryRow(query string, args ...interface{}) *sql.Row {
+	return tx.Tx.QueryRow(tx.rebind(query), args...)
+}
+
+func (tx sqlTx) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	return tx.Tx.Query(tx.rebind(query), args...)
+}
+
+func (tx sqlTx) Prepare(query string) (*sql.Stmt, error) {
+	return tx.Tx.Prepare(tx.rebind(query))
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// likeEscaper 转义 LIKE 的通配符，配合 ESCAPE '\' 使用
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// containsPattern 返回匹配包含 keyword 的 LIKE 模式，keyword 中的 % 和 _ 按字面匹配
+func containsPattern(keyword string) string {
+	return "%" + likeEscaper.Replace(keyword) + "%"
+}
+
+// execer 兼容 sqlDB 和 sqlTx
+type execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+func (s *SQLStore) GetKeyValue(key string) (string, error) {
+	var value string
+	err := s.db.QueryRow("SELECT value FROM shin_key_value WHERE key = ?", key).Scan(&value)
+	return value, err
+}
+
+func (s *SQLStore) SetKeyValue(key, value string) error {
+	now := time.Now()
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	if _, err := tx.Exec(`DELETE FROM shin_key_value WHERE key = ?;`, key); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to delete %s: %w", key, err)
+	}
+	_, err = tx.Exec(`INSERT INTO shin_key_value (id, key, value, created_at) VALUES (?, ?, ?, ?);`,
+		NewID(), key, value, now.Unix())
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to insert %s: %w", key, err)
+	}
+	return tx.Commit()
+}
+
+// CutPost 把未分配的条目归入新的一期，没有条目时不创建 post
+func (s *SQLStore) CutPost(postID, title string) (int64, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	result, err := tx.Exec("UPDATE shin_post_item SET post_id = ? WHERE post_id = ''", postID)
+	if err != nil {
+		tx.Rollback()
+		return 0, fmt.Errorf("failed to assign post items: %w", err)
+	}
+	count, _ := result.RowsAffected()
+	if count == 0 {
+		tx.Rollback()
+		return 0, nil
+	}
+
+	_, err = tx.Exec("INSERT INTO shin_post (id, title, created_at) VALUES (?, ?, ?)",
+		postID, title, time.Now().Unix())
+	if err != nil {
+		tx.Rollback()
+		return 0, fmt.Errorf("failed to insert post: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return count, nil
+}
+
+const postColumns = "id, title, created_at, read_at, summary, tags"
+
+func scanPost(row rowScanner) (Post, error) {
+	var post Post
+	var tagsJSON string
+	if err := row.Scan(&post.ID, &post.Title, &post.CreatedAt, &post.ReadAt, &post.Summary, &tagsJSON); err != nil {
+		return post, err
+	}
+	json.Unmarshal([]byte(tagsJSON), &post.Tags)
+	return post, nil
+}
+
+func (s *SQLStore) GetPost(postID string) (*Post, error) {
+	post, err := scanPost(s.db.QueryRow("SELECT "+postColumns+" FROM shin_post WHERE id = ?", postID))
+	if err != nil {
+		return nil, err
+	}
+	return &post, nil
+}
+
+// ListPosts 按创建时间倒序分页，页码翻页时同时返回总数
+func (s *SQLStore) ListPosts(page Page) ([]Post, PageInfo, error) {
+	where := "1 = 1"
+	var args []interface{}
+	if page.Cursor != nil {
+		where = "(created_at < ? OR (created_at = ? AND id < ?))"
+		args = append(args, page.Cursor.CreatedAt, page.Cursor.CreatedAt, page.Cursor.ID)
+	}
+	args = append(args, page.Size+1, page.Offset())
+
+	rows, err := s.db.Query("SELECT "+postColumns+" FROM shin_post WHERE "+where+" ORDER BY created_at DESC, id DESC LIMIT ? OFFSET ?", args...)
+	if err != nil {
+		return nil, PageInfo{}, fmt.Errorf("failed to query posts: %w", err)
+	}
+	defer rows.Close()
+
+	posts := []Post{}
+	for rows.Next() {
+		post, err := scanPost(rows)
+		if err != nil {
+			return nil, PageInfo{}, fmt.Errorf("failed to scan post: %w", err)
+		}
+		posts = append(posts, post)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, PageInfo{}, err
+	}
+
+	info, n := pageInfo(page, len(posts), func(i int) Cursor {
+		return Cursor{CreatedAt: posts[i].CreatedAt, ID: posts[i].ID}
+	})
+	if page.Cursor == nil {
+		var total int64
+		if err := s.db.QueryRow("SELECT COUNT(*) FROM shin_post").Scan(&total); err != nil {
+			return nil, PageInfo{}, fmt.Errorf("failed to count posts: %w", err)
+		}
+		info.setTotal(total)
+	}
+	return posts[:n], info, nil
+}
+
+func (s *SQLStore) UpdatePostSummary(postID, summary string, tags []string) error {
+	if tags == nil {
+		tags = []string{}
+	}
+	tagsJSON, _ := json.Marshal(tags)
+	_, err := s.db.Exec("UPDATE shin_post SET summary = ?, tags = ? WHERE id = ?", summary, string(tagsJSON), postID)
+	if err != nil {
+		return fmt.Errorf("failed to update summary: %w", err)
+	}
+	return nil
+}
+
+// MarkPostRead 标记一期摘要及其条目为已读
+func (s *SQLStore) MarkPostRead(postID string) ([]string, error) {
+	result, err := s.db.Exec("UPDATE shin_post SET read_at = ? WHERE id = ?", time.Now().Unix(), postID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update post: %w", err)
+	}
+	if count, _ := result.RowsAffected(); count == 0 {
+		return nil, ErrNotFound
+	}
+	if _, err := s.db.Exec("UPDATE shin_post_item SET read = 1 WHERE post_id = ?", postID); err != nil {
+		return nil, fmt.Errorf("failed to update post items: %w", err)
+	}
+	return s.upstreamIDsOf("SELECT upstream_id FROM shin_post_item WHERE post_id = ? AND upstream_id != ''", postID)
+}
+
+func (s *SQLStore) InsertPostItems(items []PostItem) error {
+	// 启动事务
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	// 准备插入SQL
+	stmt, err := tx.Prepare(`INSERT INTO shin_post_item (id, post_id, feed_title, content, memo_id, upstream_id, created_at, guid) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to prepare insert statement: %w", err)
+	}
+	defer stmt.Close()
+
+	searchStmt, err := tx.Prepare(`INSERT INTO shin_search (item_id, kind, text) VALUES (?, 'title', ?)`)
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to prepare search statement: %w", err)
+	}
+	defer searchStmt.Close()
+
+	// 批量插入
+	for _, item := range items {
+		// TODO query before insert
+		_, err := stmt.Exec(item.ID, item.PostID, item.FeedTitle, item.Content, item.MemoID, item.UpstreamID, itemCreatedAt(item), item.GUID)
+		if err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to execute insert statement: %w", err)
+		}
+
+		if _, err := searchStmt.Exec(item.ID, itemSearchText(item)); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to index item: %w", err)
+		}
+
+		for _, tag := range item.Tags {
+			if err := addItemTag(tx, item.ID, tag); err != nil {
+				tx.Rollback()
+				return err
+			}
+		}
+	}
+
+	// 提交事务
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// itemCreatedAt 是条目写入的 created_at。旧备份没有这个字段，id 是创建时的 UnixNano
+func itemCreatedAt(item PostItem) int64 {
+	if item.CreatedAt != 0 {
+		return item.CreatedAt
+	}
+	if nano, err := strconv.ParseInt(item.ID, 10, 64); err == nil && nano > 1e18 {
+		return nano / 1e9
+	}
+	return time.Now().Unix()
+}
+
+// itemSearchText 是条目在全文索引中的标题文本
+func itemSearchText(item PostItem) string {
+	var content PostItemContent
+	json.Unmarshal([]byte(item.Content), &content)
+	return content.CnTitle + "\n" + content.Title + "\n" + content.Link
+}
+
+// postItemColumns 与 scanPostItem 对应，查询时不能给 shin_post_item 起别名
+const postItemColumns = `shin_post_item.id, shin_post_item.post_id, shin_post_item.feed_title, shin_post_item.content, shin_post_item.memo_id,
+	shin_post_item.upstream_id, shin_post_item.read, shin_post_item.starred, shin_post_item.created_at, shin_post_item.guid,
+	(SELECT COALESCE(string_agg(sink, ','), '') FROM shin_saved WHERE shin_saved.item_id = shin_post_item.id),
+	(SELECT COALESCE(string_agg(sink, ','), '') FROM shin_outbox WHERE shin_outbox.item_id = shin_post_item.id AND shin_outbox.status = 'pending'),
+	(SELECT COALESCE(string_agg(shin_tag.name, ','), '') FROM shin_item_tag JOIN shin_tag ON shin_tag.id = shin_item_tag.tag_id WHERE shin_item_tag.item_id = shin_post_item.id)`
+
+func scanPostItem(row rowScanner) (PostItem, error) {
+	var item PostItem
+	var saved, pending, tags string
+	if err := row.Scan(&item.ID, &item.PostID, &item.FeedTitle, &item.Content, &item.MemoID,
+		&item.UpstreamID, &item.Read, &item.Starred, &item.CreatedAt, &item.GUID, &saved, &pending, &tags); err != nil {
+		return item, err
+	}
+	item.Saved = splitList(saved)
+	item.Pending = splitList(pending)
+	item.Tags = splitList(tags)
+	return item, nil
+}
+
+func (s *SQLStore) GetPostItem(itemID string) (*PostItem, error) {
+	item, err := scanPostItem(s.db.QueryRow(`SELECT `+postItemColumns+` FROM shin_post_item WHERE id = ?`, itemID))
+	if err != nil {
+		return nil, err
+	}
+	return &item, nil
+}
+
+func (s *SQLStore) queryPostItems(query string, args ...interface{}) ([]PostItem, error) {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query post items: %w", err)
+	}
+	defer rows.Close()
+
+	var items []PostItem
+	for rows.Next() {
+		item, err := scanPostItem(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+// pagePostItems 在 where 条件下按 (created_at, id) 倒序分页查询条目
+func (s *SQLStore) pagePostItems(where string, args []interface{}, page Page) ([]PostItem, PageInfo, error) {
+	if page.Cursor != nil {
+		where += " AND (shin_post_item.created_at < ? OR (shin_post_item.created_at = ? AND shin_post_item.id < ?))"
+		args = append(args, page.Cursor.CreatedAt, page.Cursor.CreatedAt, page.Cursor.ID)
+	}
+	args = append(args, page.Size+1, page.Offset())
+
+	items, err := s.queryPostItems(`SELECT `+postItemColumns+` FROM shin_post_item WHERE `+where+`
+		ORDER BY shin_post_item.created_at DESC, shin_post_item.id DESC LIMIT ? OFFSET ?`, args...)
+	if err != nil {
+		return nil, PageInfo{}, err
+	}
+	info, n := pageInfo(page, len(items), func(i int) Cursor {
+		return Cursor{CreatedAt: items[i].CreatedAt, ID: items[i].ID}
+	})
+	return items[:n], info, nil
+}
+
+func (s *SQLStore) PostItems(postID string) ([]PostItem, error) {
+	return s.queryPostItems(`SELECT `+postItemColumns+` FROM shin_post_item WHERE post_id = ? ORDER BY created_at, id`, postID)
+}
+
+func (s *SQLStore) ItemsOfPostsBetween(excludePostID string, since, until int64) ([]PostItem, error) {
+	return s.queryPostItems(`SELECT `+postItemColumns+` FROM shin_post_item
+		JOIN shin_post p ON p.id = shin_post_item.post_id
+		WHERE shin_post_item.post_id != ? AND p.created_at BETWEEN ? AND ?`, excludePostID, since, until)
+}
+
+// SearchItems 通过全文索引匹配标题、正文和笔记，新的在前
+func (s *SQLStore) SearchItems(keyword string, page Page) ([]PostItem, PageInfo, error) {
+	subquery, args := s.dialect.search(keyword)
+	return s.pagePostItems(`shin_post_item.id IN (`+subquery+`)`, args, page)
+}
+
+func (s *SQLStore) ItemsByFeeds(feedTitles []string, page Page) ([]PostItem, PageInfo, error) {
+	var placeholders []string
+	var args []interface{}
+	for _, feedTitle := range feedTitles {
+		placeholders = append(placeholders, "?")
+		args = append(args, feedTitle)
+	}
+	return s.pagePostItems(`shin_post_item.feed_title IN (`+strings.Join(placeholders, ",")+`)`, args, page)
+}
+
+func (s *SQLStore) ListItems(page Page) ([]PostItem, PageInfo, error) {
+	return s.pagePostItems(`1 = 1`, nil, page)
+}
+
+func (s *SQLStore) ItemsByTag(tag string, page Page) ([]PostItem, PageInfo, error) {
+	return s.pagePostItems(`shin_post_item.id IN (SELECT item_id FROM shin_item_tag JOIN shin_tag ON shin_tag.id = shin_item_tag.tag_id WHERE shin_tag.name = ?)`,
+		[]interface{}{tag}, page)
+}
+
+// FeedCounts 列出出现过的订阅及条目数
+func (s *SQLStore) FeedCounts() ([]FeedCount, error) {
+	rows, err := s.db.Query("SELECT feed_title, COUNT(*) FROM shin_post_item GROUP BY feed_title ORDER BY feed_title")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	feeds := []FeedCount{}
+	for rows.Next() {
+		var feed FeedCount
+		if err := rows.Scan(&feed.Title, &feed.ItemCount); err != nil {
+			return nil, err
+		}
+		feeds = append(feeds, feed)
+	}
+	return feeds, rows.Err()
+}
+
+func (s *SQLStore) KnownGUIDs(guids []string) (map[string]bool, error) {
+	known := make(map[string]bool)
+	if len(guids) == 0 {
+		return known, nil
+	}
+	args := make([]interface{}, len(guids))
+	for i, guid := range guids {
+		args[i] = guid
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(guids)), ",")
+	found, err := s.upstreamIDsOf(`SELECT guid FROM shin_post_item WHERE guid IN (`+placeholders+`)`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query guids: %w", err)
+	}
+	for _, guid := range found {
+		known[guid] = true
+	}
+	return known, nil
+}
+
+func (s *SQLStore) upstreamIDsOf(query string, args ...interface{}) ([]string, error) {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+func (s *SQLStore) setItemState(itemID, assignments string, on bool) (string, error) {
+	value := 0
+	if on {
+		value = 1
+	}
+	result, err := s.db.Exec("UPDATE shin_post_item SET "+assignments+" WHERE id = ?", value, itemID)
+	if err != nil {
+		return "", err
+	}
+	if count, _ := result.RowsAffected(); count == 0 {
+		return "", ErrNotFound
+	}
+	var upstreamID string
+	err = s.db.QueryRow("SELECT upstream_id FROM shin_post_item WHERE id = ?", itemID).Scan(&upstreamID)
+	return upstreamID, err
+}
+
+func (s *SQLStore) SetItemRead(itemID string, read bool) (string, error) {
+	return s.setItemState(itemID, "read = ?", read)
+}
+
+func (s *SQLStore) SetItemStarred(itemID string, starred bool) (string, error) {
+	return s.setItemState(itemID, "starred = ?, star_synced = CASE WHEN upstream_id != '' THEN 0 ELSE 1 END", starred)
+}
+
+func (s *SQLStore) UnsyncedStars() ([]string, []string, error) {
+	starred, err := s.upstreamIDsOf("SELECT upstream_id FROM shin_post_item WHERE star_synced = 0 AND starred = 1 AND upstream_id != ''")
+	if err != nil {
+		return nil, nil, err
+	}
+	unstarred, err := s.upstreamIDsOf("SELECT upstream_id FROM shin_post_item WHERE star_synced = 0 AND starred = 0 AND upstream_id != ''")
+	if err != nil {
+		return nil, nil, err
+	}
+	return starred, unstarred, nil
+}
+
+func (s *SQLStore) MarkStarSynced(upstreamIDs []string, starred bool) error {
+	value := 0
+	if starred {
+		value = 1
+	}
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	for _, id := range upstreamIDs {
+		if _, err := tx.Exec("UPDATE shin_post_item SET star_synced = 1 WHERE upstream_id = ? AND starred = ?", id, value); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (s *SQLStore) ApplyUpstreamState(readIDs, starredIDs []string) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	for _, id := range readIDs {
+		if _, err := tx.Exec("UPDATE shin_post_item SET read = 1 WHERE upstream_id = ?", id); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	// 星标列表是完整的，不在列表中的条目取消星标；本地改过、还没同步到上游的条目以本地为准
+	if _, err := tx.Exec("UPDATE shin_post_item SET starred = 0 WHERE starred = 1 AND upstream_id != '' AND star_synced = 1"); err != nil {
+		tx.Rollback()
+		return err
+	}
+	for _, id := range starredIDs {
+		if _, err := tx.Exec("UPDATE shin_post_item SET starred = 1 WHERE upstream_id = ? AND star_synced = 1", id); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+const noteColumns = "id, item_id, body, created_at, updated_at"
+
+func scanNote(row rowScanner) (Note, error) {
+	var n Note
+	err := row.Scan(&n.ID, &n.ItemID, &n.Body, &n.CreatedAt, &n.UpdatedAt)
+	return n, err
+}
+
+func noteSearchKind(noteID string) string {
+	return "note:" + noteID
+}
+
+func (s *SQLStore) InsertNote(itemID, body string) (*Note, error) {
+	now := time.Now()
+	note := Note{
+		ID:        NewID(),
+		ItemID:    itemID,
+		Body:      body,
+		CreatedAt: now.Unix(),
+		UpdatedAt: now.Unix(),
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	_, err = tx.Exec(`INSERT INTO shin_note (`+noteColumns+`) VALUES (?, ?, ?, ?, ?)`,
+		note.ID, note.ItemID, note.Body, note.CreatedAt, note.UpdatedAt)
+	if err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("failed to insert note: %w", err)
+	}
+	// 笔记加入全文索引
+	if _, err := tx.Exec(`INSERT INTO shin_search (item_id, kind, text) VALUES (?, ?, ?)`, itemID, noteSearchKind(note.ID), body); err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("failed to index note: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return &note, nil
+}
+
+func (s *SQLStore) UpdateNote(noteID, body string) (*Note, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	result, err := tx.Exec(`UPDATE shin_note SET body = ?, updated_at = ? WHERE id = ?`,
+		body, time.Now().Unix(), noteID)
+	if err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("failed to update note: %w", err)
+	}
+	if count, _ := result.RowsAffected(); count == 0 {
+		tx.Rollback()
+		return nil, ErrNotFound
+	}
+	if _, err := tx.Exec(`UPDATE shin_search SET text = ? WHERE kind = ?`, body, noteSearchKind(noteID)); err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("failed to index note: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return s.GetNote(noteID)
+}
+
+func (s *SQLStore) DeleteNote(noteID string) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	result, err := tx.Exec(`DELETE FROM shin_note WHERE id = ?`, noteID)
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to delete note: %w", err)
+	}
+	if count, _ := result.RowsAffected(); count == 0 {
+		tx.Rollback()
+		return ErrNotFound
+	}
+	if _, err := tx.Exec(`DELETE FROM shin_search WHERE kind = ?`, noteSearchKind(noteID)); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to delete note index: %w", err)
+	}
+	return tx.Commit()
+}
+
+func (s *SQLStore) GetNote(noteID string) (*Note, error) {
+	note, err := scanNote(s.db.QueryRow(`SELECT `+noteColumns+` FROM shin_note WHERE id = ?`, noteID))
+	if err != nil {
+		return nil, err
+	}
+	return &note, nil
+}
+
+// NotesByItemIDs 按条目分组返回笔记
+func (s *SQLStore) NotesByItemIDs(itemIDs []string) (map[string][]Note, error) {
+	notes := make(map[string][]Note)
+	if len(itemIDs) == 0 {
+		return notes, nil
+	}
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(itemIDs)), ",")
+	args := make([]interface{}, len(itemIDs))
+	for i, id := range itemIDs {
+		args[i] = id
+	}
+	rows, err := s.db.Query(`SELECT `+noteColumns+` FROM shin_note WHERE item_id IN (`+placeholders+`) ORDER BY created_at`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query notes: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		note, err := scanNote(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan note: %w", err)
+		}
+		notes[note.ItemID] = append(notes[note.ItemID], note)
+	}
+	return notes, rows.Err()
+}
+
+func addItemTag(e execer, itemID, name string) error {
+	now := time.Now()
+	if _, err := e.Exec(`INSERT INTO shin_tag (id, name, created_at) VALUES (?, ?, ?) ON CONFLICT(name) DO NOTHING`,
+		NewID(), name, now.Unix()); err != nil {
+		return fmt.Errorf("failed to insert tag: %w", err)
+	}
+	if _, err := e.Exec(`INSERT INTO shin_item_tag (item_id, tag_id, created_at)
+		SELECT ?, id, ? FROM shin_tag WHERE name = ? ON CONFLICT DO NOTHING`,
+		itemID, now.Unix(), name); err != nil {
+		return fmt.Errorf("failed to tag item: %w", err)
+	}
+	return nil
+}
+
+func (s *SQLStore) AddItemTag(itemID, name string) error {
+	return addItemTag(s.db, itemID, name)
+}
+
+func (s *SQLStore) RemoveItemTag(itemID, name string) error {
+	_, err := s.db.Exec(`DELETE FROM shin_item_tag WHERE item_id = ? AND tag_id IN (SELECT id FROM shin_tag WHERE name = ?)`, itemID, name)
+	return err
+}
+
+func (s *SQLStore) TagCounts() ([]TagCount, error) {
+	rows, err := s.db.Query(`SELECT shin_tag.name, COUNT(shin_item_tag.item_id) FROM shin_tag
+		LEFT JOIN shin_item_tag ON shin_item_tag.tag_id = shin_tag.id
+		GROUP BY shin_tag.id, shin_tag.name ORDER BY shin_tag.name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := []TagCount{}
+	for rows.Next() {
+		var tag TagCount
+		if err := rows.Scan(&tag.Name, &tag.Count); err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+	return tags, rows.Err()
+}
+
+// InsertSaved 记录保存结果，保存到 Memos 时同时更新 memo_id
+func (s *SQLStore) InsertSaved(itemID, sink, externalID string) error {
+	logger.Println("InsertSaved: ", itemID, sink, externalID)
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	_, err = tx.Exec(`INSERT INTO shin_saved (item_id, sink, external_id, saved_at) VALUES (?, ?, ?, ?)
+		ON CONFLICT(item_id, sink) DO UPDATE SET external_id = excluded.external_id, saved_at = excluded.saved_at`,
+		itemID, sink, externalID, time.Now().Unix())
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to insert saved: %w", err)
+	}
+
+	if sink == "memos" {
+		if _, err := tx.Exec("UPDATE shin_post_item SET memo_id = ? WHERE id = ?", externalID, itemID); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to update memo_id: %w", err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetSavedID 返回条目在目标中的 ID，旧数据只有 memo_id 时也能找到
+func (s *SQLStore) GetSavedID(itemID, sink string) (string, error) {
+	var externalID string
+	err := s.db.QueryRow("SELECT external_id FROM shin_saved WHERE item_id = ? AND sink = ?", itemID, sink).Scan(&externalID)
+	if err == sql.ErrNoRows {
+		return "", ErrNotSaved
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to query saved: %w", err)
+	}
+	return externalID, nil
+}
+
+func (s *SQLStore) DeleteSaved(itemID, sink string) error {
+	logger.Println("DeleteSaved: ", itemID, sink)
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	if _, err := tx.Exec("DELETE FROM shin_saved WHERE item_id = ? AND sink = ?", itemID, sink); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to delete saved: %w", err)
+	}
+
+	if sink == "memos" {
+		if _, err := tx.Exec("UPDATE shin_post_item SET memo_id = '' WHERE id = ?", itemID); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to clear memo_id: %w", err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+const outboxColumns = "id, item_id, sink, status, attempts, next_attempt_at, last_error, external_id, created_at, note_id"
+
+func scanOutboxEntry(row rowScanner) (OutboxEntry, error) {
+	var e OutboxEntry
+	err := row.Scan(&e.ID, &e.ItemID, &e.Sink, &e.Status, &e.Attempts, &e.NextAttemptAt, &e.LastError, &e.ExternalID, &e.CreatedAt, &e.NoteID)
+	return e, err
+}
+
+func idempotencyKey(itemID, sink string) string {
+	return itemID + ":" + sink
+}
+
+// EnqueueOutbox 同一条目同一目标只有一条记录，正在排队时重复入队不会产生新记录，已完成或失败的记录重新入队
+func (s *SQLStore) EnqueueOutbox(itemID, sink, noteID string) error {
+	now := time.Now()
+	_, err := s.db.Exec(`INSERT INTO shin_outbox (id, item_id, sink, idempotency_key, status, attempts, next_attempt_at, last_error, external_id, created_at, note_id)
+		VALUES (?, ?, ?, ?, 'pending', 0, ?, '', '', ?, ?)
+		ON CONFLICT(idempotency_key) DO UPDATE SET status = 'pending', attempts = 0, next_attempt_at = excluded.next_attempt_at, last_error = '', note_id = excluded.note_id
+		WHERE shin_outbox.status != 'pending'`,
+		NewID(), itemID, sink, idempotencyKey(itemID, sink), now.Unix(), now.Unix(), noteID)
+	if err != nil {
+		return fmt.Errorf("failed to enqueue save: %w", err)
+	}
+	return nil
+}
+
+func (s *SQLStore) queryOutbox(query string, args ...interface{}) ([]OutboxEntry, error) {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query outbox: %w", err)
+	}
+	defer rows.Close()
+
+	entries := []OutboxEntry{}
+	for rows.Next() {
+		entry, err := scanOutboxEntry(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan outbox: %w", err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
+func (s *SQLStore) DueOutboxEntries(limit int) ([]OutboxEntry, error) {
+	return s.queryOutbox(`SELECT `+outboxColumns+` FROM shin_outbox
+		WHERE status = 'pending' AND next_attempt_at <= ? ORDER BY created_at LIMIT ?`,
+		time.Now().Unix(), limit)
+}
+
+func (s *SQLStore) MarkOutboxDone(id, externalID string) error {
+	_, err := s.db.Exec("UPDATE shin_outbox SET status = 'done', external_id = ?, last_error = '' WHERE id = ?", externalID, id)
+	return err
+}
+
+func (s *SQLStore) MarkOutboxRetry(id, status string, attempts int, nextAttemptAt time.Time, lastError string) error {
+	_, err := s.db.Exec("UPDATE shin_outbox SET status = ?, attempts = ?, next_attempt_at = ?, last_error = ? WHERE id = ?",
+		status, attempts, nextAttemptAt.Unix(), lastError, id)
+	return err
+}
+
+// ListOutbox 返回最新的投递记录，itemID 为空时返回所有条目的
+func (s *SQLStore) ListOutbox(itemID string, limit int) ([]OutboxEntry, error) {
+	query := `SELECT ` + outboxColumns + ` FROM shin_outbox`
+	var args []interface{}
+	if itemID != "" {
+		query += ` WHERE item_id = ?`
+		args = append(args, itemID)
+	}
+	query += ` ORDER BY created_at DESC LIMIT ?`
+	args = append(args, limit)
+	return s.queryOutbox(query, args...)
+}
+
+func (s *SQLStore) GetArticle(itemID string) (*Article, error) {
+	var a Article
+	err := s.db.QueryRow(`SELECT item_id, url, title, html, text, status, error, fetched_at FROM shin_article WHERE item_id = ?`, itemID).
+		Scan(&a.ItemID, &a.URL, &a.Title, &a.HTML, &a.Text, &a.Status, &a.Error, &a.FetchedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+func (s *SQLStore) SaveArticle(article Article) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	_, err = tx.Exec(`INSERT INTO shin_article (item_id, url, title, html, text, status, error, fetched_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(item_id) DO UPDATE SET url = excluded.url, title = excluded.title, html = excluded.html,
+		text = excluded.text, status = excluded.status, error = excluded.error, fetched_at = excluded.fetched_at`,
+		article.ItemID, article.URL, article.Title, article.HTML, article.Text, article.Status, article.Error, article.FetchedAt)
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to save article: %w", err)
+	}
+
+	// 正文加入全文索引
+	if _, err := tx.Exec(`DELETE FROM shin_search WHERE item_id = ? AND kind = 'article'`, article.ItemID); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to delete search text: %w", err)
+	}
+	if article.Text != "" {
+		if _, err := tx.Exec(`INSERT INTO shin_search (item_id, kind, text) VALUES (?, 'article', ?)`, article.ItemID, article.Text); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to index article: %w", err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+const feedColumns = "id, url, title, category, enabled, translate, upstream_id, created_at, updated_at, deleted_at"
+
+func scanFeed(row rowScanner) (Feed, error) {
+	var f Feed
+	err := row.Scan(&f.ID, &f.URL, &f.Title, &f.Category, &f.Enabled, &f.Translate, &f.UpstreamID, &f.CreatedAt, &f.UpdatedAt, &f.DeletedAt)
+	return f, err
+}
+
+func (s *SQLStore) queryFeeds(where string) ([]Feed, error) {
+	rows, err := s.db.Query(`SELECT ` + feedColumns + ` FROM shin_feed WHERE ` + where + ` ORDER BY category, title`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query feeds: %w", err)
+	}
+	defer rows.Close()
+
+	feeds := []Feed{}
+	for rows.Next() {
+		feed, err := scanFeed(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan feed: %w", err)
+		}
+		feeds = append(feeds, feed)
+	}
+	return feeds, rows.Err()
+}
+
+func (s *SQLStore) ListFeeds() ([]Feed, error) {
+	return s.queryFeeds(`deleted_at = 0`)
+}
+
+func (s *SQLStore) ListDeletedFeeds() ([]Feed, error) {
+	return s.queryFeeds(`deleted_at > 0`)
+}
+
+func (s *SQLStore) GetFeed(feedID string) (*Feed, error) {
+	feed, err := scanFeed(s.db.QueryRow(`SELECT `+feedColumns+` FROM shin_feed WHERE id = ? AND deleted_at = 0`, feedID))
+	if err != nil {
+		return nil, err
+	}
+	return &feed, nil
+}
+
+func feedFlags(feed Feed) (int, string) {
+	enabled := 0
+	if feed.Enabled {
+		enabled = 1
+	}
+	if feed.Translate == "" {
+		feed.Translate = TranslateTitle
+	}
+	return enabled, feed.Translate
+}
+
+func (s *SQLStore) AddFeed(feed Feed) (bool, error) {
+	now := time.Now().Unix()
+	if feed.ID == "" {
+		feed.ID = NewID()
+	}
+	if feed.CreatedAt == 0 {
+		feed.CreatedAt = now
+	}
+	if feed.UpdatedAt == 0 {
+		feed.UpdatedAt = now
+	}
+	enabled, translate := feedFlags(feed)
+	result, err := s.db.Exec(`INSERT INTO shin_feed (`+feedColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?) ON CONFLICT(url) DO NOTHING`,
+		feed.ID, feed.URL, feed.Title, feed.Category, enabled, translate, feed.UpstreamID, feed.CreatedAt, feed.UpdatedAt, feed.DeletedAt)
+	if err != nil {
+		return false, fmt.Errorf("failed to add feed: %w", err)
+	}
+	if count, _ := result.RowsAffected(); count > 0 {
+		return true, nil
+	}
+	if feed.DeletedAt > 0 {
+		return false, nil
+	}
+
+	// 已删除的订阅按新加入处理，换成新的 ID
+	result, err = s.db.Exec(`UPDATE shin_feed SET id = ?, title = ?, category = ?, enabled = ?, translate = ?,
+		upstream_id = COALESCE(NULLIF(?, ''), upstream_id), created_at = ?, updated_at = ?, deleted_at = 0
+		WHERE url = ? AND deleted_at > 0`,
+		feed.ID, feed.Title, feed.Category, enabled, translate, feed.UpstreamID, feed.CreatedAt, feed.UpdatedAt, feed.URL)
+	if err != nil {
+		return false, fmt.Errorf("failed to restore feed: %w", err)
+	}
+	if count, _ := result.RowsAffected(); count > 0 {
+		return true, nil
+	}
+
+	// 已有的订阅保留用户修改过的标题、分类和开关
+	if feed.UpstreamID != "" {
+		if _, err := s.db.Exec(`UPDATE shin_feed SET upstream_id = ? WHERE url = ? AND upstream_id != ?`,
+			feed.UpstreamID, feed.URL, feed.UpstreamID); err != nil {
+			return false, fmt.Errorf("failed to update feed: %w", err)
+		}
+	}
+	return false, nil
+}
+
+func (s *SQLStore) UpdateFeed(feed Feed) error {
+	enabled, translate := feedFlags(feed)
+	result, err := s.db.Exec(`UPDATE shin_feed SET title = ?, category = ?, enabled = ?, translate = ?, updated_at = ? WHERE id = ? AND deleted_at = 0`,
+		feed.Title, feed.Category, enabled, translate, time.Now().Unix(), feed.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update feed: %w", err)
+	}
+	if count, _ := result.RowsAffected(); count == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *SQLStore) DeleteFeed(feedID string) error {
+	now := time.Now().Unix()
+	result, err := s.db.Exec(`UPDATE shin_feed SET enabled = 0, updated_at = ?, deleted_at = ? WHERE id = ? AND upstream_id != '' AND deleted_at = 0`,
+		now, now, feedID)
+	if err != nil {
+		return fmt.Errorf("failed to delete feed: %w", err)
+	}
+	if count, _ := result.RowsAffected(); count > 0 {
+		return nil
+	}
+
+	// 直接抓取的订阅没有上游，直接删除
+	result, err = s.db.Exec(`DELETE FROM shin_feed WHERE id = ? AND upstream_id = '' AND deleted_at = 0`, feedID)
+	if err != nil {
+		return fmt.Errorf("failed to delete feed: %w", err)
+	}
+	if count, _ := result.RowsAffected(); count == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *SQLStore) ImportPost(post Post) error {
+	if post.Tags == nil {
+		post.Tags = []string{}
+	}
+	tagsJSON, _ := json.Marshal(post.Tags)
+	_, err := s.db.Exec(`INSERT INTO shin_post (id, title, created_at, read_at, summary, tags) VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT(id) DO UPDATE SET title = excluded.title, created_at = excluded.created_at, read_at = excluded.read_at,
+		summary = excluded.summary, tags = excluded.tags`,
+		post.ID, post.Title, post.CreatedAt, post.ReadAt, post.Summary, string(tagsJSON))
+	if err != nil {
+		return fmt.Errorf("failed to import post: %w", err)
+	}
+	return nil
+}
+
+func (s *SQLStore) ImportPostItems(items []PostItem) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	for _, item := range items {
+		if err := importPostItem(tx, item); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to import item %s: %w", item.ID, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+func importPostItem(tx sqlTx, item PostItem) error {
+	read, starred := 0, 0
+	if item.Read {
+		read = 1
+	}
+	if item.Starred {
+		starred = 1
+	}
+
+	var exists int
+	err := tx.QueryRow(`SELECT COUNT(*) FROM shin_post_item WHERE id = ?`, item.ID).Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if exists > 0 {
+		// 导出文件中没有 memo_id 时保留库里已有的
+		if _, err := tx.Exec(`UPDATE shin_post_item SET read = ?, starred = ?, memo_id = COALESCE(NULLIF(?, ''), memo_id) WHERE id = ?`,
+			read, starred, item.MemoID, item.ID); err != nil {
+			return err
+		}
+	} else {
+		if _, err := tx.Exec(`INSERT INTO shin_post_item (id, post_id, feed_title, content, memo_id, upstream_id, read, starred, created_at, guid) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			item.ID, item.PostID, item.FeedTitle, item.Content, item.MemoID, item.UpstreamID, read, starred, itemCreatedAt(item), item.GUID); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`INSERT INTO shin_search (item_id, kind, text) VALUES (?, 'title', ?)`, item.ID, itemSearchText(item)); err != nil {
+			return err
+		}
+	}
+
+	for _, tag := range item.Tags {
+		if err := addItemTag(tx, item.ID, tag); err != nil {
+			return err
+		}
+	}
+
+	for _, note := range item.Notes {
+		result, err := tx.Exec(`INSERT INTO shin_note (id, item_id, body, created_at, updated_at) VALUES (?, ?, ?, ?, ?) ON CONFLICT(id) DO NOTHING`,
+			note.ID, item.ID, note.Body, note.CreatedAt, note.UpdatedAt)
+		if err != nil {
+			return err
+		}
+		if count, _ := result.RowsAffected(); count == 0 {
+			continue
+		}
+		if _, err := tx.Exec(`INSERT INTO shin_search (item_id, kind, text) VALUES (?, ?, ?)`, item.ID, noteSearchKind(note.ID), note.Body); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// keptItem 是保留期过后仍要保留的条目：已保存（含旧的 memo_id）、星标、有笔记或标签、正在等待保存
+const keptItem = `(COALESCE(shin_post_item.memo_id, '') != '' OR shin_post_item.starred = 1
+	OR EXISTS (SELECT 1 FROM shin_saved WHERE shin_saved.item_id = shin_post_item.id)
+	OR EXISTS (SELECT 1 FROM shin_outbox WHERE shin_outbox.item_id = shin_post_item.id AND shin_outbox.status = 'pending')
+	OR EXISTS (SELECT 1 FROM shin_note WHERE shin_note.item_id = shin_post_item.id)
+	OR EXISTS (SELECT 1 FROM shin_item_tag WHERE shin_item_tag.item_id = shin_post_item.id))`
+
+func (s *SQLStore) ExpiredPosts(before int64) ([]Post, error) {
+	rows, err := s.db.Query(`SELECT `+postColumns+` FROM shin_post WHERE created_at < ? AND (
+		EXISTS (SELECT 1 FROM shin_post_item WHERE shin_post_item.post_id = shin_post.id AND NOT `+keptItem+`)
+		OR NOT EXISTS (SELECT 1 FROM shin_post_item WHERE shin_post_item.post_id = shin_post.id))
+		ORDER BY created_at, id`, before)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query expired posts: %w", err)
+	}
+	defer rows.Close()
+
+	var posts []Post
+	for rows.Next() {
+		post, err := scanPost(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan post: %w", err)
+		}
+		posts = append(posts, post)
+	}
+	return posts, rows.Err()
+}
+
+func (s *SQLStore) ExpiredItems(postID string) ([]PostItem, int, error) {
+	items, err := s.queryPostItems(`SELECT `+postItemColumns+` FROM shin_post_item
+		WHERE post_id = ? AND NOT `+keptItem+` ORDER BY id`, postID)
+	if err != nil {
+		return nil, 0, err
+	}
+	var kept int
+	if err := s.db.QueryRow(`SELECT COUNT(*) FROM shin_post_item WHERE post_id = ? AND `+keptItem, postID).Scan(&kept); err != nil {
+		return nil, 0, fmt.Errorf("failed to count kept items: %w", err)
+	}
+	return items, kept, nil
+}
+
+func (s *SQLStore) PruneItems(postID string, itemIDs []string) (int, bool, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	// 查询之后可能有条目被星标或保存，删除前再按 keptItem 过滤一次
+	var ids []string
+	if len(itemIDs) > 0 {
+		args := []interface{}{postID}
+		for _, id := range itemIDs {
+			args = append(args, id)
+		}
+		rows, err := tx.Query(`SELECT id FROM shin_post_item WHERE post_id = ? AND id IN (`+
+			strings.TrimSuffix(strings.Repeat("?,", len(itemIDs)), ",")+`) AND NOT `+keptItem, args...)
+		if err != nil {
+			tx.Rollback()
+			return 0, false, fmt.Errorf("failed to query items: %w", err)
+		}
+		for rows.Next() {
+			var id string
+			if err := rows.Scan(&id); err != nil {
+				rows.Close()
+				tx.Rollback()
+				return 0, false, err
+			}
+			ids = append(ids, id)
+		}
+		rows.Close()
+	}
+
+	if len(ids) > 0 {
+		placeholders := strings.TrimSuffix(strings.Repeat("?,", len(ids)), ",")
+		args := make([]interface{}, len(ids))
+		for i, id := range ids {
+			args[i] = id
+		}
+		for _, table := range []string{"shin_search", "shin_article", "shin_outbox"} {
+			if _, err := tx.Exec(`DELETE FROM `+table+` WHERE item_id IN (`+placeholders+`)`, args...); err != nil {
+				tx.Rollback()
+				return 0, false, fmt.Errorf("failed to delete from %s: %w", table, err)
+			}
+		}
+		if _, err := tx.Exec(`DELETE FROM shin_post_item WHERE id IN (`+placeholders+`)`, args...); err != nil {
+			tx.Rollback()
+			return 0, false, fmt.Errorf("failed to delete items: %w", err)
+		}
+	}
+
+	result, err := tx.Exec(`DELETE FROM shin_post WHERE id = ? AND NOT EXISTS (SELECT 1 FROM shin_post_item WHERE post_id = ?)`, postID, postID)
+	if err != nil {
+		tx.Rollback()
+		return 0, false, fmt.Errorf("failed to delete post: %w", err)
+	}
+	postDeleted, _ := result.RowsAffected()
+
+	if err := tx.Commit(); err != nil {
+		return 0, false, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return len(ids), postDeleted > 0, nil
+}
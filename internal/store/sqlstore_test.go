@@ -0,0 +1,370 @@
+package store
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+)
+
+// openTestStore 打开一个测试独占的内存 SQLite 库。读写两个连接池需要共享同一个库，
+// 所以用命名的 shared cache 而不是 :memory:
+func openTestStore(t *testing.T) *SQLStore {
+	t.Helper()
+	name := strings.NewReplacer("/", "_", " ", "_").Replace(t.Name())
+	s, err := OpenSQLite(fmt.Sprintf("file:%s?mode=memory&cache=shared", name))
+	if err != nil {
+		t.Fatalf("OpenSQLite: %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+// openPostgresTestStore 在 SHIN_TEST_PG_DSN 指向的库中建一个测试独占的 schema，测试结束后删除
+func openPostgresTestStore(t *testing.T) *SQLStore {
+	t.Helper()
+	dsn := os.Getenv("SHIN_TEST_PG_DSN")
+	if dsn == "" {
+		t.Skip("SHIN_TEST_PG_DSN is not set")
+	}
+	schema := "shin_test_" + strings.ToLower(strings.NewReplacer("/", "_", " ", "_", "#", "_").Replace(t.Name()))
+	admin, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { admin.Close() })
+	if _, err := admin.Exec(`DROP SCHEMA IF EXISTS ` + schema + ` CASCADE; CREATE SCHEMA ` + schema); err != nil {
+		t.Fatalf("create schema: %v", err)
+	}
+	t.Cleanup(func() { admin.Exec(`DROP SCHEMA IF EXISTS ` + schema + ` CASCADE`) })
+
+	// lib/pq 把不认识的参数作为会话参数发送
+	if strings.Contains(dsn, "://") {
+		sep := "?"
+		if strings.Contains(dsn, "?") {
+			sep = "&"
+		}
+		dsn += sep + "search_path=" + schema
+	} else {
+		dsn += " search_path=" + schema
+	}
+	s, err := OpenPostgres(dsn)
+	if err != nil {
+		t.Fatalf("OpenPostgres: %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+// eachStore 在 SQLite 和 PostgreSQL 上各跑一遍 fn
+func eachStore(t *testing.T, fn func(t *testing.T, s *SQLStore)) {
+	t.Run("sqlite", func(t *testing.T) { fn(t, openTestStore(t)) })
+	t.Run("postgres", func(t *testing.T) { fn(t, openPostgresTestStore(t)) })
+}
+
+func testItem(title string) PostItem {
+	content, _ := json.Marshal(PostItemContent{CnTitle: title, Title: title, Link: "https://example.com/" + NewID()})
+	return PostItem{ID: NewID(), FeedTitle: "Example", Content: string(content)}
+}
+
+func itemTitles(items []PostItem) []string {
+	titles := []string{}
+	for _, item := range items {
+		var content PostItemContent
+		json.Unmarshal([]byte(item.Content), &content)
+		titles = append(titles, content.Title)
+	}
+	return titles
+}
+
+func TestSearchItemsEscapesWildcards(t *testing.T) {
+	eachStore(t, func(t *testing.T, s *SQLStore) {
+		if err := s.InsertPostItems([]PostItem{
+			testItem("100% pure_data"),
+			testItem("1000 pure data"),
+			testItem(`C:\path\to`),
+		}); err != nil {
+			t.Fatal(err)
+		}
+
+		tests := []struct {
+			keyword string
+			want    []string
+		}{
+			{"0% pure_", []string{"100% pure_data"}},
+			{"%", []string{"100% pure_data"}},
+			{"e_d", []string{"100% pure_data"}},
+			{"pure", []string{"1000 pure data", "100% pure_data"}},
+			{`:\path`, []string{`C:\path\to`}},
+		}
+		for _, tt := range tests {
+			items, _, err := s.SearchItems(tt.keyword, Page{Number: 1, Size: 10})
+			if err != nil {
+				t.Fatalf("SearchItems(%q): %v", tt.keyword, err)
+			}
+			if got := itemTitles(items); strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Errorf("SearchItems(%q) = %q, want %q", tt.keyword, got, tt.want)
+			}
+		}
+	})
+}
+
+func TestListItemsCursorFollowsCreatedAt(t *testing.T) {
+	eachStore(t, func(t *testing.T, s *SQLStore) {
+		// id 按字符串比较和创建时间的顺序相反，翻页只能依赖 created_at
+		var items []PostItem
+		for i, id := range []string{"9", "10", "11", "12", "8"} {
+			item := testItem(fmt.Sprintf("item %d", i))
+			item.ID, item.CreatedAt = id, int64(1700000000+i/2)
+			items = append(items, item)
+		}
+		if err := s.InsertPostItems(items); err != nil {
+			t.Fatal(err)
+		}
+
+		var got []string
+		page := Page{Number: 1, Size: 2}
+		for {
+			list, info, err := s.ListItems(page)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got = append(got, itemTitles(list)...)
+			if !info.HasMore {
+				break
+			}
+			if page.Cursor, err = DecodeCursor(info.NextCursor); err != nil {
+				t.Fatal(err)
+			}
+		}
+		want := []string{"item 4", "item 3", "item 2", "item 0", "item 1"}
+		if strings.Join(got, "|") != strings.Join(want, "|") {
+			t.Errorf("pages = %q, want %q", got, want)
+		}
+	})
+}
+
+func TestInsertPostItemsCreatedAt(t *testing.T) {
+	eachStore(t, func(t *testing.T, s *SQLStore) {
+		legacy := testItem("legacy")
+		legacy.ID = "1700000000123456789"
+		fresh := testItem("fresh")
+		if err := s.InsertPostItems([]PostItem{legacy, fresh}); err != nil {
+			t.Fatal(err)
+		}
+		if item, _ := s.GetPostItem(legacy.ID); item.CreatedAt != 1700000000 {
+			t.Errorf("legacy item created_at = %d, want it derived from the UnixNano id", item.CreatedAt)
+		}
+		if item, _ := s.GetPostItem(fresh.ID); item.CreatedAt == 0 {
+			t.Error("new item created_at = 0")
+		}
+	})
+}
+
+func TestApplyUpstreamStateKeepsUnsyncedStars(t *testing.T) {
+	eachStore(t, func(t *testing.T, s *SQLStore) {
+		synced, unsynced, local := testItem("synced"), testItem("unsynced"), testItem("local")
+		synced.UpstreamID, unsynced.UpstreamID = "tag:google.com,2005:reader/item/1", "tag:google.com,2005:reader/item/2"
+		if err := s.InsertPostItems([]PostItem{synced, unsynced, local}); err != nil {
+			t.Fatal(err)
+		}
+		for _, item := range []PostItem{synced, unsynced, local} {
+			if _, err := s.SetItemStarred(item.ID, true); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := s.MarkStarSynced([]string{synced.UpstreamID}, true); err != nil {
+			t.Fatal(err)
+		}
+		starred, unstarred, err := s.UnsyncedStars()
+		if err != nil || strings.Join(starred, ",") != unsynced.UpstreamID || len(unstarred) != 0 {
+			t.Fatalf("UnsyncedStars() = %q, %q, %v", starred, unstarred, err)
+		}
+
+		// 上游没有任何星标：已同步的取消，未同步的和没有上游 ID 的保留
+		if err := s.ApplyUpstreamState([]string{synced.UpstreamID}, nil); err != nil {
+			t.Fatal(err)
+		}
+		for _, tt := range []struct {
+			item          PostItem
+			starred, read bool
+		}{{synced, false, true}, {unsynced, true, false}, {local, true, false}} {
+			got, err := s.GetPostItem(tt.item.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Starred != tt.starred || got.Read != tt.read {
+				t.Errorf("%s: starred = %v, read = %v, want %v, %v", itemTitles([]PostItem{tt.item})[0], got.Starred, got.Read, tt.starred, tt.read)
+			}
+		}
+	})
+}
+
+func TestFeedCRUD(t *testing.T) {
+	eachStore(t, func(t *testing.T, s *SQLStore) {
+		upstream := Feed{ID: NewID(), URL: "https://example.com/a.xml", Title: "A", Category: "News", Enabled: true, UpstreamID: "feed/1"}
+		direct := Feed{ID: NewID(), URL: "https://example.com/b.xml", Title: "B", Enabled: true, Translate: TranslateNone}
+		for _, feed := range []Feed{upstream, direct} {
+			if created, err := s.AddFeed(feed); err != nil || !created {
+				t.Fatalf("AddFeed(%s) = %v, %v", feed.URL, created, err)
+			}
+		}
+
+		// 同一个 URL 不重复加入，只补上 UpstreamID
+		if created, err := s.AddFeed(Feed{URL: direct.URL, Title: "Other", UpstreamID: "feed/2"}); err != nil || created {
+			t.Fatalf("AddFeed(duplicate) = %v, %v", created, err)
+		}
+		got, err := s.GetFeed(direct.ID)
+		if err != nil || got.Title != "B" || got.UpstreamID != "feed/2" || got.Translate != TranslateNone {
+			t.Fatalf("GetFeed() = %+v, %v", got, err)
+		}
+
+		got.Title, got.Enabled = "B2", false
+		if err := s.UpdateFeed(*got); err != nil {
+			t.Fatal(err)
+		}
+		feeds, err := s.ListFeeds()
+		if err != nil || len(feeds) != 2 || feeds[0].Title != "B2" || feeds[0].Enabled || feeds[1].Category != "News" {
+			t.Fatalf("ListFeeds() = %+v, %v", feeds, err)
+		}
+
+		// 直接抓取的订阅直接删除，上游订阅留下停用的记录
+		local := Feed{ID: NewID(), URL: "https://example.com/c.xml", Title: "C", Enabled: true}
+		if _, err := s.AddFeed(local); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.DeleteFeed(local.ID); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.DeleteFeed(upstream.ID); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.DeleteFeed(upstream.ID); err != ErrNotFound {
+			t.Errorf("DeleteFeed(deleted) = %v, want ErrNotFound", err)
+		}
+		if _, err := s.GetFeed(upstream.ID); err == nil {
+			t.Error("GetFeed returned a deleted feed")
+		}
+		if err := s.UpdateFeed(upstream); err != ErrNotFound {
+			t.Errorf("UpdateFeed(deleted) = %v, want ErrNotFound", err)
+		}
+		if feeds, _ := s.ListFeeds(); len(feeds) != 1 || feeds[0].ID != direct.ID {
+			t.Errorf("ListFeeds() after delete = %+v", feeds)
+		}
+		deleted, err := s.ListDeletedFeeds()
+		if err != nil || len(deleted) != 1 || deleted[0].URL != upstream.URL || deleted[0].Enabled || deleted[0].DeletedAt == 0 {
+			t.Fatalf("ListDeletedFeeds() = %+v, %v", deleted, err)
+		}
+
+		// 再次加入已删除的订阅时按新订阅处理
+		restored := Feed{ID: NewID(), URL: upstream.URL, Title: "A again", Enabled: true}
+		if created, err := s.AddFeed(restored); err != nil || !created {
+			t.Fatalf("AddFeed(deleted) = %v, %v", created, err)
+		}
+		got, err = s.GetFeed(restored.ID)
+		if err != nil || got.Title != "A again" || !got.Enabled || got.UpstreamID != "feed/1" || got.DeletedAt != 0 {
+			t.Fatalf("GetFeed(restored) = %+v, %v", got, err)
+		}
+		if deleted, _ := s.ListDeletedFeeds(); len(deleted) != 0 {
+			t.Errorf("ListDeletedFeeds() after restore = %+v", deleted)
+		}
+	})
+}
+
+// importTestPost 写入一期 createdAt 创建的摘要和它的条目
+func importTestPost(t *testing.T, s *SQLStore, createdAt int64, items ...PostItem) Post {
+	t.Helper()
+	post := Post{ID: NewID(), Title: "post", CreatedAt: createdAt}
+	if err := s.ImportPost(post); err != nil {
+		t.Fatal(err)
+	}
+	for i := range items {
+		items[i].PostID = post.ID
+	}
+	if err := s.ImportPostItems(items); err != nil {
+		t.Fatal(err)
+	}
+	return post
+}
+
+func TestPruneItemsKeepsProtectedItems(t *testing.T) {
+	eachStore(t, func(t *testing.T, s *SQLStore) {
+		old, recent := int64(1_600_000_000), int64(1_700_000_000)
+		starred, saved, noted, tagged, pending, memo := testItem("starred"), testItem("saved"), testItem("noted"), testItem("tagged"), testItem("pending"), testItem("memo")
+		plain, other := testItem("plain"), testItem("other")
+		starred.Starred, memo.MemoID = true, "memos/1"
+		mixed := importTestPost(t, s, old, starred, saved, noted, tagged, pending, memo, plain)
+		empty := importTestPost(t, s, old, other)
+		fresh := importTestPost(t, s, recent, testItem("fresh"))
+		if err := s.InsertSaved(saved.ID, "memos", "memos/2"); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.InsertNote(noted.ID, "note"); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.AddItemTag(tagged.ID, "go"); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.EnqueueOutbox(pending.ID, "notion", ""); err != nil {
+			t.Fatal(err)
+		}
+
+		posts, err := s.ExpiredPosts(recent)
+		if err != nil || len(posts) != 2 || posts[0].ID == fresh.ID || posts[1].ID == fresh.ID {
+			t.Fatalf("ExpiredPosts() = %+v, %v", posts, err)
+		}
+
+		items, kept, err := s.ExpiredItems(mixed.ID)
+		if err != nil || kept != 6 || strings.Join(itemTitles(items), ",") != "plain" {
+			t.Fatalf("ExpiredItems(mixed) = %v, %d, %v", itemTitles(items), kept, err)
+		}
+		// 列出之后才星标的条目也不会被删除
+		if _, err := s.SetItemStarred(plain.ID, true); err != nil {
+			t.Fatal(err)
+		}
+		deleted, postDeleted, err := s.PruneItems(mixed.ID, []string{plain.ID})
+		if err != nil || deleted != 0 || postDeleted {
+			t.Fatalf("PruneItems(starred after listing) = %d, %v, %v", deleted, postDeleted, err)
+		}
+		if _, err := s.SetItemStarred(plain.ID, false); err != nil {
+			t.Fatal(err)
+		}
+		deleted, postDeleted, err = s.PruneItems(mixed.ID, []string{plain.ID})
+		if err != nil || deleted != 1 || postDeleted {
+			t.Fatalf("PruneItems(mixed) = %d, %v, %v", deleted, postDeleted, err)
+		}
+		for _, item := range []PostItem{starred, saved, noted, tagged, pending, memo} {
+			if _, err := s.GetPostItem(item.ID); err != nil {
+				t.Errorf("kept item %s: %v", itemTitles([]PostItem{item})[0], err)
+			}
+		}
+		if _, err := s.GetPostItem(plain.ID); err == nil {
+			t.Error("plain item was not deleted")
+		}
+		if _, err := s.GetPost(mixed.ID); err != nil {
+			t.Errorf("post with kept items was deleted: %v", err)
+		}
+
+		// 没有保留条目的摘要连同条目一起删除
+		items, kept, err = s.ExpiredItems(empty.ID)
+		if err != nil || kept != 0 || len(items) != 1 {
+			t.Fatalf("ExpiredItems(empty) = %v, %d, %v", itemTitles(items), kept, err)
+		}
+		if deleted, postDeleted, err := s.PruneItems(empty.ID, []string{other.ID}); err != nil || deleted != 1 || !postDeleted {
+			t.Fatalf("PruneItems(empty) = %d, %v, %v", deleted, postDeleted, err)
+		}
+		if _, err := s.GetPost(empty.ID); err == nil {
+			t.Error("empty post was not deleted")
+		}
+		if results, _, _ := s.SearchItems("other", Page{Number: 1, Size: 10}); len(results) != 0 {
+			t.Errorf("search still finds the deleted item: %v", itemTitles(results))
+		}
+
+		// 剩下的都是保留的条目，旧摘要不再过期
+		if posts, _ := s.ExpiredPosts(recent); len(posts) != 0 {
+			t.Errorf("ExpiredPosts() after prune = %+v", posts)
+		}
+	})
+}
@@ -0,0 +1,278 @@
+// Package store 定义 Shin 的数据模型和存储接口，处理函数和拉取流程只通过 Store 访问数据
+package store
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrNotFound 表示记录不存在，与 sql.ErrNoRows 相同，方便直接比较
+	ErrNotFound = sql.ErrNoRows
+	ErrNotSaved = errors.New("item is not saved to this sink")
+)
+
+type Post struct {
+	ID        string   `json:"id"`
+	Title     string   `json:"title"`
+	Content   string   `json:"content"`
+	CreatedAt int64    `json:"created_at"`
+	ReadAt    *int64   `json:"read_at"` // 未读时为 null
+	Summary   string   `json:"summary"`
+	Tags      []string `json:"tags"`
+}
+
+type PostItem struct {
+	ID        string `json:"id"`
+	PostID    string `json:"post_id"`
+	FeedTitle string `json:"feed_title"`
+	Content   string `json:"content"`
+	MemoID    string `json:"memo_id"`
+	// 已保存到的目标，来自 shin_saved
+	Saved []string `json:"saved"`
+	// 在 shin_outbox 中等待投递的目标
+	Pending []string `json:"pending"`
+	// FreshRSS 中的条目 ID，用于同步已读和星标
+	UpstreamID string `json:"upstream_id"`
+	Read       bool   `json:"read"`
+	Starred    bool   `json:"starred"`
+	// 入库时间，为 0 时写入当前时间
+	CreatedAt int64 `json:"created_at"`
+	// 直接抓取的订阅中条目的 guid，没有 guid 时是链接，用于去重
+	GUID string `json:"guid"`
+	// 条目标签，来自 shin_item_tag
+	Tags []string `json:"tags"`
+	// 本地笔记，仅详情和搜索结果返回
+	Notes []Note `json:"notes,omitempty"`
+}
+
+type PostItemContent struct {
+	CnTitle string `json:"cnTitle"`
+	Title   string `json:"title"`
+	Link    string `json:"link"`
+}
+
+type Note struct {
+	ID        string `json:"id"`
+	ItemID    string `json:"item_id"`
+	Body      string `json:"body"` // markdown
+	CreatedAt int64  `json:"created_at"`
+	UpdatedAt int64  `json:"updated_at"`
+}
+
+type Article struct {
+	ItemID    string `json:"item_id"`
+	URL       string `json:"url"`
+	Title     string `json:"title"`
+	HTML      string `json:"html"`
+	Text      string `json:"text"`
+	Status    string `json:"status"` // ok, failed
+	Error     string `json:"error"`
+	FetchedAt int64  `json:"fetched_at"`
+}
+
+type OutboxEntry struct {
+	ID            string `json:"id"`
+	ItemID        string `json:"item_id"`
+	Sink          string `json:"sink"`
+	Status        string `json:"status"` // pending, done, failed
+	Attempts      int    `json:"attempts"`
+	NextAttemptAt int64  `json:"next_attempt_at"`
+	LastError     string `json:"last_error"`
+	ExternalID    string `json:"external_id"`
+	CreatedAt     int64  `json:"created_at"`
+	NoteID        string `json:"note_id"`
+}
+
+// Feed 是 shin_feed 中的订阅。UpstreamID 是 FreshRSS 中的订阅 ID，为空时直接抓取 URL；
+// DeletedAt 不为 0 表示在 Shin 中删除过的上游订阅，保留记录让同步跳过它
+type Feed struct {
+	ID         string `json:"id"`
+	URL        string `json:"url"`
+	Title      string `json:"title"`
+	Category   string `json:"category"`
+	Enabled    bool   `json:"enabled"`
+	Translate  string `json:"translate"` // translate, none
+	UpstreamID string `json:"upstream_id"`
+	CreatedAt  int64  `json:"created_at"`
+	UpdatedAt  int64  `json:"updated_at"`
+	DeletedAt  int64  `json:"deleted_at,omitempty"`
+}
+
+const (
+	TranslateTitle = "translate"
+	TranslateNone  = "none"
+)
+
+type TagCount struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+type FeedCount struct {
+	Title     string
+	ItemCount int
+}
+
+// Page 是列表查询的分页参数，带 Cursor 时按 keyset 翻页，忽略 Number
+type Page struct {
+	Number int
+	Size   int
+	Cursor *Cursor
+}
+
+// Cursor 记录上一页最后一条的排序键。摘要和条目都按 (created_at, id) 排序
+type Cursor struct {
+	CreatedAt int64  `json:"c,omitempty"`
+	ID        string `json:"i"`
+}
+
+// PageInfo 随列表一起返回，Total 和 TotalPages 只在页码翻页且能廉价计数时填写
+type PageInfo struct {
+	Page       int    `json:"page,omitempty"`
+	PageSize   int    `json:"page_size"`
+	Total      int64  `json:"total,omitempty"`
+	TotalPages int64  `json:"total_pages,omitempty"`
+	HasMore    bool   `json:"has_more"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+func (p Page) Offset() int {
+	if p.Cursor != nil {
+		return 0
+	}
+	return (p.Number - 1) * p.Size
+}
+
+func EncodeCursor(cursor Cursor) string {
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func DecodeCursor(s string) (*Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, errors.New("malformed cursor")
+	}
+	var cursor Cursor
+	if err := json.Unmarshal(data, &cursor); err != nil || cursor.ID == "" {
+		return nil, errors.New("malformed cursor")
+	}
+	return &cursor, nil
+}
+
+// pageInfo 根据多取的一条判断是否还有下一页，返回截断后的长度
+func pageInfo(page Page, fetched int, last func(i int) Cursor) (PageInfo, int) {
+	info := PageInfo{PageSize: page.Size}
+	if page.Cursor == nil {
+		info.Page = page.Number
+	}
+	n := fetched
+	if fetched > page.Size {
+		n = page.Size
+		info.HasMore = true
+		info.NextCursor = EncodeCursor(last(n - 1))
+	}
+	return info, n
+}
+
+func (info *PageInfo) setTotal(total int64) {
+	info.Total = total
+	info.TotalPages = (total + int64(info.PageSize) - 1) / int64(info.PageSize)
+}
+
+// Store 是 Shin 的全部存储操作。条目状态变更返回受影响条目的上游 ID，由调用方同步到 FreshRSS
+type Store interface {
+	GetKeyValue(key string) (string, error)
+	SetKeyValue(key, value string) error
+
+	CutPost(postID, title string) (int64, error)
+	GetPost(postID string) (*Post, error)
+	ListPosts(page Page) ([]Post, PageInfo, error)
+	UpdatePostSummary(postID, summary string, tags []string) error
+	MarkPostRead(postID string) (upstreamIDs []string, err error)
+
+	InsertPostItems(items []PostItem) error
+	GetPostItem(itemID string) (*PostItem, error)
+	PostItems(postID string) ([]PostItem, error)
+	// ItemsOfPostsBetween 返回 since 到 until（Unix 秒）之间创建的其他摘要中的条目
+	ItemsOfPostsBetween(excludePostID string, since, until int64) ([]PostItem, error)
+	SearchItems(keyword string, page Page) ([]PostItem, PageInfo, error)
+	ItemsByFeeds(feedTitles []string, page Page) ([]PostItem, PageInfo, error)
+	ItemsByTag(tag string, page Page) ([]PostItem, PageInfo, error)
+	FeedCounts() ([]FeedCount, error)
+	// KnownGUIDs 返回 guids 中已经入库的
+	KnownGUIDs(guids []string) (map[string]bool, error)
+	SetItemRead(itemID string, read bool) (upstreamID string, err error)
+	// SetItemStarred 更新星标，有上游 ID 的条目在 MarkStarSynced 之前不会被 ApplyUpstreamState 覆盖
+	SetItemStarred(itemID string, starred bool) (upstreamID string, err error)
+	// UnsyncedStars 返回星标变更还没有同步到上游的条目的上游 ID
+	UnsyncedStars() (starred, unstarred []string, err error)
+	// MarkStarSynced 记录 upstreamIDs 的星标 starred 已同步到上游，之后又改过的条目不受影响
+	MarkStarSynced(upstreamIDs []string, starred bool) error
+	// ApplyUpstreamState 标记 readIDs 为已读，并把星标设为 starredIDs（上游的完整星标列表），
+	// 星标还没有同步到上游的条目保持不变
+	ApplyUpstreamState(readIDs, starredIDs []string) error
+
+	InsertNote(itemID, body string) (*Note, error)
+	UpdateNote(noteID, body string) (*Note, error)
+	DeleteNote(noteID string) error
+	GetNote(noteID string) (*Note, error)
+	NotesByItemIDs(itemIDs []string) (map[string][]Note, error)
+
+	AddItemTag(itemID, name string) error
+	RemoveItemTag(itemID, name string) error
+	TagCounts() ([]TagCount, error)
+
+	InsertSaved(itemID, sink, externalID string) error
+	GetSavedID(itemID, sink string) (string, error)
+	DeleteSaved(itemID, sink string) error
+
+	EnqueueOutbox(itemID, sink, noteID string) error
+	DueOutboxEntries(limit int) ([]OutboxEntry, error)
+	MarkOutboxDone(id, externalID string) error
+	MarkOutboxRetry(id, status string, attempts int, nextAttemptAt time.Time, lastError string) error
+	ListOutbox(itemID string, limit int) ([]OutboxEntry, error)
+
+	GetArticle(itemID string) (*Article, error)
+	SaveArticle(article Article) error
+
+	// ListFeeds 和 GetFeed 不包括已删除的订阅
+	ListFeeds() ([]Feed, error)
+	GetFeed(feedID string) (*Feed, error)
+	// ListDeletedFeeds 返回已删除但保留记录的上游订阅
+	ListDeletedFeeds() ([]Feed, error)
+	// AddFeed 按 URL 去重，已存在时只补上 UpstreamID；已删除的订阅重新启用。返回是否新建。
+	// feed.DeletedAt 不为 0 时（导入备份）写入已删除的记录，不会启用已删除的订阅
+	AddFeed(feed Feed) (created bool, err error)
+	UpdateFeed(feed Feed) error
+	// DeleteFeed 删除订阅，上游订阅只标记删除并停用，避免同步时又被加回来
+	DeleteFeed(feedID string) error
+
+	// ListItems 按入库时间倒序列出全部条目，包括还未归入摘要的
+	ListItems(page Page) ([]PostItem, PageInfo, error)
+	// ImportPost 按原 ID 和时间写入摘要，已存在时覆盖
+	ImportPost(post Post) error
+	// ImportPostItems 按原 ID 写入条目及其已读、星标、标签和笔记，已存在的条目只更新状态
+	ImportPostItems(items []PostItem) error
+
+	// ExpiredPosts 返回 before（Unix 秒）之前创建、还有可清理条目或已经没有条目的摘要，旧的在前
+	ExpiredPosts(before int64) ([]Post, error)
+	// ExpiredItems 返回摘要中可清理的条目，kept 是因保存、星标、笔记或标签而保留的条目数
+	ExpiredItems(postID string) (items []PostItem, kept int, err error)
+	// PruneItems 删除 itemIDs 中仍可清理的条目及其关联数据，摘要没有剩余条目时一并删除
+	PruneItems(postID string, itemIDs []string) (deleted int, postDeleted bool, err error)
+
+	Close() error
+}
+
+func splitList(s string) []string {
+	if s == "" {
+		return []string{}
+	}
+	return strings.Split(s, ",")
+}
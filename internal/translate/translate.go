@@ -0,0 +1,76 @@
+// Package translate 把条目标题翻译成中文
+package translate
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"shin/internal/config"
+)
+
+var logger = config.Logger
+
+const GoogleBaseURL = "https://translate.googleapis.com/translate_a/single"
+
+// Translator 把标题翻译成中文，失败时返回空字符串
+type Translator interface {
+	Translate(text string) string
+}
+
+// Google 调用 Google 翻译的免费接口
+type Google struct {
+	BaseURL string
+	Client  *http.Client
+}
+
+func NewGoogle() *Google {
+	return &Google{BaseURL: GoogleBaseURL, Client: &http.Client{Timeout: 60 * time.Second}}
+}
+
+func (t *Google) Translate(text string) string {
+	logger.Println("text:", text)
+	encodedText := url.QueryEscape(text)
+	requestURL := t.BaseURL + "?client=gtx&sl=auto&tl=zh&dt=t&q=" + encodedText
+
+	response, err := t.Client.Get(requestURL)
+	if err != nil {
+		logger.Println("Translation error:", err)
+		return ""
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(response.Body)
+		logger.Println("Non-200 response:", response.StatusCode, string(bodyBytes))
+		return ""
+	}
+
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		logger.Println("Error reading translation response:", err)
+		return ""
+	}
+
+	var result []interface{}
+	if err := json.Unmarshal(body, &result); err != nil {
+		logger.Println("Failed to parse translation response:", err)
+		return ""
+	}
+
+	if len(result) > 0 {
+		firstItem, ok := result[0].([]interface{})
+		if ok && len(firstItem) > 0 {
+			secondItem, ok := firstItem[0].([]interface{})
+			if ok && len(secondItem) > 0 {
+				if translatedText, ok := secondItem[0].(string); ok {
+					return translatedText
+				}
+			}
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,705 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+	"strings"
+
+	"shin/internal/backup"
+	"shin/internal/ingest"
+	"shin/internal/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+// /api/v1 的所有响应都使用同一种结构：成功时 {"data": ..., "meta": ...}，失败时 {"error": {"code", "message"}}
+
+var errInvalidInput = errors.New("invalid input")
+
+type APIError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+type APIErrorEnvelope struct {
+	Error APIError `json:"error"`
+}
+
+type APIPost struct {
+	ID        string   `json:"id"`
+	Title     string   `json:"title"`
+	CreatedAt int64    `json:"created_at"`
+	ReadAt    *int64   `json:"read_at"` // 未读时为 null
+	Summary   string   `json:"summary"`
+	Tags      []string `json:"tags"`
+}
+
+type APIItem struct {
+	ID         string       `json:"id"`
+	PostID     string       `json:"post_id"`
+	FeedTitle  string       `json:"feed_title"`
+	CnTitle    string       `json:"cn_title"`
+	Title      string       `json:"title"`
+	Link       string       `json:"link"`
+	UpstreamID string       `json:"upstream_id"`
+	Read       bool         `json:"read"`
+	Starred    bool         `json:"starred"`
+	Saved      []string     `json:"saved"`
+	Pending    []string     `json:"pending"`
+	Tags       []string     `json:"tags"`
+	Notes      []store.Note `json:"notes"`
+	CreatedAt  int64        `json:"created_at"`
+}
+
+type APIFeedGroup struct {
+	FeedTitle string    `json:"feed_title"`
+	Items     []APIItem `json:"items"`
+}
+
+// APICluster 用条目 ID 引用 groups 中的条目
+type APICluster struct {
+	Representative string   `json:"representative"`
+	Also           []string `json:"also"`
+}
+
+type APIPostDetail struct {
+	APIPost
+	Groups   []APIFeedGroup `json:"groups"`
+	Clusters []APICluster   `json:"clusters"`
+}
+
+// APIFeed 是 shin_feed 中的订阅，ItemCount 是该订阅标题下的条目数
+type APIFeed struct {
+	store.Feed
+	ItemCount int  `json:"item_count"`
+	Important bool `json:"important"`
+}
+
+type APISaveResult struct {
+	Sink       string `json:"sink"`
+	Queued     bool   `json:"queued"`
+	ExternalID string `json:"external_id,omitempty"`
+}
+
+type APIArticle struct {
+	Item    APIItem       `json:"item"`
+	Article store.Article `json:"article"`
+}
+
+type APIMessage struct {
+	Message string `json:"message"`
+}
+
+type itemPatchRequest struct {
+	Read    *bool `json:"read,omitempty"`
+	Starred *bool `json:"starred,omitempty"`
+}
+
+type saveRequest struct {
+	Sink string `json:"sink"`
+}
+
+type appendRequest struct {
+	Text string `json:"text"`
+}
+
+type noteRequest struct {
+	Body string `json:"body"`
+}
+
+type tagRequest struct {
+	Tag string `json:"tag"`
+}
+
+type rewriteRulesRequest struct {
+	Rules []ingest.RewriteRule `json:"rules"`
+}
+
+type tagRulesRequest struct {
+	Rules []ingest.TagRule `json:"rules"`
+}
+
+type dryRunRequest struct {
+	Rule  ingest.RewriteRule `json:"rule"`
+	Hours int                `json:"hours"`
+	Limit int                `json:"limit"`
+}
+
+func apiOK(c *gin.Context, status int, data interface{}) {
+	c.JSON(status, gin.H{"data": data})
+}
+
+func apiAbort(c *gin.Context, status int, code, message string) {
+	c.AbortWithStatusJSON(status, APIErrorEnvelope{Error: APIError{Code: code, Message: message}})
+}
+
+// apiFail 按错误类型选择状态码。上游和内部错误只记日志，响应里不带原始错误，
+// 以免泄露 SQL、文件路径或上游返回的内容
+func apiFail(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+		apiAbort(c, http.StatusNotFound, "not_found", "resource not found")
+	case errors.Is(err, store.ErrNotSaved):
+		apiAbort(c, http.StatusNotFound, "not_found", err.Error())
+	case errors.Is(err, errInvalidInput), errors.Is(err, ingest.ErrInvalidRule), errors.Is(err, errUnknownSink), errors.Is(err, errUnsupported),
+		errors.Is(err, backup.ErrInvalidExport):
+		apiAbort(c, http.StatusBadRequest, "invalid_request", err.Error())
+	case errors.Is(err, errInvalidResponse):
+		logger.Println("api:", c.Request.Method, c.Request.URL.Path, err)
+		apiAbort(c, http.StatusBadGateway, "upstream_error", "invalid response from upstream service")
+	default:
+		logger.Println("api:", c.Request.Method, c.Request.URL.Path, err)
+		apiAbort(c, http.StatusInternalServerError, "internal", "internal server error")
+	}
+}
+
+func bindAPIJSON(c *gin.Context, input interface{}) bool {
+	if err := c.ShouldBindJSON(input); err != nil {
+		apiAbort(c, http.StatusBadRequest, "invalid_request", err.Error())
+		return false
+	}
+	return true
+}
+
+func toAPIPost(post store.Post) APIPost {
+	apiPost := APIPost{
+		ID:        post.ID,
+		Title:     post.Title,
+		CreatedAt: post.CreatedAt,
+		ReadAt:    post.ReadAt,
+		Summary:   post.Summary,
+		Tags:      post.Tags,
+	}
+	if apiPost.Tags == nil {
+		apiPost.Tags = []string{}
+	}
+	return apiPost
+}
+
+func toAPIItem(item store.PostItem) APIItem {
+	var content store.PostItemContent
+	json.Unmarshal([]byte(item.Content), &content)
+	apiItem := APIItem{
+		ID:         item.ID,
+		PostID:     item.PostID,
+		FeedTitle:  item.FeedTitle,
+		CnTitle:    content.CnTitle,
+		Title:      content.Title,
+		Link:       content.Link,
+		UpstreamID: item.UpstreamID,
+		Read:       item.Read,
+		Starred:    item.Starred,
+		Saved:      item.Saved,
+		Pending:    item.Pending,
+		Tags:       item.Tags,
+		Notes:      item.Notes,
+		CreatedAt:  item.CreatedAt,
+	}
+	if apiItem.Notes == nil {
+		apiItem.Notes = []store.Note{}
+	}
+	return apiItem
+}
+
+func toAPIItems(items []store.PostItem) []APIItem {
+	apiItems := make([]APIItem, len(items))
+	for i, item := range items {
+		apiItems[i] = toAPIItem(item)
+	}
+	return apiItems
+}
+
+func apiPaged(c *gin.Context, data interface{}, info store.PageInfo) {
+	c.JSON(http.StatusOK, gin.H{"data": data, "meta": info})
+}
+
+func apiListPosts(c *gin.Context) {
+	page, err := parsePage(c)
+	if err != nil {
+		apiFail(c, err)
+		return
+	}
+	posts, info, err := st.ListPosts(page)
+	if err != nil {
+		apiFail(c, err)
+		return
+	}
+	data := make([]APIPost, len(posts))
+	for i, post := range posts {
+		data[i] = toAPIPost(post)
+	}
+	apiPaged(c, data, info)
+}
+
+func apiGetPost(c *gin.Context) {
+	post, err := st.GetPost(c.Param("id"))
+	if err != nil {
+		apiFail(c, err)
+		return
+	}
+	grouped, err := getPostItemsGroupedByFeedTitle(post.ID)
+	if err != nil {
+		apiFail(c, err)
+		return
+	}
+
+	detail := APIPostDetail{APIPost: toAPIPost(*post), Groups: []APIFeedGroup{}, Clusters: []APICluster{}}
+	feedTitles := make([]string, 0, len(grouped))
+	for feedTitle := range grouped {
+		feedTitles = append(feedTitles, feedTitle)
+	}
+	sort.Strings(feedTitles)
+	for _, feedTitle := range feedTitles {
+		detail.Groups = append(detail.Groups, APIFeedGroup{FeedTitle: feedTitle, Items: toAPIItems(grouped[feedTitle])})
+	}
+
+	if clusterEnabled {
+		clusters, err := getPostClusters(*post)
+		if err != nil {
+			logger.Println("getPostClusters:", err)
+		}
+		for _, cluster := range clusters {
+			apiCluster := APICluster{Representative: cluster.Representative.ID, Also: []string{}}
+			for _, item := range cluster.Also {
+				apiCluster.Also = append(apiCluster.Also, item.ID)
+			}
+			detail.Clusters = append(detail.Clusters, apiCluster)
+		}
+	}
+
+	apiOK(c, http.StatusOK, detail)
+}
+
+func apiMarkPostRead(c *gin.Context) {
+	if err := MarkPostRead(c.Param("id")); err != nil {
+		apiFail(c, err)
+		return
+	}
+	apiOK(c, http.StatusOK, APIMessage{Message: "Post marked as read"})
+}
+
+func apiSummarizePost(c *gin.Context) {
+	if llmAPIURL == "" {
+		apiAbort(c, http.StatusBadRequest, "invalid_request", "LLM_API_URL is not configured")
+		return
+	}
+	if _, err := st.GetPost(c.Param("id")); err != nil {
+		apiFail(c, err)
+		return
+	}
+	if err := summarizePost(c.Param("id")); err != nil {
+		apiFail(c, err)
+		return
+	}
+	post, err := st.GetPost(c.Param("id"))
+	if err != nil {
+		apiFail(c, err)
+		return
+	}
+	apiOK(c, http.StatusOK, toAPIPost(*post))
+}
+
+func apiEmailPost(c *gin.Context) {
+	if !emailEnabled() {
+		apiAbort(c, http.StatusBadRequest, "invalid_request", "SMTP_HOST and SMTP_TO are not configured")
+		return
+	}
+	if _, err := st.GetPost(c.Param("id")); err != nil {
+		apiFail(c, err)
+		return
+	}
+	if err := sendDigestEmail(c.Param("id")); err != nil {
+		apiFail(c, err)
+		return
+	}
+	apiOK(c, http.StatusOK, APIMessage{Message: "Email sent"})
+}
+
+// apiListItems 支持 q（全文搜索）、tag 和 important=true 三种筛选
+func apiListItems(c *gin.Context) {
+	page, err := parsePage(c)
+	if err != nil {
+		apiFail(c, err)
+		return
+	}
+	var items []store.PostItem
+	var info store.PageInfo
+	switch {
+	case c.Query("q") != "":
+		items, info, err = SearchItems(c.Query("q"), page)
+	case c.Query("tag") != "":
+		if items, info, err = queryTaggedItems(c.Query("tag"), page); err == nil {
+			err = attachNotes(items)
+		}
+	case c.Query("important") == "true":
+		items, info, err = queryImportantItems(page)
+	default:
+		apiAbort(c, http.StatusBadRequest, "invalid_request", "one of q, tag or important=true is required")
+		return
+	}
+	if err != nil {
+		apiFail(c, err)
+		return
+	}
+	apiPaged(c, toAPIItems(items), info)
+}
+
+func loadAPIItem(c *gin.Context) (*store.PostItem, bool) {
+	item, err := st.GetPostItem(c.Param("id"))
+	if err != nil {
+		apiFail(c, err)
+		return nil, false
+	}
+	items := []store.PostItem{*item}
+	if err := attachNotes(items); err != nil {
+		apiFail(c, err)
+		return nil, false
+	}
+	return &items[0], true
+}
+
+func apiGetItem(c *gin.Context) {
+	item, ok := loadAPIItem(c)
+	if !ok {
+		return
+	}
+	apiOK(c, http.StatusOK, toAPIItem(*item))
+}
+
+func apiPatchItem(c *gin.Context) {
+	var input itemPatchRequest
+	if !bindAPIJSON(c, &input) {
+		return
+	}
+	if input.Read != nil {
+		if err := setItemState(c.Param("id"), ingest.StateRead, *input.Read); err != nil {
+			apiFail(c, err)
+			return
+		}
+	}
+	if input.Starred != nil {
+		if err := setItemState(c.Param("id"), ingest.StateStarred, *input.Starred); err != nil {
+			apiFail(c, err)
+			return
+		}
+	}
+	apiGetItem(c)
+}
+
+func apiGetArticle(c *gin.Context) {
+	item, ok := loadAPIItem(c)
+	if !ok {
+		return
+	}
+	article, err := LoadArticle(*item, c.Query("refresh") == "true")
+	if err != nil {
+		apiFail(c, err)
+		return
+	}
+	apiOK(c, http.StatusOK, APIArticle{Item: toAPIItem(*item), Article: *article})
+}
+
+func apiSaveItem(c *gin.Context) {
+	var input saveRequest
+	if !bindAPIJSON(c, &input) {
+		return
+	}
+	externalID, queued, err := EnqueueSave(c.Param("id"), input.Sink, "")
+	if err != nil {
+		apiFail(c, err)
+		return
+	}
+	status := http.StatusOK
+	if queued {
+		status = http.StatusAccepted
+	}
+	apiOK(c, status, APISaveResult{Sink: input.Sink, Queued: queued, ExternalID: externalID})
+}
+
+func apiUnsaveItem(c *gin.Context) {
+	if err := UnsaveItem(c.Param("id"), c.Param("sink")); err != nil {
+		apiFail(c, err)
+		return
+	}
+	apiOK(c, http.StatusOK, APIMessage{Message: "Removed from " + c.Param("sink")})
+}
+
+func apiAppendToSaved(c *gin.Context) {
+	var input appendRequest
+	if !bindAPIJSON(c, &input) {
+		return
+	}
+	if strings.TrimSpace(input.Text) == "" {
+		apiAbort(c, http.StatusBadRequest, "invalid_request", "text is required")
+		return
+	}
+	commentID, err := AppendToSaved(c.Param("id"), c.Param("sink"), input.Text)
+	if err != nil {
+		apiFail(c, err)
+		return
+	}
+	apiOK(c, http.StatusOK, gin.H{"comment_id": commentID})
+}
+
+func apiListNotes(c *gin.Context) {
+	item, ok := loadAPIItem(c)
+	if !ok {
+		return
+	}
+	apiOK(c, http.StatusOK, toAPIItem(*item).Notes)
+}
+
+func apiCreateNote(c *gin.Context) {
+	var input noteRequest
+	if !bindAPIJSON(c, &input) {
+		return
+	}
+	if strings.TrimSpace(input.Body) == "" {
+		apiAbort(c, http.StatusBadRequest, "invalid_request", "body is required")
+		return
+	}
+	if _, err := st.GetPostItem(c.Param("id")); err != nil {
+		apiFail(c, err)
+		return
+	}
+	note, err := st.InsertNote(c.Param("id"), input.Body)
+	if err != nil {
+		apiFail(c, err)
+		return
+	}
+	apiOK(c, http.StatusCreated, note)
+}
+
+func apiUpdateNote(c *gin.Context) {
+	var input noteRequest
+	if !bindAPIJSON(c, &input) {
+		return
+	}
+	if strings.TrimSpace(input.Body) == "" {
+		apiAbort(c, http.StatusBadRequest, "invalid_request", "body is required")
+		return
+	}
+	note, err := st.UpdateNote(c.Param("id"), input.Body)
+	if err != nil {
+		apiFail(c, err)
+		return
+	}
+	apiOK(c, http.StatusOK, note)
+}
+
+func apiDeleteNote(c *gin.Context) {
+	if err := st.DeleteNote(c.Param("id")); err != nil {
+		apiFail(c, err)
+		return
+	}
+	apiOK(c, http.StatusOK, APIMessage{Message: "Note deleted"})
+}
+
+func apiPromoteNote(c *gin.Context) {
+	note, err := st.GetNote(c.Param("id"))
+	if err != nil {
+		apiFail(c, err)
+		return
+	}
+	result, err := PromoteNote(note)
+	if err != nil {
+		apiFail(c, err)
+		return
+	}
+	status := http.StatusOK
+	if result.Queued {
+		status = http.StatusAccepted
+	}
+	apiOK(c, status, result)
+}
+
+func apiAddItemTag(c *gin.Context) {
+	var input tagRequest
+	if !bindAPIJSON(c, &input) {
+		return
+	}
+	tag, err := ingest.NormalizeTag(input.Tag)
+	if err != nil {
+		apiAbort(c, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+	if _, err := st.GetPostItem(c.Param("id")); err != nil {
+		apiFail(c, err)
+		return
+	}
+	if err := st.AddItemTag(c.Param("id"), tag); err != nil {
+		apiFail(c, err)
+		return
+	}
+	apiGetItem(c)
+}
+
+func apiRemoveItemTag(c *gin.Context) {
+	// 标签名可以带 /，路由用通配参数
+	if err := st.RemoveItemTag(c.Param("id"), strings.TrimPrefix(c.Param("tag"), "/")); err != nil {
+		apiFail(c, err)
+		return
+	}
+	apiGetItem(c)
+}
+
+func apiListTags(c *gin.Context) {
+	tags, err := st.TagCounts()
+	if err != nil {
+		apiFail(c, err)
+		return
+	}
+	apiOK(c, http.StatusOK, tags)
+}
+
+// apiListFeeds 列出 shin_feed 中的订阅，按分类和标题排序，附带条目数
+func apiListFeeds(c *gin.Context) {
+	feeds, err := st.ListFeeds()
+	if err != nil {
+		apiFail(c, err)
+		return
+	}
+	counts, err := st.FeedCounts()
+	if err != nil {
+		apiFail(c, err)
+		return
+	}
+
+	itemCounts := make(map[string]int)
+	for _, count := range counts {
+		itemCounts[count.Title] = count.ItemCount
+	}
+	important := make(map[string]bool)
+	for _, feedTitle := range importantFeedTitles() {
+		important[feedTitle] = true
+	}
+	result := []APIFeed{}
+	for _, feed := range feeds {
+		result = append(result, APIFeed{Feed: feed, ItemCount: itemCounts[feed.Title], Important: important[feed.Title]})
+	}
+	apiOK(c, http.StatusOK, result)
+}
+
+// apiImport 导入 GET /export 导出的 JSONL，请求体就是文件内容
+func apiImport(c *gin.Context) {
+	counts, err := backup.Import(st, c.Request.Body)
+	if err != nil {
+		apiFail(c, err)
+		return
+	}
+	apiOK(c, http.StatusOK, counts)
+}
+
+func apiListSinks(c *gin.Context) {
+	names := []string{}
+	for _, sink := range sinks {
+		names = append(names, sink.Name())
+	}
+	apiOK(c, http.StatusOK, names)
+}
+
+func apiGetRewriteRules(c *gin.Context) {
+	apiOK(c, http.StatusOK, ingest.GetRewriteRules(st))
+}
+
+func apiPutRewriteRules(c *gin.Context) {
+	var input rewriteRulesRequest
+	if !bindAPIJSON(c, &input) {
+		return
+	}
+	if err := ingest.SaveRewriteRules(st, input.Rules); err != nil {
+		apiFail(c, err)
+		return
+	}
+	apiOK(c, http.StatusOK, ingest.GetRewriteRules(st))
+}
+
+func apiDryRunRewriteRule(c *gin.Context) {
+	var input dryRunRequest
+	if !bindAPIJSON(c, &input) {
+		return
+	}
+	results, err := ingester.DryRunRewriteRule(input.Rule, input.Hours, input.Limit)
+	if err != nil {
+		apiFail(c, err)
+		return
+	}
+	apiOK(c, http.StatusOK, results)
+}
+
+func apiGetTagRules(c *gin.Context) {
+	apiOK(c, http.StatusOK, ingest.GetTagRules(st))
+}
+
+func apiPutTagRules(c *gin.Context) {
+	var input tagRulesRequest
+	if !bindAPIJSON(c, &input) {
+		return
+	}
+	if err := ingest.SaveTagRules(st, input.Rules); err != nil {
+		apiFail(c, err)
+		return
+	}
+	apiOK(c, http.StatusOK, ingest.GetTagRules(st))
+}
+
+var pageParams = []apiParam{{Name: "page", Type: "integer"}, {Name: "page_size", Type: "integer"}, {Name: "cursor", Type: "string"}}
+
+// apiRoutes 同时用于注册路由和生成 OpenAPI 文档
+var apiRoutes = []apiRoute{
+	{Method: "GET", Path: "/posts", Summary: "List digests", Handler: apiListPosts, Response: []APIPost{}, Paged: true, Query: pageParams},
+	{Method: "GET", Path: "/posts/:id", Summary: "Get a digest with its items grouped by feed", Handler: apiGetPost, Response: APIPostDetail{}},
+	{Method: "POST", Path: "/posts/:id/read", Summary: "Mark a digest and its items as read", Handler: apiMarkPostRead, Response: APIMessage{}},
+	{Method: "POST", Path: "/posts/:id/summarize", Summary: "Regenerate the LLM summary of a digest", Handler: apiSummarizePost, Response: APIPost{}},
+	{Method: "POST", Path: "/posts/:id/email", Summary: "Send a digest by email", Handler: apiEmailPost, Response: APIMessage{}},
+
+	{Method: "GET", Path: "/items", Summary: "Search items, or list tagged or important items", Handler: apiListItems, Response: []APIItem{}, Paged: true,
+		Query: append([]apiParam{{Name: "q", Type: "string"}, {Name: "tag", Type: "string"}, {Name: "important", Type: "boolean"}}, pageParams...)},
+	{Method: "GET", Path: "/items/:id", Summary: "Get an item", Handler: apiGetItem, Response: APIItem{}},
+	{Method: "PATCH", Path: "/items/:id", Summary: "Update read or starred state", Handler: apiPatchItem, Request: itemPatchRequest{}, Response: APIItem{}},
+	{Method: "GET", Path: "/items/:id/article", Summary: "Get the extracted article of an item", Handler: apiGetArticle, Response: APIArticle{},
+		Query: []apiParam{{Name: "refresh", Type: "boolean"}}},
+	{Method: "POST", Path: "/items/:id/saves", Summary: "Queue an item for saving to a sink", Handler: apiSaveItem, Request: saveRequest{}, Response: APISaveResult{}},
+	{Method: "DELETE", Path: "/items/:id/saves/:sink", Summary: "Remove an item from a sink", Handler: apiUnsaveItem, Response: APIMessage{}},
+	{Method: "POST", Path: "/items/:id/saves/:sink/comments", Summary: "Append text to a saved item", Handler: apiAppendToSaved, Request: appendRequest{}, Response: map[string]string{}},
+	{Method: "GET", Path: "/items/:id/notes", Summary: "List notes of an item", Handler: apiListNotes, Response: []store.Note{}},
+	{Method: "POST", Path: "/items/:id/notes", Summary: "Add a note to an item", Handler: apiCreateNote, Request: noteRequest{}, Response: store.Note{}},
+	{Method: "POST", Path: "/items/:id/tags", Summary: "Tag an item", Handler: apiAddItemTag, Request: tagRequest{}, Response: APIItem{}},
+	{Method: "DELETE", Path: "/items/:id/tags/*tag", Summary: "Remove a tag from an item", Handler: apiRemoveItemTag, Response: APIItem{}},
+
+	{Method: "PUT", Path: "/notes/:id", Summary: "Update a note", Handler: apiUpdateNote, Request: noteRequest{}, Response: store.Note{}},
+	{Method: "DELETE", Path: "/notes/:id", Summary: "Delete a note", Handler: apiDeleteNote, Response: APIMessage{}},
+	{Method: "POST", Path: "/notes/:id/promote", Summary: "Save a note to Memos", Handler: apiPromoteNote, Response: PromoteResult{}},
+
+	{Method: "GET", Path: "/feeds", Summary: "List subscriptions with item counts", Handler: apiListFeeds, Response: []APIFeed{}},
+	{Method: "GET", Path: "/tags", Summary: "List tags with item counts", Handler: apiListTags, Response: []store.TagCount{}},
+	{Method: "GET", Path: "/sinks", Summary: "List configured save targets", Handler: apiListSinks, Response: []string{}},
+
+	{Method: "GET", Path: "/rules/rewrite", Summary: "Get link rewrite rules", Handler: apiGetRewriteRules, Response: []ingest.RewriteRule{}},
+	{Method: "PUT", Path: "/rules/rewrite", Summary: "Replace link rewrite rules", Handler: apiPutRewriteRules, Request: rewriteRulesRequest{}, Response: []ingest.RewriteRule{}},
+	{Method: "POST", Path: "/rules/rewrite/dry-run", Summary: "Preview a rewrite rule against recent items", Handler: apiDryRunRewriteRule, Request: dryRunRequest{}, Response: []ingest.DryRunResult{}},
+	{Method: "GET", Path: "/rules/tag", Summary: "Get tag rules", Handler: apiGetTagRules, Response: []ingest.TagRule{}},
+	{Method: "PUT", Path: "/rules/tag", Summary: "Replace tag rules", Handler: apiPutTagRules, Request: tagRulesRequest{}, Response: []ingest.TagRule{}},
+
+	{Method: "GET", Path: "/export", Summary: "Export all data as versioned JSONL", Handler: exportData, ResponseType: "application/x-ndjson"},
+	{Method: "POST", Path: "/import", Summary: "Import a JSONL export", Handler: apiImport, RequestType: "application/x-ndjson", Response: backup.Counts{}},
+}
+
+func registerAPI(r *gin.Engine) {
+	v1 := r.Group("/api/v1")
+	for _, route := range apiRoutes {
+		v1.Handle(route.Method, route.Path, route.Handler)
+	}
+	v1.GET("/openapi.json", func(c *gin.Context) {
+		c.JSON(http.StatusOK, openAPIDocument())
+	})
+	r.NoRoute(func(c *gin.Context) {
+		if strings.HasPrefix(c.Request.URL.Path, "/api/") {
+			apiAbort(c, http.StatusNotFound, "not_found", "no such endpoint")
+			return
+		}
+		c.String(http.StatusNotFound, "404 page not found")
+	})
+}
@@ -0,0 +1,45 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"shin/internal/store"
+)
+
+func TestAPIFail(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tests := []struct {
+		err         error
+		wantStatus  int
+		wantCode    string
+		wantMessage string
+	}{
+		{store.ErrNotFound, http.StatusNotFound, "not_found", "resource not found"},
+		{fmt.Errorf("%w: page_size", errInvalidInput), http.StatusBadRequest, "invalid_request", "invalid input: page_size"},
+		{fmt.Errorf("%w: unexpected token <html>", errInvalidResponse), http.StatusBadGateway, "upstream_error", "invalid response from upstream service"},
+		{errors.New("sql: no such column: secret_col in /data/shin.db"), http.StatusInternalServerError, "internal", "internal server error"},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest("GET", "/api/v1/items", nil)
+		apiFail(c, tt.err)
+
+		var body APIErrorEnvelope
+		json.Unmarshal(w.Body.Bytes(), &body)
+		if w.Code != tt.wantStatus || body.Error.Code != tt.wantCode || body.Error.Message != tt.wantMessage {
+			t.Errorf("apiFail(%v) = %d %+v, want %d %s %q", tt.err, w.Code, body.Error, tt.wantStatus, tt.wantCode, tt.wantMessage)
+		}
+		if tt.wantStatus >= 500 && strings.Contains(w.Body.String(), tt.err.Error()) {
+			t.Errorf("apiFail(%v) exposes the error: %s", tt.err, w.Body.String())
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package web
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"shin/internal/backup"
+	"shin/internal/ingest"
+
+	"github.com/gin-gonic/gin"
+)
+
+// exportData 下载全部数据的 JSONL，与 shin export 相同
+func exportData(c *gin.Context) {
+	c.Header("Content-Type", "application/x-ndjson")
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="shin-%s.jsonl"`, time.Now().In(location).Format("20060102")))
+	counts, err := backup.Export(st, c.Writer)
+	if err != nil {
+		// 响应已经开始写出，只能记录日志
+		logger.Println("exportData:", err)
+		return
+	}
+	logger.Println("exportData:", counts)
+}
+
+// exportOPML 下载 shin_feed 中的订阅列表
+func exportOPML(c *gin.Context) {
+	feeds, err := st.ListFeeds()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.Header("Content-Type", "text/x-opml; charset=utf-8")
+	c.Header("Content-Disposition", `attachment; filename="shin-subscriptions.opml"`)
+	if err := backup.WriteOPML(c.Writer, feeds); err != nil {
+		logger.Println("exportOPML:", err)
+	}
+}
+
+// importData 导入 exportData 导出的 JSONL，请求体就是文件内容
+func importData(c *gin.Context) {
+	counts, err := backup.Import(st, c.Request.Body)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, backup.ErrInvalidExport) || errors.Is(err, ingest.ErrInvalidRule) {
+			status = http.StatusBadRequest
+		}
+		c.JSON(status, gin.H{"error": err.Error(), "imported": counts})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Imported", "imported": counts})
+}
@@ -0,0 +1,216 @@
+package web
+
+import (
+	"encoding/json"
+	"hash/fnv"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"shin/internal/store"
+)
+
+var (
+	clusterEnabled       = os.Getenv("CLUSTER_ENABLED") != "false"
+	clusterThreshold     = 0.45
+	clusterWindowDays, _ = strconv.Atoi(os.Getenv("CLUSTER_WINDOW_DAYS"))
+	minHashSeeds         = makeMinHashSeeds(64)
+)
+
+func init() {
+	if v, err := strconv.ParseFloat(os.Getenv("CLUSTER_THRESHOLD"), 64); err == nil && v > 0 {
+		clusterThreshold = v
+	}
+}
+
+// ItemCluster 是同一个故事在多个订阅中的条目，Representative 之外的条目放在 Also 里
+type ItemCluster struct {
+	Representative store.PostItem   `json:"representative"`
+	Also           []store.PostItem `json:"also"`
+}
+
+func makeMinHashSeeds(n int) []uint64 {
+	seeds := make([]uint64, n)
+	x := uint64(0x9e3779b97f4a7c15)
+	for i := range seeds {
+		x = mix64(x + uint64(i))
+		seeds[i] = x
+	}
+	return seeds
+}
+
+// mix64 是 splitmix64 的混淆函数
+func mix64(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
+
+func normalizeTitle(title string) []rune {
+	var runes []rune
+	space := true
+	for _, r := range strings.ToLower(title) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			runes = append(runes, r)
+			space = false
+		} else if !space {
+			runes = append(runes, ' ')
+			space = true
+		}
+	}
+	return []rune(strings.TrimSpace(string(runes)))
+}
+
+// shingles 按字符 3-gram 切分，中英文标题都适用
+func shingles(title string, set map[string]struct{}) {
+	runes := normalizeTitle(title)
+	if len(runes) == 0 {
+		return
+	}
+	if len(runes) < 3 {
+		set[string(runes)] = struct{}{}
+		return
+	}
+	for i := 0; i+3 <= len(runes); i++ {
+		set[string(runes[i:i+3])] = struct{}{}
+	}
+}
+
+// minHashSignature 对原标题和译文标题的 shingle 并集计算 MinHash 签名
+func minHashSignature(content store.PostItemContent) []uint64 {
+	set := make(map[string]struct{})
+	shingles(content.Title, set)
+	shingles(content.CnTitle, set)
+	if len(set) == 0 {
+		return nil
+	}
+
+	sig := make([]uint64, len(minHashSeeds))
+	for i := range sig {
+		sig[i] = ^uint64(0)
+	}
+	for shingle := range set {
+		h := fnv.New64a()
+		h.Write([]byte(shingle))
+		base := h.Sum64()
+		for i, seed := range minHashSeeds {
+			if v := mix64(base ^ seed); v < sig[i] {
+				sig[i] = v
+			}
+		}
+	}
+	return sig
+}
+
+func minHashSimilarity(a, b []uint64) float64 {
+	if len(a) == 0 || len(a) != len(b) {
+		return 0
+	}
+	same := 0
+	for i := range a {
+		if a[i] == b[i] {
+			same++
+		}
+	}
+	return float64(same) / float64(len(a))
+}
+
+// itemBefore 按入库时间排序，同一秒入库的按 ID
+func itemBefore(a, b store.PostItem) bool {
+	if a.CreatedAt != b.CreatedAt {
+		return a.CreatedAt < b.CreatedAt
+	}
+	return a.ID < b.ID
+}
+
+// clusterItems 把 postItems 中相似的条目聚类，windowItems 只会被挂到已有的聚类上
+func clusterItems(postItems, windowItems []store.PostItem, threshold float64) []ItemCluster {
+	all := append(append([]store.PostItem{}, postItems...), windowItems...)
+	sigs := make([][]uint64, len(all))
+	for i, item := range all {
+		var content store.PostItemContent
+		json.Unmarshal([]byte(item.Content), &content)
+		sigs[i] = minHashSignature(content)
+	}
+
+	parent := make([]int, len(all))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+
+	for i := range postItems {
+		for j := i + 1; j < len(all); j++ {
+			// 同一订阅内的条目不聚类
+			if all[i].FeedTitle == all[j].FeedTitle {
+				continue
+			}
+			if minHashSimilarity(sigs[i], sigs[j]) >= threshold {
+				parent[find(j)] = find(i)
+			}
+		}
+	}
+
+	members := make(map[int][]int)
+	for i := range all {
+		root := find(i)
+		members[root] = append(members[root], i)
+	}
+
+	var clusters []ItemCluster
+	for _, indexes := range members {
+		if len(indexes) < 2 {
+			continue
+		}
+		// 代表条目取本期中最早入库的条目
+		rep := -1
+		for _, i := range indexes {
+			if i < len(postItems) && (rep < 0 || itemBefore(all[i], all[rep])) {
+				rep = i
+			}
+		}
+		if rep < 0 {
+			continue
+		}
+		cluster := ItemCluster{Representative: all[rep]}
+		for _, i := range indexes {
+			if i != rep {
+				cluster.Also = append(cluster.Also, all[i])
+			}
+		}
+		sort.Slice(cluster.Also, func(a, b int) bool { return itemBefore(cluster.Also[a], cluster.Also[b]) })
+		clusters = append(clusters, cluster)
+	}
+	sort.Slice(clusters, func(a, b int) bool { return itemBefore(clusters[a].Representative, clusters[b].Representative) })
+	return clusters
+}
+
+// getPostClusters 计算一期摘要内的聚类，配置了 CLUSTER_WINDOW_DAYS 时也与之前 N 天的条目比较
+func getPostClusters(post store.Post) ([]ItemCluster, error) {
+	postItems, err := st.PostItems(post.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	var windowItems []store.PostItem
+	if clusterWindowDays > 0 {
+		since := post.CreatedAt - int64(clusterWindowDays)*24*3600
+		windowItems, err = st.ItemsOfPostsBetween(post.ID, since, post.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return clusterItems(postItems, windowItems, clusterThreshold), nil
+}
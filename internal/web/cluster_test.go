@@ -0,0 +1,87 @@
+package web
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"shin/internal/store"
+)
+
+// clusterItem 构造聚类用的条目，ID 故意与入库时间的顺序不同
+func clusterItem(id, feedTitle, title string, createdAt int64) store.PostItem {
+	content, _ := json.Marshal(store.PostItemContent{Title: title, CnTitle: title})
+	return store.PostItem{ID: id, FeedTitle: feedTitle, Content: string(content), CreatedAt: createdAt}
+}
+
+// clusterIDs 把聚类写成代表条目 ID 和其余条目 ID
+func clusterIDs(clusters []ItemCluster) [][]string {
+	var ids [][]string
+	for _, c := range clusters {
+		group := []string{c.Representative.ID}
+		for _, item := range c.Also {
+			group = append(group, item.ID)
+		}
+		ids = append(ids, group)
+	}
+	return ids
+}
+
+func TestMinHashSimilarity(t *testing.T) {
+	sig := func(title string) []uint64 {
+		return minHashSignature(store.PostItemContent{Title: title})
+	}
+	a := sig("OpenAI releases GPT-5 with new reasoning features")
+	if !reflect.DeepEqual(a, sig("OpenAI releases GPT-5 with new reasoning features")) {
+		t.Fatal("signature is not deterministic")
+	}
+	if got := minHashSimilarity(a, sig("openai RELEASES gpt 5, with new reasoning features!")); got != 1 {
+		t.Errorf("similarity after normalizing = %v, want 1", got)
+	}
+	if got := minHashSimilarity(a, sig("OpenAI releases GPT-5 with new reasoning features today")); got < clusterThreshold {
+		t.Errorf("near duplicate similarity = %v, want >= %v", got, clusterThreshold)
+	}
+	if got := minHashSimilarity(a, sig("Rust 1.80 stabilizes lazy cell types")); got >= clusterThreshold {
+		t.Errorf("distinct similarity = %v, want < %v", got, clusterThreshold)
+	}
+	if sig("!!!") != nil || minHashSimilarity(nil, a) != 0 {
+		t.Error("empty title should not match anything")
+	}
+}
+
+func TestClusterItems(t *testing.T) {
+	postItems := []store.PostItem{
+		clusterItem("09", "HN", "OpenAI releases GPT-5 with new reasoning features", 100),
+		clusterItem("01", "Lobsters", "OpenAI releases GPT-5 with new reasoning features today", 200),
+		clusterItem("05", "Reddit", "OpenAI Releases GPT-5, With New Reasoning Features!", 300),
+		clusterItem("07", "Lobsters", "Rust 1.80 stabilizes lazy cell types", 150),
+		clusterItem("06", "Lobsters", "Rust 1.80 stabilizes lazy cell types (discussion)", 160),
+		clusterItem("04", "HN", "Postgres 17 adds incremental backups", 400),
+		clusterItem("03", "Reddit", "Postgres 17 adds incremental backups", 400),
+		clusterItem("02", "HN", "A completely unrelated story about gardening", 50),
+		clusterItem("10", "HN", "Go 1.23 ships range over func iterators", 500),
+		clusterItem("11", "HN", "Go 1.23 ships range over func iterators", 510),
+	}
+	windowItems := []store.PostItem{
+		clusterItem("00", "Blog", "Rust 1.80 stabilizes lazy cell types in std", 10),
+		clusterItem("08", "Blog", "Yet another unrelated post about cooking", 20),
+	}
+
+	// 代表条目取本期中最早入库的，同一秒入库的按 ID；之前的条目只挂在已有的聚类上。
+	// 同一订阅内的条目不直接聚类，但会经由其他订阅的相似条目连到一起
+	want := [][]string{
+		{"09", "01", "05"},
+		{"07", "00", "06"},
+		{"03", "04"},
+	}
+	for i := 0; i < 10; i++ {
+		if got := clusterIDs(clusterItems(postItems, windowItems, clusterThreshold)); !reflect.DeepEqual(got, want) {
+			t.Fatalf("clusterItems() = %v, want %v", got, want)
+		}
+	}
+
+	// 之前的条目之间不聚类
+	if got := clusterItems(nil, windowItems[:1], clusterThreshold); len(got) != 0 {
+		t.Errorf("clusterItems(window only) = %v", clusterIDs(got))
+	}
+}
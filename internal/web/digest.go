@@ -0,0 +1,242 @@
+package web
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"shin/internal/store"
+)
+
+var (
+	digestSchedule, digestScheduleErr = parseSchedule(os.Getenv("DIGEST_SCHEDULE"))
+	timezone                          = os.Getenv("TIMEZONE")
+	location                          = loadLocation(timezone)
+)
+
+func loadLocation(name string) *time.Location {
+	if name == "" {
+		name = "Asia/Shanghai"
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		logger.Printf("Invalid TIMEZONE %q, fallback to UTC: %v", name, err)
+		return time.UTC
+	}
+	return loc
+}
+
+// cronSpec 是标准的 5 段 cron 表达式：分 时 日 月 周
+type cronSpec struct {
+	minute, hour, dom, month, dow uint64
+	domStar, dowStar              bool
+}
+
+// Schedule 由一个或多个 cronSpec 组成，取最近的触发时间
+type Schedule []cronSpec
+
+// parseSchedule 支持 cron 表达式（"0 8,18 * * *"，多条用 ";" 分隔）
+// 以及时间列表（"08:00,18:00"），空字符串表示不按计划切分
+func parseSchedule(expr string) (Schedule, error) {
+	expr = strings.TrimSpace(expr)
+	if expr == "" {
+		return nil, nil
+	}
+
+	var schedule Schedule
+	if strings.Contains(expr, ":") {
+		for _, hm := range strings.Split(expr, ",") {
+			parts := strings.Split(strings.TrimSpace(hm), ":")
+			if len(parts) != 2 {
+				return nil, fmt.Errorf("invalid time %q", hm)
+			}
+			spec, err := parseCron(fmt.Sprintf("%s %s * * *", parts[1], parts[0]))
+			if err != nil {
+				return nil, err
+			}
+			schedule = append(schedule, spec)
+		}
+		return schedule, nil
+	}
+
+	for _, line := range strings.Split(expr, ";") {
+		spec, err := parseCron(line)
+		if err != nil {
+			return nil, err
+		}
+		schedule = append(schedule, spec)
+	}
+	return schedule, nil
+}
+
+func parseCron(expr string) (cronSpec, error) {
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return cronSpec{}, fmt.Errorf("invalid cron expression %q: expected 5 fields", expr)
+	}
+
+	var spec cronSpec
+	var err error
+	if spec.minute, err = parseCronField(fields[0], 0, 59); err != nil {
+		return spec, err
+	}
+	if spec.hour, err = parseCronField(fields[1], 0, 23); err != nil {
+		return spec, err
+	}
+	if spec.dom, err = parseCronField(fields[2], 1, 31); err != nil {
+		return spec, err
+	}
+	if spec.month, err = parseCronField(fields[3], 1, 12); err != nil {
+		return spec, err
+	}
+	if spec.dow, err = parseCronField(fields[4], 0, 7); err != nil {
+		return spec, err
+	}
+	// 周日可以写成 0 或 7
+	if spec.dow&(1<<7) != 0 {
+		spec.dow |= 1
+	}
+	spec.domStar = fields[2] == "*"
+	spec.dowStar = fields[4] == "*"
+	return spec, nil
+}
+
+// parseCronField 把 "*", "*/5", "1-5", "1,3,5", "10-20/2" 解析为位集合
+func parseCronField(field string, min, max int) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		step := 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			var err error
+			if step, err = strconv.Atoi(part[i+1:]); err != nil || step <= 0 {
+				return 0, fmt.Errorf("invalid step in %q", field)
+			}
+			part = part[:i]
+		}
+
+		start, end := min, max
+		if part != "*" {
+			bounds := strings.SplitN(part, "-", 2)
+			var err error
+			if start, err = strconv.Atoi(bounds[0]); err != nil {
+				return 0, fmt.Errorf("invalid value in %q", field)
+			}
+			end = start
+			if len(bounds) == 2 {
+				if end, err = strconv.Atoi(bounds[1]); err != nil {
+					return 0, fmt.Errorf("invalid range in %q", field)
+				}
+			} else if step > 1 {
+				end = max
+			}
+		}
+		if start < min || end > max || start > end {
+			return 0, fmt.Errorf("value out of range [%d, %d] in %q", min, max, field)
+		}
+		for v := start; v <= end; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+func (s cronSpec) matchDay(t time.Time) bool {
+	domMatch := s.dom&(1<<uint(t.Day())) != 0
+	dowMatch := s.dow&(1<<uint(t.Weekday())) != 0
+	// 与 cron 一致：日和周都有限制时满足其一即可
+	if s.domStar || s.dowStar {
+		return domMatch && dowMatch
+	}
+	return domMatch || dowMatch
+}
+
+func (s cronSpec) next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		if s.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !s.matchDay(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if s.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+			continue
+		}
+		if s.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+// Next 返回 t 之后最近的触发时间，按 t 所在时区计算
+func (s Schedule) Next(t time.Time) time.Time {
+	var next time.Time
+	for _, spec := range s {
+		if n := spec.next(t); !n.IsZero() && (next.IsZero() || n.Before(next)) {
+			next = n
+		}
+	}
+	return next
+}
+
+// cutDigest 把所有未分配的条目归入一期新的摘要
+func cutDigest() (string, error) {
+	postID := store.NewID()
+	subject := fmt.Sprintf("RSS %s", time.Now().In(location).Format("2006-01-02 15:04:05"))
+	count, err := st.CutPost(postID, subject)
+	if err != nil {
+		return "", err
+	}
+	if count == 0 {
+		logger.Println("No unassigned items.")
+		return "", nil
+	}
+	logger.Printf("cutDigest: %s %s items: %d", postID, subject, count)
+
+	if llmAPIURL != "" {
+		if err := summarizePost(postID); err != nil {
+			logger.Println("summarizePost:", err)
+		}
+	}
+	if emailEnabled() {
+		if err := sendDigestEmail(postID); err != nil {
+			logger.Println("sendDigestEmail:", err)
+		}
+	}
+	return postID, nil
+}
+
+// DigestTask 按 DIGEST_SCHEDULE 定时切分摘要
+func DigestTask() {
+	logger.Println("Starting digest schedule:", os.Getenv("DIGEST_SCHEDULE"), location)
+	for {
+		now := time.Now().In(location)
+		next := digestSchedule.Next(now)
+		if next.IsZero() {
+			logger.Println("No upcoming digest time, digest schedule stopped.")
+			return
+		}
+		logger.Println("Next digest at", next)
+		time.Sleep(next.Sub(now))
+
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					logger.Printf("Recovered from panic: %v", r)
+				}
+			}()
+			if _, err := cutDigest(); err != nil {
+				logger.Println("cutDigest:", err)
+			}
+		}()
+	}
+}
@@ -0,0 +1,156 @@
+package web
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseCronField(t *testing.T) {
+	bits := func(values ...int) uint64 {
+		var b uint64
+		for _, v := range values {
+			b |= 1 << uint(v)
+		}
+		return b
+	}
+	tests := []struct {
+		field    string
+		min, max int
+		want     uint64
+		wantErr  bool
+	}{
+		{field: "*", min: 1, max: 5, want: bits(1, 2, 3, 4, 5)},
+		{field: "*/15", min: 0, max: 59, want: bits(0, 15, 30, 45)},
+		{field: "5/20", min: 0, max: 59, want: bits(5, 25, 45)},
+		{field: "1-5", min: 0, max: 7, want: bits(1, 2, 3, 4, 5)},
+		{field: "10-20/5", min: 0, max: 59, want: bits(10, 15, 20)},
+		{field: "1,3,5", min: 0, max: 7, want: bits(1, 3, 5)},
+		{field: "0,12-14,*/20", min: 0, max: 23, want: bits(0, 12, 13, 14, 20)},
+		{field: "60", min: 0, max: 59, wantErr: true},
+		{field: "0", min: 1, max: 31, wantErr: true},
+		{field: "5-1", min: 0, max: 59, wantErr: true},
+		{field: "*/0", min: 0, max: 59, wantErr: true},
+		{field: "*/x", min: 0, max: 59, wantErr: true},
+		{field: "a", min: 0, max: 59, wantErr: true},
+		{field: "1-b", min: 0, max: 59, wantErr: true},
+		{field: "-1", min: 0, max: 59, wantErr: true},
+		{field: "1,,2", min: 0, max: 59, wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseCronField(tt.field, tt.min, tt.max)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseCronField(%q) error = %v, wantErr %v", tt.field, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && got != tt.want {
+			t.Errorf("parseCronField(%q) = %b, want %b", tt.field, got, tt.want)
+		}
+	}
+}
+
+func TestParseSchedule(t *testing.T) {
+	tests := []struct {
+		expr    string
+		specs   int
+		wantErr bool
+	}{
+		{expr: "", specs: 0},
+		{expr: "0 8,18 * * *", specs: 1},
+		{expr: "0 8 * * *; 30 20 * * 1-5", specs: 2},
+		{expr: "08:00, 18:30", specs: 2},
+		{expr: "0 8 * *", wantErr: true},
+		{expr: "0 8 * * * *", wantErr: true},
+		{expr: "0 24 * * *", wantErr: true},
+		{expr: "0 8 32 * *", wantErr: true},
+		{expr: "0 8 * 13 *", wantErr: true},
+		{expr: "0 8 * * 8", wantErr: true},
+		{expr: "0 8 * * *;", wantErr: true},
+		{expr: "08:00:00", wantErr: true},
+		{expr: "ab:cd", wantErr: true},
+		{expr: "25:00", wantErr: true},
+	}
+	for _, tt := range tests {
+		schedule, err := parseSchedule(tt.expr)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseSchedule(%q) error = %v, wantErr %v", tt.expr, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && len(schedule) != tt.specs {
+			t.Errorf("parseSchedule(%q) = %d specs, want %d", tt.expr, len(schedule), tt.specs)
+		}
+	}
+}
+
+func TestScheduleNext(t *testing.T) {
+	loc := loadLocation("")
+	at := func(year int, month time.Month, day, hour, min int) time.Time {
+		return time.Date(year, month, day, hour, min, 0, 0, loc)
+	}
+	tests := []struct {
+		expr     string
+		from     time.Time
+		want     time.Time
+		wantZero bool
+	}{
+		// 月末和年末顺延到下个月
+		{expr: "0 8,18 * * *", from: at(2024, 1, 31, 19, 0), want: at(2024, 2, 1, 8, 0)},
+		{expr: "0 0 1 * *", from: at(2024, 12, 15, 12, 0), want: at(2025, 1, 1, 0, 0)},
+		{expr: "0 9 31 * *", from: at(2024, 4, 1, 0, 0), want: at(2024, 5, 31, 9, 0)},
+		{expr: "0 0 29 2 *", from: at(2024, 3, 1, 0, 0), want: at(2028, 2, 29, 0, 0)},
+		// 只返回之后的时间
+		{expr: "*/30 * * * *", from: at(2024, 1, 1, 10, 15), want: at(2024, 1, 1, 10, 30)},
+		{expr: "*/30 * * * *", from: at(2024, 1, 1, 10, 30), want: at(2024, 1, 1, 11, 0)},
+		{expr: "*/30 * * * *", from: at(2024, 1, 1, 23, 45), want: at(2024, 1, 2, 0, 0)},
+		// 周日写成 0 或 7；日和周都有限制时满足其一即可
+		{expr: "0 9 * * 0", from: at(2024, 1, 3, 0, 0), want: at(2024, 1, 7, 9, 0)},
+		{expr: "0 9 * * 7", from: at(2024, 1, 3, 0, 0), want: at(2024, 1, 7, 9, 0)},
+		{expr: "0 9 13 * 5", from: at(2024, 1, 1, 0, 0), want: at(2024, 1, 5, 9, 0)},
+		{expr: "0 9 1-7 * *", from: at(2024, 1, 7, 10, 0), want: at(2024, 2, 1, 9, 0)},
+		// 多条取最近的
+		{expr: "08:00,18:00", from: at(2024, 1, 1, 12, 0), want: at(2024, 1, 1, 18, 0)},
+		{expr: "0 8 * * *; 30 7 * * 1-5", from: at(2024, 1, 6, 6, 0), want: at(2024, 1, 6, 8, 0)},
+		{expr: "0 8 * * *; 30 7 * * 1-5", from: at(2024, 1, 8, 6, 0), want: at(2024, 1, 8, 7, 30)},
+		// 不存在的日期没有触发时间
+		{expr: "0 0 30 2 *", from: at(2024, 1, 1, 0, 0), wantZero: true},
+		{expr: "", from: at(2024, 1, 1, 0, 0), wantZero: true},
+	}
+	for _, tt := range tests {
+		schedule, err := parseSchedule(tt.expr)
+		if err != nil {
+			t.Fatalf("parseSchedule(%q): %v", tt.expr, err)
+		}
+		got := schedule.Next(tt.from)
+		if tt.wantZero {
+			if !got.IsZero() {
+				t.Errorf("%q.Next(%s) = %s, want zero", tt.expr, tt.from, got)
+			}
+			continue
+		}
+		if !got.Equal(tt.want) {
+			t.Errorf("%q.Next(%s) = %s, want %s", tt.expr, tt.from, got, tt.want)
+		}
+	}
+}
+
+func TestLoadLocation(t *testing.T) {
+	if loc := loadLocation(""); loc.String() != "Asia/Shanghai" {
+		t.Errorf("default TIMEZONE = %s, want Asia/Shanghai", loc)
+	}
+	if loc := loadLocation("Europe/Berlin"); loc.String() != "Europe/Berlin" {
+		t.Errorf("loadLocation(Europe/Berlin) = %s", loc)
+	}
+	if loc := loadLocation("Not/AZone"); loc != time.UTC {
+		t.Errorf("invalid TIMEZONE = %s, want UTC", loc)
+	}
+
+	// Next 按传入时间的时区计算：UTC 1 月 31 日 23:30 在上海已经是 2 月 1 日
+	schedule, _ := parseSchedule("0 8 * * *")
+	from := time.Date(2024, 1, 31, 23, 30, 0, 0, time.UTC)
+	want := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+	if got := schedule.Next(from.In(loadLocation(""))); !got.Equal(want) {
+		t.Errorf("Next in Asia/Shanghai = %s, want %s", got, want)
+	}
+	if got := schedule.Next(from); !got.Equal(want.Add(8 * time.Hour)) {
+		t.Errorf("Next in UTC = %s, want %s", got, want.Add(8*time.Hour))
+	}
+}
@@ -0,0 +1,378 @@
+package web
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	htmltemplate "html/template"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/http"
+	"net/smtp"
+	"net/textproto"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"shin/internal/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	smtpHost     = os.Getenv("SMTP_HOST")
+	smtpPort     = os.Getenv("SMTP_PORT")
+	smtpUsername = os.Getenv("SMTP_USERNAME")
+	smtpPassword = os.Getenv("SMTP_PASSWORD")
+	smtpFrom     = os.Getenv("SMTP_FROM")
+	smtpTo       = os.Getenv("SMTP_TO")       // 多个收件人用逗号分隔
+	smtpSecurity = os.Getenv("SMTP_SECURITY") // starttls（默认）、tls 或 none
+
+	emailHTMLTemplate = loadEmailHTMLTemplate(os.Getenv("EMAIL_HTML_TEMPLATE"))
+	emailTextTemplate = loadEmailTextTemplate(os.Getenv("EMAIL_TEXT_TEMPLATE"))
+)
+
+const defaultEmailHTMLTemplate = `<html><body>
+<h2><a href="{{.DetailURL}}">{{.Post.Title}}</a></h2>
+{{if .Post.Summary}}<p>{{.Post.Summary}}</p>{{end}}
+{{range .Groups}}<h3>{{.FeedTitle}}</h3>
+<ul>
+{{range .Items}}<li>{{.CnTitle}} <a href="{{.Link}}">{{.Title}}</a>{{if .SaveURL}} <a href="{{.SaveURL}}">💾</a>{{end}}</li>
+{{end}}</ul>
+{{end}}</body></html>
+`
+
+const defaultEmailTextTemplate = `{{.Post.Title}}
+{{.DetailURL}}
+{{if .Post.Summary}}
+{{.Post.Summary}}
+{{end}}{{range .Groups}}
+## {{.FeedTitle}}
+{{range .Items}}
+- {{.CnTitle}}
+  {{.Title}}
+  {{.Link}}{{if .SaveURL}}
+  Save: {{.SaveURL}}{{end}}
+{{end}}{{end}}`
+
+// EmailData 是邮件模板中可以使用的字段
+type EmailData struct {
+	Post      store.Post
+	DetailURL string
+	Groups    []EmailGroup
+}
+
+type EmailGroup struct {
+	FeedTitle string
+	Items     []EmailItem
+}
+
+type EmailItem struct {
+	ID      string
+	CnTitle string
+	Title   string
+	Link    string
+	SaveURL string // 打开确认页，确认后保存到第一个保存目标，未配置 BASE_URL 或保存目标时为空
+}
+
+func readTemplateFile(path string) string {
+	if path == "" {
+		return ""
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		logger.Println("Failed to read email template, fallback to default:", err)
+		return ""
+	}
+	return string(data)
+}
+
+func loadEmailHTMLTemplate(path string) *htmltemplate.Template {
+	if text := readTemplateFile(path); text != "" {
+		tmpl, err := htmltemplate.New("email.html").Parse(text)
+		if err == nil {
+			return tmpl
+		}
+		logger.Println("Invalid EMAIL_HTML_TEMPLATE, fallback to default:", err)
+	}
+	return htmltemplate.Must(htmltemplate.New("email.html").Parse(defaultEmailHTMLTemplate))
+}
+
+func loadEmailTextTemplate(path string) *template.Template {
+	if text := readTemplateFile(path); text != "" {
+		tmpl, err := template.New("email.txt").Parse(text)
+		if err == nil {
+			return tmpl
+		}
+		logger.Println("Invalid EMAIL_TEXT_TEMPLATE, fallback to default:", err)
+	}
+	return template.Must(template.New("email.txt").Parse(defaultEmailTextTemplate))
+}
+
+func emailEnabled() bool {
+	return smtpHost != "" && smtpTo != ""
+}
+
+func emailRecipients() []string {
+	var to []string
+	for _, addr := range strings.Split(smtpTo, ",") {
+		if addr = strings.TrimSpace(addr); addr != "" {
+			to = append(to, addr)
+		}
+	}
+	return to
+}
+
+// emailDataFor 与 getPostItemsGroupedByFeedTitle 一样按订阅分组
+func emailDataFor(post store.Post) (EmailData, error) {
+	grouped, err := getPostItemsGroupedByFeedTitle(post.ID)
+	if err != nil {
+		return EmailData{}, err
+	}
+
+	data := EmailData{Post: post}
+	if baseURL != "" {
+		data.DetailURL = baseURL + "/detail?id=" + post.ID
+	}
+
+	feedTitles := make([]string, 0, len(grouped))
+	for feedTitle := range grouped {
+		feedTitles = append(feedTitles, feedTitle)
+	}
+	sort.Strings(feedTitles)
+
+	for _, feedTitle := range feedTitles {
+		group := EmailGroup{FeedTitle: feedTitle}
+		for _, item := range grouped[feedTitle] {
+			var content store.PostItemContent
+			json.Unmarshal([]byte(item.Content), &content)
+			emailItem := EmailItem{ID: item.ID, CnTitle: content.CnTitle, Title: content.Title, Link: content.Link}
+			if baseURL != "" && len(sinks) > 0 {
+				emailItem.SaveURL = baseURL + "/save?id=" + url.QueryEscape(item.ID)
+			}
+			group.Items = append(group.Items, emailItem)
+		}
+		data.Groups = append(data.Groups, group)
+	}
+	return data, nil
+}
+
+func writeQuotedPrintablePart(w *multipart.Writer, contentType, body string) error {
+	part, err := w.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {contentType},
+		"Content-Transfer-Encoding": {"quoted-printable"},
+	})
+	if err != nil {
+		return err
+	}
+	qp := quotedprintable.NewWriter(part)
+	if _, err := qp.Write([]byte(body)); err != nil {
+		return err
+	}
+	return qp.Close()
+}
+
+// buildDigestEmail 生成包含纯文本和 HTML 两部分的邮件
+func buildDigestEmail(from string, to []string, data EmailData) ([]byte, error) {
+	var textBody, htmlBody strings.Builder
+	if err := emailTextTemplate.Execute(&textBody, data); err != nil {
+		return nil, fmt.Errorf("failed to render text email: %w", err)
+	}
+	if err := emailHTMLTemplate.Execute(&htmlBody, data); err != nil {
+		return nil, fmt.Errorf("failed to render html email: %w", err)
+	}
+
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	if err := writeQuotedPrintablePart(w, "text/plain; charset=UTF-8", textBody.String()); err != nil {
+		return nil, err
+	}
+	if err := writeQuotedPrintablePart(w, "text/html; charset=UTF-8", htmlBody.String()); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", from)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(to, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("UTF-8", data.Post.Title))
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&msg, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&msg, "Content-Type: multipart/alternative; boundary=%s\r\n\r\n", w.Boundary())
+	msg.Write(body.Bytes())
+	return msg.Bytes(), nil
+}
+
+func sendMail(from string, to []string, msg []byte) error {
+	port := smtpPort
+	if port == "" {
+		port = "587"
+		if smtpSecurity == "tls" {
+			port = "465"
+		}
+	}
+	addr := net.JoinHostPort(smtpHost, port)
+	tlsConfig := &tls.Config{ServerName: smtpHost}
+
+	var conn net.Conn
+	var err error
+	if smtpSecurity == "tls" {
+		conn, err = tls.DialWithDialer(&net.Dialer{Timeout: 30 * time.Second}, "tcp", addr, tlsConfig)
+	} else {
+		conn, err = net.DialTimeout("tcp", addr, 30*time.Second)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to connect to %s: %w", addr, err)
+	}
+	conn.SetDeadline(time.Now().Add(2 * time.Minute))
+
+	client, err := smtp.NewClient(conn, smtpHost)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if smtpSecurity == "" || smtpSecurity == "starttls" {
+		if err := client.StartTLS(tlsConfig); err != nil {
+			return fmt.Errorf("starttls: %w", err)
+		}
+	}
+	if smtpUsername != "" {
+		if err := client.Auth(smtp.PlainAuth("", smtpUsername, smtpPassword, smtpHost)); err != nil {
+			return fmt.Errorf("auth: %w", err)
+		}
+	}
+
+	if err := client.Mail(from); err != nil {
+		return err
+	}
+	for _, addr := range to {
+		if err := client.Rcpt(addr); err != nil {
+			return err
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// sendDigestEmail 把一期摘要发送到 SMTP_TO
+func sendDigestEmail(postID string) error {
+	post, err := st.GetPost(postID)
+	if err != nil {
+		return fmt.Errorf("failed to load post: %w", err)
+	}
+	data, err := emailDataFor(*post)
+	if err != nil {
+		return err
+	}
+
+	from := smtpFrom
+	if from == "" {
+		from = smtpUsername
+	}
+	to := emailRecipients()
+	msg, err := buildDigestEmail(from, to, data)
+	if err != nil {
+		return err
+	}
+	if err := sendMail(from, to, msg); err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+	logger.Printf("Digest email sent: %s to %s", postID, strings.Join(to, ", "))
+	return nil
+}
+
+// saveLinkSink 返回保存链接的目标，未指定时使用第一个保存目标
+func saveLinkSink(sinkName string) string {
+	if sinkName == "" && len(sinks) > 0 {
+		return sinks[0].Name()
+	}
+	return sinkName
+}
+
+// confirmSaveFromLink 是邮件中保存链接打开的确认页。邮件扫描和链接预取会请求 GET，
+// 所以 GET 只展示条目，点击确认后才 POST 到 /save 保存
+func confirmSaveFromLink(c *gin.Context) {
+	itemID := c.Query("id")
+	sinkName := saveLinkSink(c.Query("sink"))
+	if _, err := getSink(sinkName); err != nil {
+		c.String(saveErrorStatus(err), err.Error())
+		return
+	}
+	item, err := st.GetPostItem(itemID)
+	if err != nil {
+		c.String(rowErrorStatus(err), "Item not found")
+		return
+	}
+
+	var content store.PostItemContent
+	json.Unmarshal([]byte(item.Content), &content)
+	_, savedErr := st.GetSavedID(itemID, sinkName)
+	c.HTML(http.StatusOK, "save.html", gin.H{
+		"ID":      item.ID,
+		"PostID":  item.PostID,
+		"Sink":    sinkName,
+		"CnTitle": content.CnTitle,
+		"Title":   content.Title,
+		"Link":    content.Link,
+		"Saved":   savedErr == nil,
+	})
+}
+
+// saveFromLink 处理确认页提交的保存，保存后跳转到所在的摘要
+func saveFromLink(c *gin.Context) {
+	itemID := c.PostForm("id")
+	sinkName := saveLinkSink(c.PostForm("sink"))
+
+	if _, _, err := EnqueueSave(itemID, sinkName, ""); err != nil {
+		c.String(saveErrorStatus(err), err.Error())
+		return
+	}
+
+	item, err := st.GetPostItem(itemID)
+	if err != nil {
+		c.String(http.StatusNotFound, "Item not found")
+		return
+	}
+	c.Redirect(http.StatusSeeOther, "/detail?id="+url.QueryEscape(item.PostID))
+}
+
+func resendDigestEmail(c *gin.Context) {
+	var input struct {
+		PostID string `json:"post_id"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !emailEnabled() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "SMTP_HOST and SMTP_TO are not configured"})
+		return
+	}
+
+	if err := sendDigestEmail(input.PostID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Email sent"})
+}
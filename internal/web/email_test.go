@@ -0,0 +1,234 @@
+package web
+
+import (
+	"bufio"
+	"encoding/base64"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/mail"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// smtpStub 是进程内的 SMTP 服务器，只实现发信用到的命令，收到的邮件记录在 messages
+type smtpStub struct {
+	mu       sync.Mutex
+	auth     []string
+	from     []string
+	rcpt     []string
+	messages []string
+}
+
+func startSMTPStub(t *testing.T) (*smtpStub, string) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	stub := &smtpStub{}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go stub.serve(conn)
+		}
+	}()
+	return stub, ln.Addr().String()
+}
+
+func (s *smtpStub) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	reply := func(line string) { io.WriteString(conn, line+"\r\n") }
+
+	reply("220 localhost ESMTP stub")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		verb, arg, _ := strings.Cut(line, " ")
+		s.mu.Lock()
+		switch strings.ToUpper(verb) {
+		case "EHLO", "HELO":
+			reply("250-localhost")
+			reply("250 AUTH PLAIN")
+		case "AUTH":
+			_, credentials, _ := strings.Cut(arg, " ")
+			decoded, _ := base64.StdEncoding.DecodeString(credentials)
+			s.auth = append(s.auth, string(decoded))
+			reply("235 2.7.0 Authentication successful")
+		case "MAIL":
+			s.from = append(s.from, arg)
+			reply("250 OK")
+		case "RCPT":
+			s.rcpt = append(s.rcpt, arg)
+			reply("250 OK")
+		case "DATA":
+			reply("354 End data with <CR><LF>.<CR><LF>")
+			s.mu.Unlock()
+			var data strings.Builder
+			for {
+				l, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if l == ".\r\n" {
+					break
+				}
+				data.WriteString(strings.TrimPrefix(l, "."))
+			}
+			s.mu.Lock()
+			s.messages = append(s.messages, data.String())
+			reply("250 OK queued")
+		case "QUIT":
+			reply("221 Bye")
+			s.mu.Unlock()
+			return
+		default:
+			reply("502 Command not implemented")
+		}
+		s.mu.Unlock()
+	}
+}
+
+// useSMTP 把 SMTP 配置指向 addr，测试结束后恢复
+func useSMTP(t *testing.T, addr string) {
+	t.Helper()
+	host, port, _ := net.SplitHostPort(addr)
+	old := []string{smtpHost, smtpPort, smtpUsername, smtpPassword, smtpFrom, smtpTo, smtpSecurity, baseURL}
+	smtpHost, smtpPort, smtpUsername, smtpPassword = host, port, "shin", "secret"
+	smtpFrom, smtpTo, smtpSecurity = "shin@example.com", "a@example.com, b@example.com", "none"
+	baseURL = "https://shin.example.com"
+	t.Cleanup(func() {
+		smtpHost, smtpPort, smtpUsername, smtpPassword = old[0], old[1], old[2], old[3]
+		smtpFrom, smtpTo, smtpSecurity, baseURL = old[4], old[5], old[6], old[7]
+	})
+}
+
+func TestSendDigestEmail(t *testing.T) {
+	useTestStore(t)
+	useSinks(t, &fakeSink{name: "fake"})
+	stub, addr := startSMTPStub(t)
+	useSMTP(t, addr)
+
+	item := insertTestItem(t, "Example Feed", "SQLite 3.47 released", "https://example.com/sqlite")
+	if _, err := st.CutPost("p1", "每日摘要"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := sendDigestEmail("p1"); err != nil {
+		t.Fatalf("sendDigestEmail() error = %v", err)
+	}
+
+	stub.mu.Lock()
+	defer stub.mu.Unlock()
+	if len(stub.messages) != 1 {
+		t.Fatalf("received %d messages, want 1", len(stub.messages))
+	}
+	if len(stub.auth) != 1 || stub.auth[0] != "\x00shin\x00secret" {
+		t.Errorf("AUTH = %q", stub.auth)
+	}
+	if strings.Join(stub.rcpt, ",") != "TO:<a@example.com>,TO:<b@example.com>" {
+		t.Errorf("RCPT = %q", stub.rcpt)
+	}
+
+	msg, err := mail.ReadMessage(strings.NewReader(stub.messages[0]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	subject, _ := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	if subject != "每日摘要" {
+		t.Errorf("Subject = %q", subject)
+	}
+	_, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	saveURL := "https://shin.example.com/save?id=" + url.QueryEscape(item.ID)
+	parts := multipart.NewReader(msg.Body, params["boundary"])
+	var contentTypes []string
+	for {
+		part, err := parts.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		contentTypes = append(contentTypes, part.Header.Get("Content-Type"))
+		// multipart.Reader 已经解码了 quoted-printable
+		body, _ := io.ReadAll(part)
+		for _, want := range []string{"SQLite 3.47 released", "https://shin.example.com/detail?id=p1", saveURL} {
+			if !strings.Contains(string(body), want) {
+				t.Errorf("%s part does not contain %q:\n%s", part.Header.Get("Content-Type"), want, body)
+			}
+		}
+	}
+	if strings.Join(contentTypes, ",") != "text/plain; charset=UTF-8,text/html; charset=UTF-8" {
+		t.Errorf("parts = %q", contentTypes)
+	}
+}
+
+func TestSaveLinkConfirmsBeforeSaving(t *testing.T) {
+	useTestStore(t)
+	sink := &fakeSink{name: "fake"}
+	useSinks(t, sink)
+	item := insertTestItem(t, "Feed", "Title", "https://example.com/a")
+	st.CutPost("p1", "Digest")
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.LoadHTMLGlob("../../templates/*")
+	r.GET("/save", confirmSaveFromLink)
+	r.POST("/save", saveFromLink)
+
+	// GET 只展示确认页，不保存
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/save?id="+item.ID, nil))
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `<form action="/save" method="post">`) {
+		t.Fatalf("GET /save = %d\n%s", w.Code, w.Body.String())
+	}
+	processOutbox()
+	if sink.savedCount() != 0 {
+		t.Fatalf("GET /save saved the item")
+	}
+
+	w = httptest.NewRecorder()
+	req := httptest.NewRequest("POST", "/save", strings.NewReader(url.Values{"id": {item.ID}}.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusSeeOther || w.Header().Get("Location") != "/detail?id=p1" {
+		t.Fatalf("POST /save = %d %s", w.Code, w.Header().Get("Location"))
+	}
+	processOutbox()
+	if sink.savedCount() != 1 {
+		t.Errorf("saved %d times after POST, want 1", sink.savedCount())
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/save?id="+item.ID, nil))
+	if !strings.Contains(w.Body.String(), "Already saved") {
+		t.Errorf("GET /save after saving does not say so:\n%s", w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/save?id=missing", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("GET /save?id=missing = %d, want 404", w.Code)
+	}
+}
@@ -0,0 +1,557 @@
+package web
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"syscall"
+	"time"
+
+	"shin/internal/store"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+	"golang.org/x/net/html/charset"
+)
+
+var (
+	articleExtractEnabled = os.Getenv("ARTICLE_EXTRACT_ENABLED") == "true"
+	articleMaxBytes       = int64(5 << 20)
+	unlikelyCandidates    = regexp.MustCompile(`(?i)comment|footer|sidebar|nav|menu|share|social|advert|promo|related|subscribe|cookie|popup|banner|breadcrumb|masthead`)
+	maybeCandidates       = regexp.MustCompile(`(?i)article|content|main|post|entry|body|story|text`)
+	positiveCandidates    = regexp.MustCompile(`(?i)article|content|main|post|entry|body|story|text|blog`)
+	negativeCandidates    = regexp.MustCompile(`(?i)comment|footer|sidebar|nav|menu|share|social|advert|promo|related|meta|widget|hidden`)
+	whitespaceRegex       = regexp.MustCompile(`[ \t\r\f\v]+`)
+	blankLinesRegex       = regexp.MustCompile(`\n{3,}`)
+)
+
+var (
+	articleClient       = newArticleClient()
+	errNonPublicAddress = errors.New("refusing to fetch non-public address")
+
+	// extractQueue 是入库后等待抽取正文的条目，由 ExtractTask 的 worker 在后台处理。
+	// 队列满时丢弃，丢弃的条目在阅读视图打开时再抽取
+	extractQueue   = make(chan store.PostItem, 500)
+	extractWorkers = 2
+)
+
+// 这些元素连同内容一起丢弃
+var droppedTags = map[atom.Atom]bool{
+	atom.Script: true, atom.Style: true, atom.Noscript: true, atom.Iframe: true,
+	atom.Form: true, atom.Nav: true, atom.Header: true, atom.Footer: true,
+	atom.Aside: true, atom.Svg: true, atom.Button: true, atom.Input: true,
+	atom.Select: true, atom.Textarea: true, atom.Object: true, atom.Embed: true,
+	atom.Canvas: true, atom.Template: true, atom.Link: true, atom.Meta: true,
+}
+
+// 清洗后允许保留的元素，其余元素只保留子节点
+var allowedTags = map[atom.Atom]bool{
+	atom.P: true, atom.Br: true, atom.Hr: true, atom.H1: true, atom.H2: true,
+	atom.H3: true, atom.H4: true, atom.H5: true, atom.H6: true, atom.Ul: true,
+	atom.Ol: true, atom.Li: true, atom.Blockquote: true, atom.Pre: true,
+	atom.Code: true, atom.Em: true, atom.Strong: true, atom.B: true, atom.I: true,
+	atom.A: true, atom.Img: true, atom.Figure: true, atom.Figcaption: true,
+	atom.Table: true, atom.Thead: true, atom.Tbody: true, atom.Tr: true,
+	atom.Th: true, atom.Td: true, atom.Dl: true, atom.Dt: true, atom.Dd: true,
+	atom.Sub: true, atom.Sup: true,
+}
+
+var blockTags = map[atom.Atom]bool{
+	atom.P: true, atom.Div: true, atom.Br: true, atom.Hr: true, atom.H1: true,
+	atom.H2: true, atom.H3: true, atom.H4: true, atom.H5: true, atom.H6: true,
+	atom.Li: true, atom.Blockquote: true, atom.Pre: true, atom.Tr: true,
+	atom.Section: true, atom.Article: true, atom.Figure: true, atom.Figcaption: true,
+	atom.Dt: true, atom.Dd: true, atom.Table: true,
+}
+
+// newArticleClient 返回只连接公网地址的客户端。链接来自订阅内容，检查放在建立连接时，
+// 跳转后的地址和 DNS 解析出的地址都会检查；不走代理，TLS 设置与全局的 http.DefaultTransport 一致
+func newArticleClient() *http.Client {
+	dialer := &net.Dialer{Timeout: 10 * time.Second, Control: publicAddressOnly}
+	return &http.Client{
+		Timeout: 60 * time.Second,
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			TLSClientConfig:     &tls.Config{InsecureSkipVerify: true},
+			TLSHandshakeTimeout: 10 * time.Second,
+		},
+	}
+}
+
+// publicAddressOnly 拒绝回环、私有、链路本地、组播和未指定地址
+func publicAddressOnly(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified() {
+		return fmt.Errorf("%w: %s", errNonPublicAddress, host)
+	}
+	return nil
+}
+
+// fetchArticle 用 articleClient 获取页面并抽取正文，只支持 http 和 https
+func fetchArticle(pageURL string) (*store.Article, error) {
+	req, err := http.NewRequest("GET", pageURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+		return nil, fmt.Errorf("unsupported scheme: %q", req.URL.Scheme)
+	}
+	req.Header.Set("User-Agent", "Mozilla/5.0 (compatible; Shin)")
+	req.Header.Set("Accept", "text/html,application/xhtml+xml")
+
+	resp, err := articleClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch page: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status: %d", resp.StatusCode)
+	}
+	contentType := resp.Header.Get("Content-Type")
+	if contentType != "" && !strings.Contains(contentType, "html") {
+		return nil, fmt.Errorf("unsupported content type: %s", contentType)
+	}
+
+	reader, err := charset.NewReader(io.LimitReader(resp.Body, articleMaxBytes), contentType)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode page: %w", err)
+	}
+
+	base := resp.Request.URL
+	return extractArticle(reader, base)
+}
+
+// extractArticle 是简化版的 readability：给段落打分，选出得分最高的容器作为正文
+func extractArticle(r io.Reader, base *url.URL) (*store.Article, error) {
+	doc, err := html.Parse(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse html: %w", err)
+	}
+
+	title := documentTitle(doc)
+	body := findFirst(doc, atom.Body)
+	if body == nil {
+		body = doc
+	}
+	removeUnlikely(body)
+
+	top := topCandidate(body)
+	if top == nil {
+		return nil, fmt.Errorf("no content found")
+	}
+
+	var sb strings.Builder
+	sanitizeNode(&sb, top, base)
+	var tb strings.Builder
+	collectText(&tb, top)
+
+	lines := strings.Split(tb.String(), "\n")
+	for i := range lines {
+		lines[i] = strings.TrimSpace(lines[i])
+	}
+	text := blankLinesRegex.ReplaceAllString(strings.TrimSpace(strings.Join(lines, "\n")), "\n\n")
+	if text == "" {
+		return nil, fmt.Errorf("no content found")
+	}
+
+	return &store.Article{
+		URL:   base.String(),
+		Title: title,
+		HTML:  sb.String(),
+		Text:  text,
+	}, nil
+}
+
+func findFirst(n *html.Node, a atom.Atom) *html.Node {
+	if n.Type == html.ElementNode && n.DataAtom == a {
+		return n
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if found := findFirst(c, a); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+func documentTitle(doc *html.Node) string {
+	var title string
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if title != "" {
+			return
+		}
+		if n.Type == html.ElementNode && n.DataAtom == atom.Meta && attr(n, "property") == "og:title" {
+			title = attr(n, "content")
+			return
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(doc)
+	if title == "" {
+		if t := findFirst(doc, atom.Title); t != nil {
+			title = textContent(t)
+		}
+	}
+	return strings.TrimSpace(title)
+}
+
+func textContent(n *html.Node) string {
+	if n.Type == html.TextNode {
+		return n.Data
+	}
+	var sb strings.Builder
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		sb.WriteString(textContent(c))
+	}
+	return sb.String()
+}
+
+func removeUnlikely(n *html.Node) {
+	for c := n.FirstChild; c != nil; {
+		next := c.NextSibling
+		if c.Type == html.CommentNode {
+			n.RemoveChild(c)
+		} else if c.Type == html.ElementNode {
+			classID := attr(c, "class") + " " + attr(c, "id")
+			if droppedTags[c.DataAtom] ||
+				(c.DataAtom != atom.Body && c.DataAtom != atom.Article && c.DataAtom != atom.Main &&
+					unlikelyCandidates.MatchString(classID) && !maybeCandidates.MatchString(classID)) {
+				n.RemoveChild(c)
+			} else {
+				removeUnlikely(c)
+			}
+		}
+		c = next
+	}
+}
+
+func classWeight(n *html.Node) float64 {
+	weight := 0.0
+	for _, s := range []string{attr(n, "class"), attr(n, "id")} {
+		if s == "" {
+			continue
+		}
+		if negativeCandidates.MatchString(s) {
+			weight -= 25
+		}
+		if positiveCandidates.MatchString(s) {
+			weight += 25
+		}
+	}
+	return weight
+}
+
+func linkDensity(n *html.Node) float64 {
+	textLength := len(strings.TrimSpace(textContent(n)))
+	if textLength == 0 {
+		return 0
+	}
+	linkLength := 0
+	var walk func(*html.Node)
+	walk = func(c *html.Node) {
+		if c.Type == html.ElementNode && c.DataAtom == atom.A {
+			linkLength += len(strings.TrimSpace(textContent(c)))
+			return
+		}
+		for cc := c.FirstChild; cc != nil; cc = cc.NextSibling {
+			walk(cc)
+		}
+	}
+	walk(n)
+	return float64(linkLength) / float64(textLength)
+}
+
+func topCandidate(body *html.Node) *html.Node {
+	scores := make(map[*html.Node]float64)
+	var order []*html.Node
+	addScore := func(n *html.Node, score float64) {
+		if n == nil || n.Type != html.ElementNode {
+			return
+		}
+		if _, ok := scores[n]; !ok {
+			scores[n] = classWeight(n)
+			if n.DataAtom == atom.Article || n.DataAtom == atom.Main {
+				scores[n] += 10
+			}
+			order = append(order, n)
+		}
+		scores[n] += score
+	}
+
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			switch n.DataAtom {
+			case atom.P, atom.Pre, atom.Td, atom.Blockquote:
+				text := strings.TrimSpace(textContent(n))
+				if len(text) >= 25 {
+					score := 1 + float64(strings.Count(text, ",")+strings.Count(text, "，")) +
+						math.Min(float64(len(text))/100, 3)
+					addScore(n.Parent, score)
+					if n.Parent != nil {
+						addScore(n.Parent.Parent, score/2)
+					}
+				}
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(body)
+
+	var top *html.Node
+	topScore := 0.0
+	for _, n := range order {
+		score := scores[n] * (1 - linkDensity(n))
+		if top == nil || score > topScore {
+			top, topScore = n, score
+		}
+	}
+	if top == nil {
+		return nil
+	}
+
+	// 把得分足够高的兄弟节点一起纳入正文
+	threshold := math.Max(10, topScore*0.2)
+	parent := top.Parent
+	if parent == nil {
+		return top
+	}
+	var siblings []*html.Node
+	for s := parent.FirstChild; s != nil; s = s.NextSibling {
+		if s == top {
+			siblings = append(siblings, s)
+			continue
+		}
+		if s.Type != html.ElementNode {
+			continue
+		}
+		if score, ok := scores[s]; ok && score*(1-linkDensity(s)) >= threshold {
+			siblings = append(siblings, s)
+		} else if s.DataAtom == atom.P && len(strings.TrimSpace(textContent(s))) > 80 && linkDensity(s) < 0.25 {
+			siblings = append(siblings, s)
+		}
+	}
+	if len(siblings) == 1 {
+		return top
+	}
+	wrapper := &html.Node{Type: html.ElementNode, DataAtom: atom.Div, Data: "div"}
+	for _, s := range siblings {
+		parent.RemoveChild(s)
+		wrapper.AppendChild(s)
+	}
+	return wrapper
+}
+
+func resolveURL(base *url.URL, ref string) string {
+	u, err := url.Parse(strings.TrimSpace(ref))
+	if err != nil {
+		return ""
+	}
+	if base != nil {
+		u = base.ResolveReference(u)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return ""
+	}
+	return u.String()
+}
+
+// sanitizeNode 只输出白名单中的元素和属性，链接与图片地址转换为绝对地址
+func sanitizeNode(sb *strings.Builder, n *html.Node, base *url.URL) {
+	switch n.Type {
+	case html.TextNode:
+		sb.WriteString(html.EscapeString(n.Data))
+		return
+	case html.ElementNode:
+	default:
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			sanitizeNode(sb, c, base)
+		}
+		return
+	}
+
+	if droppedTags[n.DataAtom] {
+		return
+	}
+	if !allowedTags[n.DataAtom] {
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			sanitizeNode(sb, c, base)
+		}
+		return
+	}
+
+	switch n.DataAtom {
+	case atom.Img:
+		src := attr(n, "src")
+		if src == "" || strings.HasPrefix(src, "data:") {
+			src = attr(n, "data-src")
+		}
+		if src = resolveURL(base, src); src != "" {
+			fmt.Fprintf(sb, `<img src="%s" alt="%s" loading="lazy">`, html.EscapeString(src), html.EscapeString(attr(n, "alt")))
+		}
+		return
+	case atom.Br, atom.Hr:
+		fmt.Fprintf(sb, "<%s>", n.Data)
+		return
+	case atom.A:
+		if href := resolveURL(base, attr(n, "href")); href != "" {
+			fmt.Fprintf(sb, `<a href="%s" target="_blank" rel="noopener noreferrer">`, html.EscapeString(href))
+		} else {
+			sb.WriteString("<a>")
+		}
+	default:
+		fmt.Fprintf(sb, "<%s>", n.Data)
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		sanitizeNode(sb, c, base)
+	}
+	fmt.Fprintf(sb, "</%s>", n.Data)
+}
+
+func collectText(sb *strings.Builder, n *html.Node) {
+	if n.Type == html.TextNode {
+		sb.WriteString(whitespaceRegex.ReplaceAllString(strings.ReplaceAll(n.Data, "\n", " "), " "))
+		return
+	}
+	if n.Type == html.ElementNode && droppedTags[n.DataAtom] {
+		return
+	}
+	block := n.Type == html.ElementNode && blockTags[n.DataAtom]
+	if block {
+		sb.WriteString("\n")
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		collectText(sb, c)
+	}
+	if block {
+		sb.WriteString("\n")
+	}
+}
+
+// extractAndSave 抽取条目链接的正文并保存，失败时也会记录状态，避免每次都重新抓取
+func extractAndSave(item store.PostItem) (*store.Article, error) {
+	var content store.PostItemContent
+	if err := json.Unmarshal([]byte(item.Content), &content); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal content: %w", err)
+	}
+
+	logger.Println("extractArticle:", item.ID, content.Link)
+	article, err := fetchArticle(content.Link)
+	if err != nil {
+		article = &store.Article{URL: content.Link, Status: "failed", Error: err.Error()}
+	} else {
+		article.Status = "ok"
+	}
+	article.ItemID = item.ID
+	article.FetchedAt = time.Now().Unix()
+
+	if saveErr := st.SaveArticle(*article); saveErr != nil {
+		return nil, saveErr
+	}
+	return article, err
+}
+
+// queueExtraction 把条目放入 extractQueue，不等待抽取完成，慢的网站不会拖住拉取流程
+func queueExtraction(items []store.PostItem) {
+	for _, item := range items {
+		select {
+		case extractQueue <- item:
+		default:
+			logger.Println("extractQueue full, skip:", item.ID)
+		}
+	}
+}
+
+// ExtractTask 启动 extractWorkers 个 worker 抽取 extractQueue 中的条目
+func ExtractTask() {
+	logger.Println("Starting article extraction, workers:", extractWorkers)
+	for i := 0; i < extractWorkers; i++ {
+		go extractWorker()
+	}
+}
+
+func extractWorker() {
+	for item := range extractQueue {
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					logger.Printf("Recovered from panic: %v", r)
+				}
+			}()
+			if _, err := extractAndSave(item); err != nil {
+				logger.Println("extractAndSave:", item.ID, err)
+			}
+		}()
+	}
+}
+
+// LoadArticle 返回已保存的正文，没有保存过或 refresh 为 true 时重新抽取；
+// 抽取失败时返回记录了失败状态的正文
+func LoadArticle(item store.PostItem, refresh bool) (*store.Article, error) {
+	article, err := st.GetArticle(item.ID)
+	if errors.Is(err, store.ErrNotFound) || refresh {
+		article, err = extractAndSave(item)
+		if article != nil {
+			return article, nil
+		}
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch article: %w", err)
+	}
+	return article, nil
+}
+
+func getArticle(c *gin.Context) {
+	itemID := c.Query("id")
+	item, err := st.GetPostItem(itemID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Item not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching item"})
+		}
+		return
+	}
+
+	article, err := LoadArticle(*item, c.Query("refresh") == "1")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"item":    item,
+		"article": article,
+	})
+}
@@ -0,0 +1,193 @@
+package web
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"shin/internal/store"
+)
+
+func TestExtractArticle(t *testing.T) {
+	base, _ := url.Parse("https://blog.example.com/posts/sqlite")
+	tests := []struct {
+		fixture   string
+		wantTitle string
+		wantText  string
+		wantHTML  []string
+		wantErr   bool
+	}{
+		{
+			fixture:   "blog.html",
+			wantTitle: "Why SQLite Is Enough",
+			wantText: "Why SQLite Is Enough\n\n" +
+				"Most personal tools never outgrow a single file database, and SQLite handles thousands of writes per second on modest hardware.\n\n" +
+				"WAL mode lets readers and the writer work at the same time, which is exactly what a small web app with a background job needs.\n\n" +
+				"Backups are a file copy, migrations are plain SQL, and there is no server to keep patched. Read more about backups.",
+			wantHTML: []string{
+				`<a href="https://blog.example.com/posts/backups" target="_blank" rel="noopener noreferrer">`,
+				`<img src="https://blog.example.com/img/chart.png" alt="Write throughput" loading="lazy">`,
+			},
+		},
+		{
+			fixture:   "news.html",
+			wantTitle: "城市更新计划公布",
+			wantText: "城市更新计划公布\n\n" +
+				"市政府今天公布了新一轮城市更新计划，涉及老旧小区改造、道路拓宽和公共绿地建设，预计三年内完成。\n\n" +
+				"负责人表示，计划将优先改造建成年代较早的住宅小区，同时增加社区养老和托育设施，方便居民生活。\n\n" +
+				"“我们希望通过这次更新，让老城区重新焕发活力，”一位居民代表说。",
+		},
+		{
+			fixture: "empty.html",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.fixture, func(t *testing.T) {
+			f, err := os.Open(filepath.Join("testdata", "extract", tt.fixture))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+
+			article, err := extractArticle(f, base)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("extractArticle() = %q, want error", article.Text)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("extractArticle() error = %v", err)
+			}
+			if article.Title != tt.wantTitle {
+				t.Errorf("Title = %q, want %q", article.Title, tt.wantTitle)
+			}
+			if article.Text != tt.wantText {
+				t.Errorf("Text = %q, want %q", article.Text, tt.wantText)
+			}
+			for _, want := range tt.wantHTML {
+				if !strings.Contains(article.HTML, want) {
+					t.Errorf("HTML does not contain %q:\n%s", want, article.HTML)
+				}
+			}
+			if strings.Contains(article.HTML, "<script") || strings.Contains(article.Text, "tracking") {
+				t.Errorf("script leaked into article: %q", article.HTML)
+			}
+		})
+	}
+}
+
+func TestFetchArticle(t *testing.T) {
+	fixture, err := os.ReadFile(filepath.Join("testdata", "extract", "blog.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/posts/sqlite", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write(fixture)
+	})
+	mux.HandleFunc("/old", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/posts/sqlite", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/feed.json", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{}`))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	useArticleClient(t, server.Client())
+
+	// 跳转后相对地址按最终地址解析
+	article, err := fetchArticle(server.URL + "/old")
+	if err != nil {
+		t.Fatalf("fetchArticle() error = %v", err)
+	}
+	if article.URL != server.URL+"/posts/sqlite" {
+		t.Errorf("URL = %q, want %q", article.URL, server.URL+"/posts/sqlite")
+	}
+	if want := `src="` + server.URL + `/img/chart.png"`; !strings.Contains(article.HTML, want) {
+		t.Errorf("HTML does not contain %q", want)
+	}
+
+	for _, path := range []string{"/feed.json", "/missing"} {
+		if _, err := fetchArticle(server.URL + path); err == nil {
+			t.Errorf("fetchArticle(%s) error = nil, want error", path)
+		}
+	}
+}
+
+// useArticleClient 替换 fetchArticle 使用的客户端，让测试可以访问本机的 httptest 服务
+func useArticleClient(t *testing.T, client *http.Client) {
+	t.Helper()
+	old := articleClient
+	articleClient = client
+	t.Cleanup(func() { articleClient = old })
+}
+
+func TestFetchArticleRejectsNonPublicAddress(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("request reached the server: %s", r.URL)
+	}))
+	defer server.Close()
+
+	for _, pageURL := range []string{
+		server.URL + "/post",
+		"http://localhost:1/post",
+		"http://10.0.0.1/post",
+		"http://192.168.1.1/post",
+		"http://169.254.169.254/latest/meta-data/",
+		"http://[::1]:1/post",
+		"http://[fd00::1]/post",
+		"http://0.0.0.0:1/post",
+	} {
+		if _, err := fetchArticle(pageURL); !errors.Is(err, errNonPublicAddress) {
+			t.Errorf("fetchArticle(%s) error = %v, want errNonPublicAddress", pageURL, err)
+		}
+	}
+	if _, err := fetchArticle("file:///etc/passwd"); err == nil {
+		t.Error("fetchArticle(file:///etc/passwd) error = nil")
+	}
+}
+
+func TestQueueExtraction(t *testing.T) {
+	useTestStore(t)
+	fixture, err := os.ReadFile(filepath.Join("testdata", "extract", "blog.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write(fixture)
+	}))
+	defer server.Close()
+	useArticleClient(t, server.Client())
+
+	old := extractQueue
+	extractQueue = make(chan store.PostItem, 1)
+	t.Cleanup(func() { extractQueue = old })
+
+	// 队列满时直接丢弃，不等待 worker
+	first := insertTestItem(t, "Feed", "First", server.URL+"/first")
+	second := insertTestItem(t, "Feed", "Second", server.URL+"/second")
+	queueExtraction([]store.PostItem{first, second})
+	if len(extractQueue) != 1 {
+		t.Fatalf("queued %d items, want 1", len(extractQueue))
+	}
+
+	close(extractQueue)
+	extractWorker()
+	if article, err := st.GetArticle(first.ID); err != nil || article.Status != "ok" || article.Title != "Why SQLite Is Enough" {
+		t.Errorf("GetArticle(first) = %+v, %v", article, err)
+	}
+	if _, err := st.GetArticle(second.ID); !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("GetArticle(second) error = %v, want ErrNotFound", err)
+	}
+}
@@ -0,0 +1,361 @@
+package web
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"html"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"shin/internal/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+const FEED_TOKENS_KEY = "feedTokens"
+
+var (
+	// BASE_URL 用于生成订阅中的链接，未设置时按请求的 Host 推断
+	baseURL        = strings.TrimSuffix(os.Getenv("BASE_URL"), "/")
+	feedTokensLock sync.Mutex
+)
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Author  atomAuthor  `xml:"author"`
+	Links   []atomLink  `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+type atomText struct {
+	Type string `xml:"type,attr,omitempty"`
+	Body string `xml:",chardata"`
+}
+
+type atomEntry struct {
+	ID         string         `xml:"id"`
+	Title      string         `xml:"title"`
+	Updated    string         `xml:"updated"`
+	Author     *atomAuthor    `xml:"author,omitempty"`
+	Links      []atomLink     `xml:"link"`
+	Summary    *atomText      `xml:"summary,omitempty"`
+	Content    *atomText      `xml:"content,omitempty"`
+	Categories []atomCategory `xml:"category"`
+}
+
+type atomCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+// 订阅的 key：digests、important、tag/<name>
+func feedKeyForTag(tag string) string {
+	return "tag/" + tag
+}
+
+func getFeedTokens() map[string]string {
+	tokens := make(map[string]string)
+	if value, err := st.GetKeyValue(FEED_TOKENS_KEY); err == nil && value != "" {
+		if err := json.Unmarshal([]byte(value), &tokens); err != nil {
+			logger.Println("Unmarshal feedTokens err:", err)
+		}
+	}
+	return tokens
+}
+
+func newFeedToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// feedToken 返回订阅的访问令牌，没有时生成一个；rotate 为 true 时重新生成
+func feedToken(feed string, rotate bool) (string, error) {
+	feedTokensLock.Lock()
+	defer feedTokensLock.Unlock()
+
+	tokens := getFeedTokens()
+	if token, ok := tokens[feed]; ok && !rotate {
+		return token, nil
+	}
+
+	token, err := newFeedToken()
+	if err != nil {
+		return "", fmt.Errorf("failed to generate token: %w", err)
+	}
+	tokens[feed] = token
+	valueJSON, _ := json.Marshal(tokens)
+	if err := st.SetKeyValue(FEED_TOKENS_KEY, string(valueJSON)); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// checkFeedToken 订阅不走 cookie 鉴权，而是校验 URL 中的 token
+func checkFeedToken(c *gin.Context, feed string) bool {
+	expected, ok := getFeedTokens()[feed]
+	token := c.Query("token")
+	if !ok || token == "" || subtle.ConstantTimeCompare([]byte(token), []byte(expected)) != 1 {
+		c.String(http.StatusUnauthorized, "invalid token")
+		return false
+	}
+	return true
+}
+
+func requestBaseURL(c *gin.Context) string {
+	if baseURL != "" {
+		return baseURL
+	}
+	scheme := "http"
+	if c.Request.TLS != nil {
+		scheme = "https"
+	}
+	if proto := c.GetHeader("X-Forwarded-Proto"); proto != "" {
+		scheme = proto
+	}
+	return scheme + "://" + c.Request.Host
+}
+
+func feedPath(feed string) string {
+	return "/feeds/" + feed + ".atom"
+}
+
+func atomTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+// itemTitleHTML 译文标题加原标题链接
+func itemTitleHTML(content store.PostItemContent) string {
+	return fmt.Sprintf(`%s <a href="%s">%s</a>`, html.EscapeString(content.CnTitle), html.EscapeString(content.Link), html.EscapeString(content.Title))
+}
+
+func itemEntry(base string, item store.PostItem) atomEntry {
+	var content store.PostItemContent
+	json.Unmarshal([]byte(item.Content), &content)
+
+	title := content.CnTitle
+	if content.Title != "" && content.Title != content.CnTitle {
+		title += " / " + content.Title
+	}
+	entry := atomEntry{
+		ID:      "urn:shin:item:" + item.ID,
+		Title:   title,
+		Updated: atomTime(time.Unix(item.CreatedAt, 0)),
+		Author:  &atomAuthor{Name: item.FeedTitle},
+		Links: []atomLink{
+			{Href: content.Link, Rel: "alternate"},
+			{Href: base + "/reader?id=" + item.ID, Rel: "related"},
+		},
+		Content: &atomText{Type: "html", Body: "<p>" + itemTitleHTML(content) + "</p>"},
+	}
+	for _, tag := range item.Tags {
+		entry.Categories = append(entry.Categories, atomCategory{Term: tag})
+	}
+	return entry
+}
+
+// digestEntry 一期摘要作为一个条目，内容按订阅分组列出所有条目
+func digestEntry(base string, post store.Post) (atomEntry, error) {
+	grouped, err := getPostItemsGroupedByFeedTitle(post.ID)
+	if err != nil {
+		return atomEntry{}, err
+	}
+	feedTitles := make([]string, 0, len(grouped))
+	for feedTitle := range grouped {
+		feedTitles = append(feedTitles, feedTitle)
+	}
+	sort.Strings(feedTitles)
+
+	var sb strings.Builder
+	if post.Summary != "" {
+		sb.WriteString("<p>" + html.EscapeString(post.Summary) + "</p>")
+	}
+	for _, feedTitle := range feedTitles {
+		sb.WriteString("<h3>" + html.EscapeString(feedTitle) + "</h3><ul>")
+		for _, item := range grouped[feedTitle] {
+			var content store.PostItemContent
+			json.Unmarshal([]byte(item.Content), &content)
+			sb.WriteString("<li>" + itemTitleHTML(content) + "</li>")
+		}
+		sb.WriteString("</ul>")
+	}
+
+	entry := atomEntry{
+		ID:      "urn:shin:post:" + post.ID,
+		Title:   post.Title,
+		Updated: atomTime(time.Unix(post.CreatedAt, 0)),
+		Links:   []atomLink{{Href: base + "/detail?id=" + post.ID, Rel: "alternate"}},
+		Content: &atomText{Type: "html", Body: sb.String()},
+	}
+	if post.Summary != "" {
+		entry.Summary = &atomText{Body: post.Summary}
+	}
+	for _, tag := range post.Tags {
+		entry.Categories = append(entry.Categories, atomCategory{Term: tag})
+	}
+	return entry, nil
+}
+
+func writeAtom(c *gin.Context, feed, title string, entries []atomEntry) {
+	base := requestBaseURL(c)
+	updated := time.Unix(0, 0)
+	for _, entry := range entries {
+		if t, err := time.Parse(time.RFC3339, entry.Updated); err == nil && t.After(updated) {
+			updated = t
+		}
+	}
+
+	doc := atomFeed{
+		ID:      "urn:shin:feed:" + feed,
+		Title:   title,
+		Updated: atomTime(updated),
+		Author:  atomAuthor{Name: "Shin"},
+		Links: []atomLink{
+			{Href: base + feedPath(feed), Rel: "self"},
+			{Href: base + "/home", Rel: "alternate"},
+		},
+		Entries: entries,
+	}
+	output, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		c.String(http.StatusInternalServerError, err.Error())
+		return
+	}
+	c.Data(http.StatusOK, "application/atom+xml; charset=utf-8", append([]byte(xml.Header), output...))
+}
+
+func digestsFeed(c *gin.Context) {
+	if !checkFeedToken(c, "digests") {
+		return
+	}
+
+	posts, _, err := st.ListPosts(store.Page{Number: 1, Size: 20})
+	if err != nil {
+		c.String(http.StatusInternalServerError, "Error fetching posts")
+		return
+	}
+
+	base := requestBaseURL(c)
+	entries := []atomEntry{}
+	for _, post := range posts {
+		entry, err := digestEntry(base, post)
+		if err != nil {
+			c.String(http.StatusInternalServerError, err.Error())
+			return
+		}
+		entries = append(entries, entry)
+	}
+	writeAtom(c, "digests", "Shin digests", entries)
+}
+
+func writeItemsFeed(c *gin.Context, feed, title string, items []store.PostItem, err error) {
+	if err != nil {
+		c.String(http.StatusInternalServerError, "Failed to execute query")
+		logger.Println("feed", feed, err)
+		return
+	}
+	base := requestBaseURL(c)
+	entries := []atomEntry{}
+	for _, item := range items {
+		entries = append(entries, itemEntry(base, item))
+	}
+	writeAtom(c, feed, title, entries)
+}
+
+func importantFeed(c *gin.Context) {
+	if !checkFeedToken(c, "important") {
+		return
+	}
+	items, _, err := queryImportantItems(store.Page{Number: 1, Size: 50})
+	writeItemsFeed(c, "important", "Shin important", items, err)
+}
+
+// tagFeed 处理 /feeds/tag/*name，标签名可以带 /，所以用通配参数，自己去掉开头的 / 和结尾的 .atom
+func tagFeed(c *gin.Context) {
+	name, ok := strings.CutSuffix(strings.TrimPrefix(c.Param("name"), "/"), ".atom")
+	if !ok || name == "" {
+		c.String(http.StatusNotFound, "not found")
+		return
+	}
+	feed := feedKeyForTag(name)
+	if !checkFeedToken(c, feed) {
+		return
+	}
+	items, _, err := queryTaggedItems(name, store.Page{Number: 1, Size: 50})
+	writeItemsFeed(c, feed, "Shin #"+name, items, err)
+}
+
+type feedURL struct {
+	Feed string `json:"feed"`
+	URL  string `json:"url"`
+}
+
+// getFeedURLs 返回所有可订阅的地址（含 token），需要登录
+func getFeedURLs(c *gin.Context) {
+	feeds := []string{"digests", "important"}
+	tags, err := st.TagCounts()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to execute query"})
+		return
+	}
+	for _, tag := range tags {
+		feeds = append(feeds, feedKeyForTag(tag.Name))
+	}
+
+	base := requestBaseURL(c)
+	urls := []feedURL{}
+	for _, feed := range feeds {
+		token, err := feedToken(feed, false)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		urls = append(urls, feedURL{Feed: feed, URL: base + feedPath(feed) + "?token=" + token})
+	}
+
+	c.JSON(http.StatusOK, urls)
+}
+
+func rotateFeedToken(c *gin.Context) {
+	var input struct {
+		Feed string `json:"feed"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if input.Feed != "digests" && input.Feed != "important" && !strings.HasPrefix(input.Feed, "tag/") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "unknown feed"})
+		return
+	}
+
+	token, err := feedToken(input.Feed, true)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, feedURL{Feed: input.Feed, URL: requestBaseURL(c) + feedPath(input.Feed) + "?token=" + token})
+}
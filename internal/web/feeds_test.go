@@ -0,0 +1,90 @@
+package web
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"shin/internal/store"
+)
+
+func TestTagFeedWithSlash(t *testing.T) {
+	useTestStore(t)
+	item := insertTestItem(t, "Feed", "Nested tag item", "https://example.com/nested")
+	if err := st.AddItemTag(item.ID, "lang/go"); err != nil {
+		t.Fatal(err)
+	}
+	token, err := feedToken(feedKeyForTag("lang/go"), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/feeds/tag/*name", tagFeed)
+
+	tests := []struct {
+		path     string
+		token    string
+		wantCode int
+	}{
+		{"/feeds/tag/lang/go.atom", token, http.StatusOK},
+		{"/feeds/tag/lang%2Fgo.atom", token, http.StatusOK},
+		{"/feeds/tag/lang/go.atom", "wrong", http.StatusUnauthorized},
+		{"/feeds/tag/lang/go", token, http.StatusNotFound},
+		{"/feeds/tag/.atom", token, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", tt.path+"?token="+url.QueryEscape(tt.token), nil))
+		if w.Code != tt.wantCode {
+			t.Errorf("GET %s = %d, want %d", tt.path, w.Code, tt.wantCode)
+			continue
+		}
+		if tt.wantCode == http.StatusOK && !strings.Contains(w.Body.String(), "Nested tag item") {
+			t.Errorf("GET %s body does not contain the tagged item:\n%s", tt.path, w.Body.String())
+		}
+	}
+}
+
+func TestItemFeedUpdated(t *testing.T) {
+	useTestStore(t)
+	created := time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC)
+	content, _ := json.Marshal(store.PostItemContent{CnTitle: "Dated", Title: "Dated", Link: "https://example.com/dated"})
+	item := store.PostItem{ID: store.NewID(), FeedTitle: "Feed", Content: string(content), CreatedAt: created.Unix()}
+	if err := st.InsertPostItems([]store.PostItem{item}); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.AddItemTag(item.ID, "dated"); err != nil {
+		t.Fatal(err)
+	}
+	token, err := feedToken(feedKeyForTag("dated"), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/feeds/tag/*name", tagFeed)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/feeds/tag/dated.atom?token="+url.QueryEscape(token), nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET tag feed = %d\n%s", w.Code, w.Body.String())
+	}
+
+	var doc atomFeed
+	if err := xml.Unmarshal(w.Body.Bytes(), &doc); err != nil {
+		t.Fatal(err)
+	}
+	want := "2024-05-06T07:08:09Z"
+	if len(doc.Entries) != 1 || doc.Entries[0].Updated != want || doc.Updated != want {
+		t.Errorf("feed updated %q, entries %+v, want %s", doc.Updated, doc.Entries, want)
+	}
+}
@@ -0,0 +1,101 @@
+package web
+
+import (
+	"net/http"
+	"os"
+
+	"shin/internal/ingest"
+
+	"github.com/gin-gonic/gin"
+)
+
+// FRESHRSS_SYNC=true 时把已读和星标同步回 FreshRSS
+var freshrssSyncEnabled = os.Getenv("FRESHRSS_SYNC") == "true"
+
+// syncUpstream 在后台把状态同步到 FreshRSS，失败只记录日志，本地状态以 Shin 为准。
+// 星标推送成功后记录为已同步，失败的在下一轮拉取状态前重试
+func syncUpstream(upstreamIDs []string, add, remove string) {
+	if !freshrssSyncEnabled || freshrss == nil || len(upstreamIDs) == 0 {
+		return
+	}
+	go func() {
+		var err error
+		if add == ingest.StateStarred || remove == ingest.StateStarred {
+			err = freshrss.SetStarred(st, freshrss.Auth(), upstreamIDs, add == ingest.StateStarred)
+		} else {
+			err = freshrss.EditTag(freshrss.Auth(), upstreamIDs, add, remove)
+		}
+		if err != nil {
+			logger.Println("syncUpstream:", err)
+			return
+		}
+		logger.Printf("syncUpstream: %d items a=%s r=%s", len(upstreamIDs), add, remove)
+	}()
+}
+
+func stateChange(state string, on bool) (add, remove string) {
+	if on {
+		return state, ""
+	}
+	return "", state
+}
+
+// setItemState 更新条目的已读或星标，state 是 ingest.StateRead 或 ingest.StateStarred
+func setItemState(itemID, state string, on bool) error {
+	set := st.SetItemRead
+	if state == ingest.StateStarred {
+		set = st.SetItemStarred
+	}
+	upstreamID, err := set(itemID, on)
+	if err != nil {
+		return err
+	}
+	if upstreamID != "" {
+		add, remove := stateChange(state, on)
+		syncUpstream([]string{upstreamID}, add, remove)
+	}
+	return nil
+}
+
+func markItemRead(c *gin.Context) {
+	var input struct {
+		ItemID string `json:"item_id"`
+		Read   *bool  `json:"read"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	read := input.Read == nil || *input.Read
+
+	if err := setItemState(input.ItemID, ingest.StateRead, read); err != nil {
+		c.JSON(rowErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Item updated"})
+}
+
+func starItem(c *gin.Context) {
+	var input struct {
+		ItemID  string `json:"item_id"`
+		Starred bool   `json:"starred"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := setItemState(input.ItemID, ingest.StateStarred, input.Starred); err != nil {
+		c.JSON(rowErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	message := "Item unstarred"
+	if input.Starred {
+		message = "Item starred"
+	}
+	c.JSON(http.StatusOK, gin.H{"message": message})
+}
@@ -0,0 +1,197 @@
+package web
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+
+	"shin/internal/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	llmAPIURL       = strings.TrimSuffix(os.Getenv("LLM_API_URL"), "/") // OpenAI 兼容接口，如 https://api.openai.com/v1
+	llmAPIKey       = os.Getenv("LLM_API_KEY")
+	llmModel        = os.Getenv("LLM_MODEL")
+	llmSystemPrompt = os.Getenv("LLM_PROMPT")
+	llmMaxItems     = 300
+)
+
+const defaultLLMSystemPrompt = `你是一名新闻编辑。下面是一期 RSS 摘要中按订阅分组的条目标题。
+请用中文写一段不超过 150 字的摘要，概括这一期最重要的内容，并给出 3 到 5 个简短的主题标签。
+只返回 JSON，格式为：{"summary": "...", "tags": ["...", "..."]}`
+
+type chatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type chatCompletionRequest struct {
+	Model       string        `json:"model"`
+	Messages    []chatMessage `json:"messages"`
+	Temperature float64       `json:"temperature"`
+}
+
+type chatCompletionResponse struct {
+	Choices []struct {
+		Message chatMessage `json:"message"`
+	} `json:"choices"`
+}
+
+type DigestSummary struct {
+	Summary string   `json:"summary"`
+	Tags    []string `json:"tags"`
+}
+
+// buildDigestPrompt 按订阅标题排序后列出条目，与 getPostItemsGroupedByFeedTitle 的分组一致
+func buildDigestPrompt(grouped map[string][]store.PostItem) string {
+	feedTitles := make([]string, 0, len(grouped))
+	for feedTitle := range grouped {
+		feedTitles = append(feedTitles, feedTitle)
+	}
+	sort.Strings(feedTitles)
+
+	var sb strings.Builder
+	count := 0
+	for _, feedTitle := range feedTitles {
+		fmt.Fprintf(&sb, "## %s\n", feedTitle)
+		for _, item := range grouped[feedTitle] {
+			if count >= llmMaxItems {
+				break
+			}
+			var content store.PostItemContent
+			json.Unmarshal([]byte(item.Content), &content)
+			if content.CnTitle != "" && content.CnTitle != content.Title {
+				fmt.Fprintf(&sb, "- %s (%s)\n", content.Title, content.CnTitle)
+			} else {
+				fmt.Fprintf(&sb, "- %s\n", content.Title)
+			}
+			count++
+		}
+	}
+	return sb.String()
+}
+
+// parseDigestSummary 兼容模型在 JSON 前后输出多余文字或代码块的情况
+func parseDigestSummary(text string) (*DigestSummary, error) {
+	start := strings.Index(text, "{")
+	end := strings.LastIndex(text, "}")
+	if start < 0 || end < start {
+		return nil, fmt.Errorf("no JSON object in response: %s", text)
+	}
+
+	var summary DigestSummary
+	if err := json.Unmarshal([]byte(text[start:end+1]), &summary); err != nil {
+		return nil, fmt.Errorf("failed to parse summary: %w", err)
+	}
+	summary.Summary = strings.TrimSpace(summary.Summary)
+	var tags []string
+	for _, tag := range summary.Tags {
+		if tag = strings.TrimSpace(strings.TrimPrefix(tag, "#")); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	summary.Tags = tags
+	return &summary, nil
+}
+
+func requestDigestSummary(prompt string) (*DigestSummary, error) {
+	model := llmModel
+	if model == "" {
+		model = "gpt-4o-mini"
+	}
+	systemPrompt := llmSystemPrompt
+	if systemPrompt == "" {
+		systemPrompt = defaultLLMSystemPrompt
+	}
+
+	reqData, err := json.Marshal(chatCompletionRequest{
+		Model: model,
+		Messages: []chatMessage{
+			{Role: "system", Content: systemPrompt},
+			{Role: "user", Content: prompt},
+		},
+		Temperature: 0.3,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode request: %w", err)
+	}
+
+	req, err := http.NewRequest("POST", llmAPIURL+"/chat/completions", bytes.NewBuffer(reqData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if llmAPIKey != "" {
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", llmAPIKey))
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("non-200 response: %d %s", resp.StatusCode, string(body))
+	}
+
+	var completion chatCompletionResponse
+	if err := json.Unmarshal(body, &completion); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+	if len(completion.Choices) == 0 {
+		return nil, fmt.Errorf("empty choices in response")
+	}
+	return parseDigestSummary(completion.Choices[0].Message.Content)
+}
+
+// summarizePost 为一期摘要生成总结和主题标签并写回 shin_post
+func summarizePost(postID string) error {
+	grouped, err := getPostItemsGroupedByFeedTitle(postID)
+	if err != nil {
+		return err
+	}
+	if len(grouped) == 0 {
+		return fmt.Errorf("post %s has no items", postID)
+	}
+
+	summary, err := requestDigestSummary(buildDigestPrompt(grouped))
+	if err != nil {
+		return err
+	}
+	logger.Printf("summarizePost: %s %s %v", postID, summary.Summary, summary.Tags)
+	return st.UpdatePostSummary(postID, summary.Summary, summary.Tags)
+}
+
+func resummarizePost(c *gin.Context) {
+	var input struct {
+		PostID string `json:"post_id"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if llmAPIURL == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "LLM_API_URL is not configured"})
+		return
+	}
+
+	if err := summarizePost(input.PostID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Post summarized"})
+}
@@ -0,0 +1,118 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// chatCompletionStub 是 OpenAI 兼容的 /chat/completions，按 status 和 body 原样返回
+func chatCompletionStub(t *testing.T, status int, body string) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/v1/chat/completions" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer test-key" {
+			t.Errorf("Authorization = %q", got)
+		}
+		var req chatCompletionRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("invalid request body: %v", err)
+		}
+		if req.Model != "test-model" || len(req.Messages) != 2 || req.Messages[1].Content != "## Feed\n- Title\n" {
+			t.Errorf("unexpected request: %+v", req)
+		}
+		w.WriteHeader(status)
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+
+	oldURL, oldKey, oldModel := llmAPIURL, llmAPIKey, llmModel
+	llmAPIURL, llmAPIKey, llmModel = server.URL+"/v1", "test-key", "test-model"
+	t.Cleanup(func() { llmAPIURL, llmAPIKey, llmModel = oldURL, oldKey, oldModel })
+	return server
+}
+
+func completionBody(content string) string {
+	data, _ := json.Marshal(map[string]interface{}{
+		"choices": []interface{}{map[string]interface{}{
+			"message": map[string]string{"role": "assistant", "content": content},
+		}},
+	})
+	return string(data)
+}
+
+func TestRequestDigestSummary(t *testing.T) {
+	tests := []struct {
+		name        string
+		status      int
+		body        string
+		wantSummary string
+		wantTags    []string
+		wantErr     string
+	}{
+		{
+			name:        "ok",
+			status:      http.StatusOK,
+			body:        completionBody("```json\n{\"summary\": \" 本期要点 \", \"tags\": [\"#AI\", \" 数据库 \", \"\"]}\n```"),
+			wantSummary: "本期要点",
+			wantTags:    []string{"AI", "数据库"},
+		},
+		{
+			name:    "malformed response",
+			status:  http.StatusOK,
+			body:    `{"choices": [`,
+			wantErr: "failed to parse response",
+		},
+		{
+			name:    "malformed summary",
+			status:  http.StatusOK,
+			body:    completionBody(`{"summary": "unterminated}`),
+			wantErr: "failed to parse summary",
+		},
+		{
+			name:    "no JSON in summary",
+			status:  http.StatusOK,
+			body:    completionBody("抱歉，我无法完成。"),
+			wantErr: "no JSON object",
+		},
+		{
+			name:    "empty choices",
+			status:  http.StatusOK,
+			body:    `{"choices": []}`,
+			wantErr: "empty choices",
+		},
+		{
+			name:    "non-200",
+			status:  http.StatusTooManyRequests,
+			body:    `{"error": {"message": "rate limited"}}`,
+			wantErr: "non-200 response: 429",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chatCompletionStub(t, tt.status, tt.body)
+
+			summary, err := requestDigestSummary("## Feed\n- Title\n")
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("requestDigestSummary() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("requestDigestSummary() error = %v", err)
+			}
+			if summary.Summary != tt.wantSummary {
+				t.Errorf("Summary = %q, want %q", summary.Summary, tt.wantSummary)
+			}
+			if strings.Join(summary.Tags, ",") != strings.Join(tt.wantTags, ",") {
+				t.Errorf("Tags = %q, want %q", summary.Tags, tt.wantTags)
+			}
+		})
+	}
+}
@@ -0,0 +1,133 @@
+package web
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"shin/internal/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ClientMemoRequest struct {
+	PostItemID string `json:"postItemID"`
+	// 可选，把这条笔记一起写入 memo
+	NoteID string `json:"noteID"`
+}
+
+func CreateMemo(c *gin.Context) {
+	var input ClientMemoRequest
+
+	// 从请求体中获取 postItemID
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if input.NoteID != "" {
+		promoteNote(c, input)
+		return
+	}
+
+	// 写入 outbox，由后台任务创建 memo
+	memoID, queued, err := EnqueueSave(input.PostItemID, "memos", "")
+	if err != nil {
+		c.JSON(saveErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	if !queued {
+		c.JSON(http.StatusOK, gin.H{"message": "Memo already exists", "memo_id": memoID})
+		return
+	}
+
+	// 返回成功消息
+	c.JSON(http.StatusAccepted, gin.H{"message": "Memo queued"})
+}
+
+type PromoteResult struct {
+	Appended  bool   `json:"appended"` // 作为评论追加到已有的 memo
+	CommentID string `json:"comment_id,omitempty"`
+	Queued    bool   `json:"queued"` // 随条目一起排队创建 memo
+}
+
+// PromoteNote 把笔记写入 memo：已有 memo 时作为评论追加，否则随条目一起排队创建 memo
+func PromoteNote(note *store.Note) (PromoteResult, error) {
+	if _, err := st.GetSavedID(note.ItemID, "memos"); err == nil {
+		commentID, err := AppendToSaved(note.ItemID, "memos", note.Body)
+		return PromoteResult{Appended: err == nil, CommentID: commentID}, err
+	} else if !errors.Is(err, store.ErrNotSaved) {
+		return PromoteResult{}, err
+	}
+
+	_, queued, err := EnqueueSave(note.ItemID, "memos", note.ID)
+	return PromoteResult{Queued: queued}, err
+}
+
+func promoteNote(c *gin.Context, input ClientMemoRequest) {
+	note, err := st.GetNote(input.NoteID)
+	if err != nil {
+		c.JSON(rowErrorStatus(err), gin.H{"error": "Note not found"})
+		return
+	}
+	if input.PostItemID != "" && note.ItemID != input.PostItemID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Note does not belong to item"})
+		return
+	}
+
+	result, err := PromoteNote(note)
+	if err != nil {
+		c.JSON(saveErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	if result.Appended {
+		c.JSON(http.StatusOK, gin.H{"message": "Note appended to memo", "comment_id": result.CommentID})
+		return
+	}
+	if !result.Queued {
+		c.JSON(http.StatusOK, gin.H{"message": "Memo already exists"})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"message": "Memo queued"})
+}
+
+func UpdateMemo(c *gin.Context) {
+	var input struct {
+		PostItemID string `json:"postItemID"`
+		Comment    string `json:"comment"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if strings.TrimSpace(input.Comment) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "comment is required"})
+		return
+	}
+
+	commentID, err := AppendToSaved(input.PostItemID, "memos", input.Comment)
+	if err != nil {
+		c.JSON(saveErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Memo updated", "comment_id": commentID})
+}
+
+func DeleteMemo(c *gin.Context) {
+	var input ClientMemoRequest
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := UnsaveItem(input.PostItemID, "memos"); err != nil {
+		c.JSON(saveErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Memo deleted"})
+}
@@ -0,0 +1,119 @@
+package web
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"shin/internal/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+// attachNotes 给条目填充 Notes 字段
+func attachNotes(items []store.PostItem) error {
+	itemIDs := make([]string, len(items))
+	for i, item := range items {
+		itemIDs[i] = item.ID
+	}
+	notes, err := st.NotesByItemIDs(itemIDs)
+	if err != nil {
+		return err
+	}
+	for i := range items {
+		items[i].Notes = notes[items[i].ID]
+	}
+	return nil
+}
+
+// rowErrorStatus 记录不存在时返回 404
+func rowErrorStatus(err error) int {
+	if errors.Is(err, store.ErrNotFound) {
+		return http.StatusNotFound
+	}
+	return http.StatusInternalServerError
+}
+
+func getNotes(c *gin.Context) {
+	notes, err := st.NotesByItemIDs([]string{c.Query("item_id")})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	result := notes[c.Query("item_id")]
+	if result == nil {
+		result = []store.Note{}
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+func createNote(c *gin.Context) {
+	var input struct {
+		ItemID string `json:"item_id"`
+		Body   string `json:"body"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if strings.TrimSpace(input.Body) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "body is required"})
+		return
+	}
+	if _, err := st.GetPostItem(input.ItemID); err != nil {
+		c.JSON(rowErrorStatus(err), gin.H{"error": "Item not found"})
+		return
+	}
+
+	note, err := st.InsertNote(input.ItemID, input.Body)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, note)
+}
+
+func updateNote(c *gin.Context) {
+	var input struct {
+		ID   string `json:"id"`
+		Body string `json:"body"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if strings.TrimSpace(input.Body) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "body is required"})
+		return
+	}
+
+	note, err := st.UpdateNote(input.ID, input.Body)
+	if err != nil {
+		c.JSON(rowErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, note)
+}
+
+func deleteNote(c *gin.Context) {
+	var input struct {
+		ID string `json:"id"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := st.DeleteNote(input.ID); err != nil {
+		c.JSON(rowErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Note deleted"})
+}
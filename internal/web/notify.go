@@ -0,0 +1,484 @@
+package web
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"shin/internal/store"
+)
+
+// Notifier 是重要条目的推送目标，一次推送一批条目
+type Notifier interface {
+	Name() string
+	Notify(batch NotifyBatch) error
+}
+
+const (
+	defaultNotifyTitle    = "Shin: {{.Count}} important item{{if gt .Count 1}}s{{end}}"
+	defaultNotifyTemplate = "{{range .Items}}{{.CnTitle}}\n{{.Title}}\n{{.Link}}\n\n{{end}}"
+	telegramMaxLength     = 4096
+	notifyMaxPending      = 200 // 每个推送目标最多积压的条目数，超出时丢弃最早的
+)
+
+var (
+	notifyTags        = splitEnvList(os.Getenv("NOTIFY_TAGS"))
+	notifyQuietHours  = os.Getenv("NOTIFY_QUIET_HOURS") // 例如 "23:00-07:00"，按 TIMEZONE 计算
+	notifyBatchWindow = parseDurationEnv("NOTIFY_BATCH_WINDOW", time.Minute)
+	notifiers         = loadNotifiers()
+
+	// 待推送的批次只在内存中，重启时丢失，推送是尽力而为的
+	notifyLock   sync.Mutex
+	notifyQueues = make(map[string]*notifyQueue)
+)
+
+// notifyQueue 是一个推送目标待推送的条目。推送失败的批次放回队首，按 retryDelay 退避后重试，
+// 各目标分开排队，一个目标失败不会让其他目标重复收到
+type notifyQueue struct {
+	items    []NotifyItem
+	since    time.Time // 第一条的入队时间
+	attempts int       // 连续失败次数
+	retryAt  time.Time
+}
+
+// push 把条目放回队首或追加到队尾，超出 notifyMaxPending 时丢弃最早的
+func (q *notifyQueue) push(items []NotifyItem, since time.Time, front bool) {
+	if len(q.items) == 0 || since.Before(q.since) {
+		q.since = since
+	}
+	if front {
+		q.items = append(append([]NotifyItem{}, items...), q.items...)
+	} else {
+		q.items = append(q.items, items...)
+	}
+	if over := len(q.items) - notifyMaxPending; over > 0 {
+		logger.Printf("Notify queue full, dropped %d items", over)
+		q.items = q.items[over:]
+	}
+}
+
+// NotifyItem 是通知模板中单个条目可以使用的字段
+type NotifyItem struct {
+	ID        string
+	FeedTitle string
+	CnTitle   string
+	Title     string
+	Link      string
+	Tags      []string
+}
+
+// NotifyBatch 是通知模板可以使用的字段
+type NotifyBatch struct {
+	Count int
+	Items []NotifyItem
+}
+
+func splitEnvList(value string) []string {
+	var list []string
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			list = append(list, v)
+		}
+	}
+	return list
+}
+
+func parseDurationEnv(key string, fallback time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		logger.Printf("Invalid %s %q, fallback to %s", key, value, fallback)
+		return fallback
+	}
+	return d
+}
+
+// notifyTemplates 每个推送目标可以用 NOTIFY_<NAME>_TITLE / NOTIFY_<NAME>_TEMPLATE 单独设置模板
+type notifyTemplates struct {
+	title *template.Template
+	body  *template.Template
+}
+
+func parseNotifyTemplate(name, env, fallback string) *template.Template {
+	text := os.Getenv(env)
+	if text == "" {
+		text = fallback
+	}
+	tmpl, err := template.New(name).Funcs(template.FuncMap{"join": strings.Join}).Parse(text)
+	if err != nil {
+		logger.Printf("Invalid %s, fallback to default: %v", env, err)
+		return template.Must(template.New(name).Funcs(template.FuncMap{"join": strings.Join}).Parse(fallback))
+	}
+	return tmpl
+}
+
+func loadNotifyTemplates(name string) notifyTemplates {
+	prefix := "NOTIFY_" + strings.ToUpper(name)
+	return notifyTemplates{
+		title: parseNotifyTemplate(name+"-title", prefix+"_TITLE", defaultNotifyTitle),
+		body:  parseNotifyTemplate(name, prefix+"_TEMPLATE", defaultNotifyTemplate),
+	}
+}
+
+func (t notifyTemplates) render(batch NotifyBatch) (string, string, error) {
+	var title, body strings.Builder
+	if err := t.title.Execute(&title, batch); err != nil {
+		return "", "", fmt.Errorf("failed to render title: %w", err)
+	}
+	if err := t.body.Execute(&body, batch); err != nil {
+		return "", "", fmt.Errorf("failed to render message: %w", err)
+	}
+	return strings.TrimSpace(title.String()), strings.TrimSpace(body.String()), nil
+}
+
+// loadNotifiers 按环境变量启用推送目标
+func loadNotifiers() []Notifier {
+	var list []Notifier
+	if token := os.Getenv("NOTIFY_TELEGRAM_TOKEN"); token != "" {
+		apiURL := os.Getenv("NOTIFY_TELEGRAM_API")
+		if apiURL == "" {
+			apiURL = "https://api.telegram.org"
+		}
+		list = append(list, telegramNotifier{
+			apiURL:    strings.TrimSuffix(apiURL, "/"),
+			token:     token,
+			chatID:    os.Getenv("NOTIFY_TELEGRAM_CHAT_ID"),
+			templates: loadNotifyTemplates("telegram"),
+		})
+	}
+	if topicURL := os.Getenv("NOTIFY_NTFY_URL"); topicURL != "" {
+		list = append(list, ntfyNotifier{
+			topicURL:  topicURL,
+			token:     os.Getenv("NOTIFY_NTFY_TOKEN"),
+			templates: loadNotifyTemplates("ntfy"),
+		})
+	}
+	if baseURL := os.Getenv("NOTIFY_GOTIFY_URL"); baseURL != "" {
+		list = append(list, gotifyNotifier{
+			baseURL:   strings.TrimSuffix(baseURL, "/"),
+			token:     os.Getenv("NOTIFY_GOTIFY_TOKEN"),
+			templates: loadNotifyTemplates("gotify"),
+		})
+	}
+	if webhookURL := os.Getenv("NOTIFY_WEBHOOK_URL"); webhookURL != "" {
+		list = append(list, webhookNotifier{
+			url:       webhookURL,
+			secret:    os.Getenv("NOTIFY_WEBHOOK_SECRET"),
+			templates: loadNotifyTemplates("webhook"),
+		})
+	}
+	return list
+}
+
+// telegramNotifier 使用 Telegram Bot API 的 sendMessage
+type telegramNotifier struct {
+	apiURL    string
+	token     string
+	chatID    string
+	templates notifyTemplates
+}
+
+func (n telegramNotifier) Name() string {
+	return "telegram"
+}
+
+func (n telegramNotifier) Notify(batch NotifyBatch) error {
+	title, body, err := n.templates.render(batch)
+	if err != nil {
+		return err
+	}
+	text := title + "\n\n" + body
+	if runes := []rune(text); len(runes) > telegramMaxLength {
+		text = string(runes[:telegramMaxLength-1]) + "…"
+	}
+
+	reqData, _ := json.Marshal(map[string]interface{}{
+		"chat_id":                  n.chatID,
+		"text":                     text,
+		"disable_web_page_preview": true,
+	})
+	req, err := http.NewRequest("POST", n.apiURL+"/bot"+n.token+"/sendMessage", bytes.NewBuffer(reqData))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if err := sendJSON(req, nil); err != nil {
+		return fmt.Errorf("telegram: %w", err)
+	}
+	return nil
+}
+
+// ntfyNotifier 把消息发布到 ntfy 的 topic 地址
+type ntfyNotifier struct {
+	topicURL  string
+	token     string
+	templates notifyTemplates
+}
+
+func (n ntfyNotifier) Name() string {
+	return "ntfy"
+}
+
+func (n ntfyNotifier) Notify(batch NotifyBatch) error {
+	title, body, err := n.templates.render(batch)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest("POST", n.topicURL, strings.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Title", mime.QEncoding.Encode("UTF-8", title))
+	if len(batch.Items) == 1 {
+		req.Header.Set("Click", batch.Items[0].Link)
+	}
+	if n.token != "" {
+		req.Header.Set("Authorization", "Bearer "+n.token)
+	}
+	if err := sendJSON(req, nil); err != nil {
+		return fmt.Errorf("ntfy: %w", err)
+	}
+	return nil
+}
+
+// gotifyNotifier 使用 Gotify 的应用 token 发送消息
+type gotifyNotifier struct {
+	baseURL   string
+	token     string
+	templates notifyTemplates
+}
+
+func (n gotifyNotifier) Name() string {
+	return "gotify"
+}
+
+func (n gotifyNotifier) Notify(batch NotifyBatch) error {
+	title, body, err := n.templates.render(batch)
+	if err != nil {
+		return err
+	}
+
+	reqData, _ := json.Marshal(map[string]interface{}{
+		"title":    title,
+		"message":  body,
+		"priority": 5,
+	})
+	req, err := http.NewRequest("POST", n.baseURL+"/message?token="+url.QueryEscape(n.token), bytes.NewBuffer(reqData))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if err := sendJSON(req, nil); err != nil {
+		return fmt.Errorf("gotify: %w", err)
+	}
+	return nil
+}
+
+// webhookNotifier 把渲染后的消息和条目列表以 JSON POST 到任意地址
+type webhookNotifier struct {
+	url       string
+	secret    string
+	templates notifyTemplates
+}
+
+func (n webhookNotifier) Name() string {
+	return "webhook"
+}
+
+func (n webhookNotifier) Notify(batch NotifyBatch) error {
+	title, body, err := n.templates.render(batch)
+	if err != nil {
+		return err
+	}
+
+	items := make([]map[string]interface{}, len(batch.Items))
+	for i, item := range batch.Items {
+		items[i] = map[string]interface{}{
+			"item_id":    item.ID,
+			"feed_title": item.FeedTitle,
+			"cn_title":   item.CnTitle,
+			"title":      item.Title,
+			"link":       item.Link,
+			"tags":       item.Tags,
+		}
+	}
+	reqData, _ := json.Marshal(map[string]interface{}{
+		"title":   title,
+		"message": body,
+		"items":   items,
+	})
+	req, err := http.NewRequest("POST", n.url, bytes.NewBuffer(reqData))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if n.secret != "" {
+		req.Header.Set("X-Shin-Secret", n.secret)
+	}
+	if err := sendJSON(req, nil); err != nil {
+		return fmt.Errorf("webhook: %w", err)
+	}
+	return nil
+}
+
+func importantFeedTitles() []string {
+	var titles []string
+	for _, feedTitle := range strings.Split(IMPORTANT_FEEDS, ",") {
+		titles = append(titles, strings.TrimSpace(feedTitle))
+	}
+	return titles
+}
+
+// isImportant 条目来自 IMPORTANT_FEEDS，或带有 NOTIFY_TAGS 中的标签
+func isImportant(item store.PostItem) bool {
+	for _, feedTitle := range importantFeedTitles() {
+		if feedTitle != "" && feedTitle == item.FeedTitle {
+			return true
+		}
+	}
+	for _, tag := range item.Tags {
+		for _, notifyTag := range notifyTags {
+			if tag == notifyTag {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// queueNotifications 把新抓取的重要条目加入待推送批次
+func queueNotifications(items []store.PostItem) {
+	if len(notifiers) == 0 {
+		return
+	}
+
+	var important []NotifyItem
+	for _, item := range items {
+		if !isImportant(item) {
+			continue
+		}
+		var content store.PostItemContent
+		json.Unmarshal([]byte(item.Content), &content)
+		important = append(important, NotifyItem{
+			ID:        item.ID,
+			FeedTitle: item.FeedTitle,
+			CnTitle:   content.CnTitle,
+			Title:     content.Title,
+			Link:      content.Link,
+			Tags:      item.Tags,
+		})
+	}
+	if len(important) == 0 {
+		return
+	}
+
+	notifyLock.Lock()
+	defer notifyLock.Unlock()
+	now := time.Now()
+	for _, notifier := range notifiers {
+		q := notifyQueues[notifier.Name()]
+		if q == nil {
+			q = &notifyQueue{}
+			notifyQueues[notifier.Name()] = q
+		}
+		q.push(important, now, false)
+	}
+}
+
+// parseQuietHours 解析 "23:00-07:00"，返回从 0 点起的分钟数
+func parseQuietHours(value string) (start, end int, ok bool) {
+	parts := strings.Split(value, "-")
+	if len(parts) != 2 {
+		return 0, 0, false
+	}
+	parseMinutes := func(hm string) (int, bool) {
+		t, err := time.Parse("15:04", strings.TrimSpace(hm))
+		if err != nil {
+			return 0, false
+		}
+		return t.Hour()*60 + t.Minute(), true
+	}
+	start, ok1 := parseMinutes(parts[0])
+	end, ok2 := parseMinutes(parts[1])
+	return start, end, ok1 && ok2
+}
+
+func inQuietHours(t time.Time) bool {
+	if notifyQuietHours == "" {
+		return false
+	}
+	start, end, ok := parseQuietHours(notifyQuietHours)
+	if !ok {
+		return false
+	}
+	t = t.In(location)
+	minutes := t.Hour()*60 + t.Minute()
+	if start <= end {
+		return minutes >= start && minutes < end
+	}
+	// 跨午夜
+	return minutes >= start || minutes < end
+}
+
+// flushNotifications 批次等待满 NOTIFY_BATCH_WINDOW 且不在免打扰时段时合并成一条推送，
+// 推送失败的批次放回队列，退避后重试
+func flushNotifications(now time.Time) {
+	if inQuietHours(now) {
+		return
+	}
+	for _, notifier := range notifiers {
+		notifyLock.Lock()
+		q := notifyQueues[notifier.Name()]
+		if q == nil || len(q.items) == 0 || now.Sub(q.since) < notifyBatchWindow || now.Before(q.retryAt) {
+			notifyLock.Unlock()
+			continue
+		}
+		batch := NotifyBatch{Count: len(q.items), Items: q.items}
+		since := q.since
+		q.items = nil
+		notifyLock.Unlock()
+
+		err := notifier.Notify(batch)
+
+		notifyLock.Lock()
+		if err != nil {
+			q.attempts++
+			q.retryAt = now.Add(retryDelay(q.attempts))
+			q.push(batch.Items, since, true)
+			logger.Printf("Notify %s failed (attempt %d), retry at %s: %v", notifier.Name(), q.attempts, q.retryAt.Format(time.RFC3339), err)
+		} else {
+			q.attempts = 0
+			logger.Printf("Notify %s: %d items", notifier.Name(), batch.Count)
+		}
+		notifyLock.Unlock()
+	}
+}
+
+// NotifyTask 定时检查待推送的批次
+func NotifyTask() {
+	logger.Println("Starting notifier, batch window:", notifyBatchWindow)
+	ticker := time.NewTicker(15 * time.Second)
+	defer ticker.Stop()
+	for now := range ticker.C {
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					logger.Printf("Recovered from panic: %v", r)
+				}
+			}()
+			flushNotifications(now)
+		}()
+	}
+}
@@ -0,0 +1,252 @@
+package web
+
+import (
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"shin/internal/store"
+)
+
+// recordedRequest 是推送服务替身收到的请求
+type recordedRequest struct {
+	method string
+	path   string
+	query  string
+	header http.Header
+	body   string
+}
+
+// notifyServer 记录收到的请求，status 为 0 时返回 200
+type notifyServer struct {
+	mu       sync.Mutex
+	status   int
+	requests []recordedRequest
+}
+
+func startNotifyServer(t *testing.T) (*notifyServer, string) {
+	t.Helper()
+	s := &notifyServer{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.requests = append(s.requests, recordedRequest{r.Method, r.URL.Path, r.URL.RawQuery, r.Header, string(body)})
+		if s.status != 0 {
+			w.WriteHeader(s.status)
+		}
+		w.Write([]byte(`{"ok": true}`))
+	}))
+	t.Cleanup(server.Close)
+	return s, server.URL
+}
+
+func (s *notifyServer) setStatus(status int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.status = status
+}
+
+func (s *notifyServer) received() []recordedRequest {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]recordedRequest{}, s.requests...)
+}
+
+func testBatch() NotifyBatch {
+	items := []NotifyItem{
+		{ID: "1", FeedTitle: "HN", CnTitle: "标题一", Title: "Title one", Link: "https://example.com/1", Tags: []string{"go"}},
+		{ID: "2", FeedTitle: "HN", CnTitle: "标题二", Title: "Title two", Link: "https://example.com/2"},
+	}
+	return NotifyBatch{Count: len(items), Items: items}
+}
+
+const testNotifyBody = "标题一\nTitle one\nhttps://example.com/1\n\n标题二\nTitle two\nhttps://example.com/2"
+
+func TestTelegramNotifier(t *testing.T) {
+	server, serverURL := startNotifyServer(t)
+	n := telegramNotifier{apiURL: serverURL, token: "123:abc", chatID: "42", templates: loadNotifyTemplates("telegram")}
+
+	if err := n.Notify(testBatch()); err != nil {
+		t.Fatalf("Notify() error = %v", err)
+	}
+	req := server.received()[0]
+	if req.method != "POST" || req.path != "/bot123:abc/sendMessage" {
+		t.Errorf("request = %s %s", req.method, req.path)
+	}
+	var payload struct {
+		ChatID  string `json:"chat_id"`
+		Text    string `json:"text"`
+		Preview bool   `json:"disable_web_page_preview"`
+	}
+	json.Unmarshal([]byte(req.body), &payload)
+	if payload.ChatID != "42" || payload.Text != "Shin: 2 important items\n\n"+testNotifyBody || !payload.Preview {
+		t.Errorf("payload = %+v", payload)
+	}
+
+	server.setStatus(http.StatusBadRequest)
+	if err := n.Notify(testBatch()); err == nil || !strings.Contains(err.Error(), "telegram") {
+		t.Errorf("Notify() on 400 error = %v", err)
+	}
+}
+
+func TestTelegramNotifierTruncates(t *testing.T) {
+	server, serverURL := startNotifyServer(t)
+	n := telegramNotifier{apiURL: serverURL, token: "t", chatID: "42", templates: loadNotifyTemplates("telegram")}
+	batch := NotifyBatch{Count: 1, Items: []NotifyItem{{Title: strings.Repeat("长", telegramMaxLength)}}}
+
+	if err := n.Notify(batch); err != nil {
+		t.Fatal(err)
+	}
+	var payload struct {
+		Text string `json:"text"`
+	}
+	json.Unmarshal([]byte(server.received()[0].body), &payload)
+	if n := len([]rune(payload.Text)); n != telegramMaxLength || !strings.HasSuffix(payload.Text, "…") {
+		t.Errorf("text has %d runes, want %d ending with …", n, telegramMaxLength)
+	}
+}
+
+func TestNtfyNotifier(t *testing.T) {
+	server, serverURL := startNotifyServer(t)
+	n := ntfyNotifier{topicURL: serverURL + "/shin", token: "tk_secret", templates: loadNotifyTemplates("ntfy")}
+
+	if err := n.Notify(testBatch()); err != nil {
+		t.Fatalf("Notify() error = %v", err)
+	}
+	req := server.received()[0]
+	title, _ := new(mime.WordDecoder).DecodeHeader(req.header.Get("Title"))
+	if req.path != "/shin" || req.body != testNotifyBody || title != "Shin: 2 important items" {
+		t.Errorf("request = %s %q title %q", req.path, req.body, title)
+	}
+	if req.header.Get("Authorization") != "Bearer tk_secret" || req.header.Get("Click") != "" {
+		t.Errorf("headers = %v", req.header)
+	}
+
+	// 只有一条时点击通知打开条目链接
+	single := NotifyBatch{Count: 1, Items: testBatch().Items[:1]}
+	if err := n.Notify(single); err != nil {
+		t.Fatal(err)
+	}
+	if click := server.received()[1].header.Get("Click"); click != "https://example.com/1" {
+		t.Errorf("Click = %q", click)
+	}
+}
+
+func TestGotifyNotifier(t *testing.T) {
+	server, serverURL := startNotifyServer(t)
+	n := gotifyNotifier{baseURL: serverURL, token: "app token", templates: loadNotifyTemplates("gotify")}
+
+	if err := n.Notify(testBatch()); err != nil {
+		t.Fatalf("Notify() error = %v", err)
+	}
+	req := server.received()[0]
+	if req.path != "/message" || req.query != "token=app+token" {
+		t.Errorf("request = %s?%s", req.path, req.query)
+	}
+	var payload struct {
+		Title    string `json:"title"`
+		Message  string `json:"message"`
+		Priority int    `json:"priority"`
+	}
+	json.Unmarshal([]byte(req.body), &payload)
+	if payload.Title != "Shin: 2 important items" || payload.Message != testNotifyBody || payload.Priority != 5 {
+		t.Errorf("payload = %+v", payload)
+	}
+}
+
+func TestWebhookNotifier(t *testing.T) {
+	server, serverURL := startNotifyServer(t)
+	t.Setenv("NOTIFY_WEBHOOK_TITLE", "{{.Count}} new")
+	n := webhookNotifier{url: serverURL + "/hook", secret: "s3cret", templates: loadNotifyTemplates("webhook")}
+
+	if err := n.Notify(testBatch()); err != nil {
+		t.Fatalf("Notify() error = %v", err)
+	}
+	req := server.received()[0]
+	if req.header.Get("X-Shin-Secret") != "s3cret" {
+		t.Errorf("X-Shin-Secret = %q", req.header.Get("X-Shin-Secret"))
+	}
+	var payload struct {
+		Title   string `json:"title"`
+		Message string `json:"message"`
+		Items   []struct {
+			ItemID string   `json:"item_id"`
+			Link   string   `json:"link"`
+			Tags   []string `json:"tags"`
+		} `json:"items"`
+	}
+	json.Unmarshal([]byte(req.body), &payload)
+	if payload.Title != "2 new" || payload.Message != testNotifyBody || len(payload.Items) != 2 ||
+		payload.Items[0].ItemID != "1" || payload.Items[0].Tags[0] != "go" {
+		t.Errorf("payload = %+v", payload)
+	}
+}
+
+// useNotifiers 替换推送目标并清空队列，测试结束后恢复
+func useNotifiers(t *testing.T, list ...Notifier) {
+	t.Helper()
+	oldNotifiers, oldQueues, oldImportant := notifiers, notifyQueues, IMPORTANT_FEEDS
+	notifiers, notifyQueues, IMPORTANT_FEEDS = list, make(map[string]*notifyQueue), "HN"
+	t.Cleanup(func() { notifiers, notifyQueues, IMPORTANT_FEEDS = oldNotifiers, oldQueues, oldImportant })
+}
+
+func TestFlushNotificationsRequeuesFailedBatch(t *testing.T) {
+	failing, failingURL := startNotifyServer(t)
+	working, workingURL := startNotifyServer(t)
+	useNotifiers(t,
+		gotifyNotifier{baseURL: failingURL, templates: loadNotifyTemplates("gotify")},
+		ntfyNotifier{topicURL: workingURL, templates: loadNotifyTemplates("ntfy")},
+	)
+	failing.setStatus(http.StatusServiceUnavailable)
+
+	content := `{"cnTitle": "标题", "title": "Title", "link": "https://example.com/a"}`
+	queueNotifications([]store.PostItem{
+		{ID: "1", FeedTitle: "HN", Content: content},
+		{ID: "2", FeedTitle: "Other", Content: content},
+	})
+
+	now := time.Now()
+	flushNotifications(now)
+	if len(working.received()) != 0 {
+		t.Fatal("flushed before the batch window elapsed")
+	}
+
+	now = now.Add(notifyBatchWindow)
+	flushNotifications(now)
+	if len(failing.received()) != 1 || len(working.received()) != 1 {
+		t.Fatalf("requests = %d failing, %d working, want 1 each", len(failing.received()), len(working.received()))
+	}
+	if q := notifyQueues["gotify"]; len(q.items) != 1 || q.attempts != 1 {
+		t.Fatalf("gotify queue = %+v, want the failed batch re-queued", q)
+	}
+
+	// 新条目并入重试的批次，退避期间不重试
+	queueNotifications([]store.PostItem{{ID: "3", FeedTitle: "HN", Content: content}})
+	flushNotifications(now.Add(time.Second))
+	if len(failing.received()) != 1 {
+		t.Fatalf("retried during backoff")
+	}
+
+	failing.setStatus(http.StatusOK)
+	flushNotifications(now.Add(retryDelay(1)))
+	requests := failing.received()
+	if len(requests) != 2 || !strings.Contains(requests[1].body, "Shin: 2 important items") {
+		t.Fatalf("retry requests = %+v", requests)
+	}
+	if q := notifyQueues["gotify"]; len(q.items) != 0 || q.attempts != 0 {
+		t.Errorf("gotify queue after retry = %+v, want empty", q)
+	}
+	// 成功的目标只收到新条目
+	if requests := working.received(); len(requests) != 2 {
+		t.Errorf("working requests = %d, want 2", len(requests))
+	} else if title, _ := new(mime.WordDecoder).DecodeHeader(requests[1].header.Get("Title")); title != "Shin: 1 important item" {
+		t.Errorf("working title = %q", title)
+	}
+}
@@ -0,0 +1,223 @@
+package web
+
+import (
+	"reflect"
+	"strings"
+
+	"shin/internal/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+type apiParam struct {
+	Name string
+	Type string // string、integer 或 boolean
+}
+
+type apiRoute struct {
+	Method   string
+	Path     string
+	Summary  string
+	Handler  gin.HandlerFunc
+	Query    []apiParam
+	Request  interface{} // 请求体的零值，nil 表示没有请求体
+	Response interface{} // data 字段的零值
+	Paged    bool        // 响应带 meta 分页信息
+
+	// 请求体或响应不是 JSON 时的 MIME 类型，内容按文件处理
+	RequestType  string
+	ResponseType string
+}
+
+// openAPIPath 把 gin 的 :id 和 *tag 形式转为 OpenAPI 的 {id} 和 {tag}
+func openAPIPath(path string) (string, []string) {
+	var params []string
+	parts := strings.Split(path, "/")
+	for i, part := range parts {
+		if strings.HasPrefix(part, ":") || strings.HasPrefix(part, "*") {
+			params = append(params, part[1:])
+			parts[i] = "{" + part[1:] + "}"
+		}
+	}
+	return strings.Join(parts, "/"), params
+}
+
+type schemaBuilder struct {
+	components map[string]interface{}
+}
+
+func (b *schemaBuilder) jsonFields(t reflect.Type, properties map[string]interface{}, required *[]string) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if field.Anonymous && tag == "" {
+			b.jsonFields(field.Type, properties, required)
+			continue
+		}
+		if !field.IsExported() || tag == "-" {
+			continue
+		}
+		name, options, _ := strings.Cut(tag, ",")
+		if name == "" {
+			name = field.Name
+		}
+		properties[name] = b.schema(field.Type)
+		if !strings.Contains(options, "omitempty") && field.Type.Kind() != reflect.Ptr {
+			*required = append(*required, name)
+		}
+	}
+}
+
+// schema 根据 Go 类型和 json 标签生成 JSON Schema，具名结构体放到 components 中引用
+func (b *schemaBuilder) schema(t reflect.Type) map[string]interface{} {
+	switch t.Kind() {
+	case reflect.Ptr:
+		s := b.schema(t.Elem())
+		if _, ok := s["$ref"]; ok {
+			return map[string]interface{}{"allOf": []interface{}{s}, "nullable": true}
+		}
+		s["nullable"] = true
+		return s
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32:
+		return map[string]interface{}{"type": "integer", "format": "int32"}
+	case reflect.Int64:
+		return map[string]interface{}{"type": "integer", "format": "int64"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array", "items": b.schema(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": b.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return b.structSchema(t)
+		}
+		name := strings.ToUpper(t.Name()[:1]) + t.Name()[1:]
+		if _, ok := b.components[name]; !ok {
+			b.components[name] = nil // 先占位，避免递归类型死循环
+			b.components[name] = b.structSchema(t)
+		}
+		return map[string]interface{}{"$ref": "#/components/schemas/" + name}
+	}
+	return map[string]interface{}{}
+}
+
+func (b *schemaBuilder) structSchema(t reflect.Type) map[string]interface{} {
+	properties := make(map[string]interface{})
+	var required []string
+	b.jsonFields(t, properties, &required)
+	s := map[string]interface{}{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		s["required"] = required
+	}
+	return s
+}
+
+func jsonContent(schema interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"application/json": map[string]interface{}{"schema": schema},
+	}
+}
+
+func fileContent(mimeType string) map[string]interface{} {
+	return map[string]interface{}{
+		mimeType: map[string]interface{}{"schema": map[string]interface{}{"type": "string", "format": "binary"}},
+	}
+}
+
+// openAPIDocument 从 apiRoutes 生成 OpenAPI 3.0 文档
+func openAPIDocument() map[string]interface{} {
+	b := &schemaBuilder{components: make(map[string]interface{})}
+	errorRef := b.schema(reflect.TypeOf(APIErrorEnvelope{}))
+	metaRef := b.schema(reflect.TypeOf(store.PageInfo{}))
+
+	paths := make(map[string]interface{})
+	for _, route := range apiRoutes {
+		path, pathParams := openAPIPath(route.Path)
+
+		var params []interface{}
+		for _, name := range pathParams {
+			params = append(params, map[string]interface{}{
+				"name": name, "in": "path", "required": true, "schema": map[string]interface{}{"type": "string"},
+			})
+		}
+		for _, param := range route.Query {
+			params = append(params, map[string]interface{}{
+				"name": param.Name, "in": "query", "schema": map[string]interface{}{"type": param.Type},
+			})
+		}
+
+		var content map[string]interface{}
+		if route.ResponseType != "" {
+			content = fileContent(route.ResponseType)
+		} else {
+			envelope := map[string]interface{}{"data": b.schema(reflect.TypeOf(route.Response))}
+			required := []string{"data"}
+			if route.Paged {
+				envelope["meta"] = metaRef
+				required = append(required, "meta")
+			}
+			content = jsonContent(map[string]interface{}{"type": "object", "properties": envelope, "required": required})
+		}
+		operation := map[string]interface{}{
+			"summary": route.Summary,
+			"responses": map[string]interface{}{
+				"200": map[string]interface{}{
+					"description": "OK",
+					"content":     content,
+				},
+				"default": map[string]interface{}{
+					"description": "Error",
+					"content":     jsonContent(errorRef),
+				},
+			},
+		}
+		if len(params) > 0 {
+			operation["parameters"] = params
+		}
+		switch {
+		case route.RequestType != "":
+			operation["requestBody"] = map[string]interface{}{
+				"required": true,
+				"content":  fileContent(route.RequestType),
+			}
+		case route.Request != nil:
+			operation["requestBody"] = map[string]interface{}{
+				"required": true,
+				"content":  jsonContent(b.schema(reflect.TypeOf(route.Request))),
+			}
+		}
+
+		item, _ := paths[path].(map[string]interface{})
+		if item == nil {
+			item = make(map[string]interface{})
+			paths[path] = item
+		}
+		item[strings.ToLower(route.Method)] = operation
+	}
+
+	return map[string]interface{}{
+		"openapi": "3.0.3",
+		"info": map[string]interface{}{
+			"title":   "Shin API",
+			"version": "1.0.0",
+		},
+		"servers": []interface{}{map[string]interface{}{"url": "/api/v1"}},
+		"paths":   paths,
+		"components": map[string]interface{}{
+			"schemas": b.components,
+			"securitySchemes": map[string]interface{}{
+				"bearer": map[string]interface{}{"type": "http", "scheme": "bearer"},
+				"cookie": map[string]interface{}{"type": "apiKey", "in": "cookie", "name": "token"},
+			},
+		},
+		"security": []interface{}{
+			map[string]interface{}{"bearer": []string{}},
+			map[string]interface{}{"cookie": []string{}},
+		},
+	}
+}
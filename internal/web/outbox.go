@@ -0,0 +1,180 @@
+package web
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"shin/internal/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	outboxMaxAttempts = 8
+	outboxBaseDelay   = 10 * time.Second
+	outboxMaxDelay    = time.Hour
+	outboxSignal      = make(chan struct{}, 1)
+)
+
+func init() {
+	if v, err := strconv.Atoi(os.Getenv("OUTBOX_MAX_ATTEMPTS")); err == nil && v > 0 {
+		outboxMaxAttempts = v
+	}
+}
+
+// EnqueueSave 把保存请求写入 shin_outbox；已保存过的条目直接返回已有的外部 ID
+// noteID 不为空时，投递时会把这条笔记一起保存
+func EnqueueSave(postItemID, sinkName, noteID string) (externalID string, queued bool, err error) {
+	if _, err := getSink(sinkName); err != nil {
+		return "", false, err
+	}
+	if _, err := st.GetPostItem(postItemID); err != nil {
+		return "", false, err
+	}
+
+	externalID, err = st.GetSavedID(postItemID, sinkName)
+	if err == nil {
+		return externalID, false, nil
+	}
+	if err != store.ErrNotSaved {
+		return "", false, err
+	}
+
+	// 同一条目同一目标只有一条记录，正在排队时重复点击不会再次入队，已完成或失败的记录重新入队
+	if err := st.EnqueueOutbox(postItemID, sinkName, noteID); err != nil {
+		return "", false, err
+	}
+
+	wakeOutbox()
+	return "", true, nil
+}
+
+func wakeOutbox() {
+	select {
+	case outboxSignal <- struct{}{}:
+	default:
+	}
+}
+
+// retryDelay 指数退避：10s, 20s, 40s ... 最长 1 小时
+func retryDelay(attempts int) time.Duration {
+	delay := outboxBaseDelay
+	for i := 1; i < attempts && delay < outboxMaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > outboxMaxDelay {
+		delay = outboxMaxDelay
+	}
+	return delay
+}
+
+func markOutboxFailed(entry store.OutboxEntry, deliverErr error) error {
+	attempts := entry.Attempts + 1
+	status := "pending"
+	if attempts >= outboxMaxAttempts {
+		status = "failed"
+	}
+	return st.MarkOutboxRetry(entry.ID, status, attempts, time.Now().Add(retryDelay(attempts)), deliverErr.Error())
+}
+
+// deliverOutboxEntry 投递一条保存请求，delivered 表示已保存到目标。
+// 所有出错的情况都会更新记录的状态，避免记录一直到期、被反复投递
+func deliverOutboxEntry(entry store.OutboxEntry) (delivered bool, err error) {
+	// 已经保存过（例如上次保存成功但状态没来得及更新）时不再重复创建
+	if externalID, err := st.GetSavedID(entry.ItemID, entry.Sink); err == nil {
+		return true, st.MarkOutboxDone(entry.ID, externalID)
+	}
+
+	sink, err := getSink(entry.Sink)
+	if err != nil {
+		return false, markOutboxFailed(store.OutboxEntry{ID: entry.ID, Attempts: outboxMaxAttempts}, err)
+	}
+	item, err := st.GetPostItem(entry.ItemID)
+	if errors.Is(err, store.ErrNotFound) {
+		return false, markOutboxFailed(store.OutboxEntry{ID: entry.ID, Attempts: outboxMaxAttempts}, err)
+	}
+	if err != nil {
+		return false, errors.Join(err, markOutboxFailed(entry, err))
+	}
+	if entry.NoteID != "" {
+		// 笔记已被删除时只保存条目本身
+		if note, err := st.GetNote(entry.NoteID); err == nil {
+			item.Notes = []store.Note{*note}
+		}
+	}
+
+	externalID, err := sink.Save(*item)
+	if err != nil {
+		logger.Printf("Outbox deliver %s to %s failed (attempt %d): %v", entry.ItemID, entry.Sink, entry.Attempts+1, err)
+		return false, markOutboxFailed(entry, err)
+	}
+	// 目标中已经创建成功，即使保存记录写入失败也要结束这条记录，重试会在目标中重复创建
+	if err := st.InsertSaved(entry.ItemID, entry.Sink, externalID); err != nil {
+		return true, errors.Join(err, st.MarkOutboxDone(entry.ID, externalID))
+	}
+	return true, st.MarkOutboxDone(entry.ID, externalID)
+}
+
+// processOutbox 分批投递到期的记录。一批中没有投递成功或有记录出错（通常是数据库写入失败）时结束，
+// 等下次唤醒再处理，避免状态没有更新的记录被立即取回、反复投递
+func processOutbox() {
+	for {
+		entries, err := st.DueOutboxEntries(20)
+		if err != nil {
+			logger.Println("processOutbox:", err)
+			return
+		}
+		if len(entries) == 0 {
+			return
+		}
+		delivered, failed := 0, 0
+		for _, entry := range entries {
+			ok, err := deliverOutboxEntry(entry)
+			if err != nil {
+				logger.Println("deliverOutboxEntry:", entry.ID, err)
+				failed++
+			}
+			if ok {
+				delivered++
+			}
+		}
+		if delivered == 0 || failed > 0 {
+			return
+		}
+	}
+}
+
+// OutboxTask 在后台投递 shin_outbox 中到期的保存请求
+func OutboxTask() {
+	logger.Println("Starting outbox worker...")
+	ticker := time.NewTicker(outboxBaseDelay)
+	defer ticker.Stop()
+	for {
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					logger.Printf("Recovered from panic: %v", r)
+				}
+			}()
+			processOutbox()
+		}()
+
+		select {
+		case <-outboxSignal:
+		case <-ticker.C:
+		}
+	}
+}
+
+func getOutbox(c *gin.Context) {
+	entries, err := st.ListOutbox(c.Query("item_id"), 100)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to execute query"})
+		return
+	}
+
+	c.JSON(http.StatusOK, entries)
+}
@@ -0,0 +1,120 @@
+package web
+
+import (
+	"errors"
+	"testing"
+
+	"shin/internal/store"
+)
+
+var errDatabaseLocked = errors.New("database is locked")
+
+// lockedStore 模拟写入保存记录或读取条目时数据库被锁
+type lockedStore struct {
+	store.Store
+	insertSavedErr error
+	getItemErr     error
+}
+
+func (s lockedStore) InsertSaved(itemID, sink, externalID string) error {
+	if s.insertSavedErr != nil {
+		return s.insertSavedErr
+	}
+	return s.Store.InsertSaved(itemID, sink, externalID)
+}
+
+func (s lockedStore) GetPostItem(itemID string) (*store.PostItem, error) {
+	if s.getItemErr != nil {
+		return nil, s.getItemErr
+	}
+	return s.Store.GetPostItem(itemID)
+}
+
+func outboxEntry(t *testing.T, itemID string) store.OutboxEntry {
+	t.Helper()
+	entries, err := st.ListOutbox(itemID, 10)
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("ListOutbox(%s) = %v, %v", itemID, entries, err)
+	}
+	return entries[0]
+}
+
+func TestProcessOutboxDelivers(t *testing.T) {
+	useTestStore(t)
+	sink := &fakeSink{name: "fake"}
+	useSinks(t, sink)
+	item := insertTestItem(t, "Feed", "Title", "https://example.com/a")
+
+	if _, queued, err := EnqueueSave(item.ID, "fake", ""); err != nil || !queued {
+		t.Fatalf("EnqueueSave() = %v, %v", queued, err)
+	}
+	processOutbox()
+
+	if sink.savedCount() != 1 {
+		t.Errorf("saved %d times, want 1", sink.savedCount())
+	}
+	if entry := outboxEntry(t, item.ID); entry.Status != "done" || entry.ExternalID != "fake-1" {
+		t.Errorf("entry = %+v, want done with fake-1", entry)
+	}
+	// 已保存的条目再次保存时直接返回外部 ID
+	if externalID, queued, err := EnqueueSave(item.ID, "fake", ""); err != nil || queued || externalID != "fake-1" {
+		t.Errorf("EnqueueSave() again = %q, %v, %v", externalID, queued, err)
+	}
+}
+
+func TestProcessOutboxRetriesSinkFailure(t *testing.T) {
+	useTestStore(t)
+	sink := &fakeSink{name: "fake", err: errors.New("unavailable")}
+	useSinks(t, sink)
+	item := insertTestItem(t, "Feed", "Title", "https://example.com/a")
+	EnqueueSave(item.ID, "fake", "")
+
+	processOutbox()
+
+	entry := outboxEntry(t, item.ID)
+	if entry.Status != "pending" || entry.Attempts != 1 || entry.LastError != "unavailable" {
+		t.Errorf("entry = %+v, want pending retry", entry)
+	}
+}
+
+// 目标保存成功但保存记录写入失败时，记录也要结束，不能被反复投递
+func TestProcessOutboxInsertSavedFails(t *testing.T) {
+	s := useTestStore(t)
+	sink := &fakeSink{name: "fake"}
+	useSinks(t, sink)
+	item := insertTestItem(t, "Feed", "Title", "https://example.com/a")
+	EnqueueSave(item.ID, "fake", "")
+
+	st = lockedStore{Store: s, insertSavedErr: errDatabaseLocked}
+	processOutbox()
+	processOutbox()
+
+	if sink.savedCount() != 1 {
+		t.Errorf("saved %d times, want 1", sink.savedCount())
+	}
+	if entry := outboxEntry(t, item.ID); entry.Status != "done" || entry.ExternalID != "fake-1" {
+		t.Errorf("entry = %+v, want done with fake-1", entry)
+	}
+}
+
+func TestProcessOutboxGetItemFails(t *testing.T) {
+	s := useTestStore(t)
+	sink := &fakeSink{name: "fake"}
+	useSinks(t, sink)
+	item := insertTestItem(t, "Feed", "Title", "https://example.com/a")
+	EnqueueSave(item.ID, "fake", "")
+
+	st = lockedStore{Store: s, getItemErr: errDatabaseLocked}
+	processOutbox()
+
+	if sink.savedCount() != 0 {
+		t.Errorf("saved %d times, want 0", sink.savedCount())
+	}
+	entry := outboxEntry(t, item.ID)
+	if entry.Status != "pending" || entry.Attempts != 1 {
+		t.Errorf("entry = %+v, want a scheduled retry", entry)
+	}
+	if due, _ := st.DueOutboxEntries(10); len(due) != 0 {
+		t.Errorf("DueOutboxEntries() = %v, want none due", due)
+	}
+}
@@ -0,0 +1,67 @@
+package web
+
+import (
+	"fmt"
+	"strconv"
+
+	"shin/internal/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+func positiveQuery(c *gin.Context, name string, fallback int) (int, error) {
+	value := c.Query(name)
+	if value == "" {
+		return fallback, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("%w: %s must be a positive integer", errInvalidInput, name)
+	}
+	return n, nil
+}
+
+// parsePage 读取 page（旧接口为 page_number）、page_size 和 cursor，
+// 缺省时使用默认值，page_size 超过上限时截断
+func parsePage(c *gin.Context) (store.Page, error) {
+	pageParam := "page"
+	if c.Query("page") == "" && c.Query("page_number") != "" {
+		pageParam = "page_number"
+	}
+	number, err := positiveQuery(c, pageParam, 1)
+	if err != nil {
+		return store.Page{}, err
+	}
+	size, err := positiveQuery(c, "page_size", defaultPageSize)
+	if err != nil {
+		return store.Page{}, err
+	}
+	if size > maxPageSize {
+		size = maxPageSize
+	}
+
+	page := store.Page{Number: number, Size: size}
+	if cursor := c.Query("cursor"); cursor != "" {
+		if page.Cursor, err = store.DecodeCursor(cursor); err != nil {
+			return store.Page{}, fmt.Errorf("%w: %v", errInvalidInput, err)
+		}
+	}
+	return page, nil
+}
+
+// pageResponse 是旧接口的分页响应，total_page 为兼容首页保留
+func pageResponse(data interface{}, info store.PageInfo) gin.H {
+	return gin.H{
+		"data":        data,
+		"page":        info.Page,
+		"page_size":   info.PageSize,
+		"total_page":  info.TotalPages,
+		"has_more":    info.HasMore,
+		"next_cursor": info.NextCursor,
+	}
+}
@@ -0,0 +1,91 @@
+package web
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"shin/internal/ingest"
+	"shin/internal/store"
+)
+
+// TestFetchNewsEndToEnd 从 FreshRSS 拉取、翻译、入库、生成摘要，再把条目保存到 Memos
+func TestFetchNewsEndToEnd(t *testing.T) {
+	useTestStore(t)
+	upstream, freshrssSource := startFreshRSSStub(t)
+	useSource(t, freshrssSource)
+	memosServer, memosClient := startMemosStub(t)
+	useSinks(t, memosClient)
+
+	now := time.Now().Unix()
+	upstream.addFeed("feed/1", "HN", now-600,
+		[2]string{"SQLite 3.47 released", "https://m.example.com/sqlite"},
+		[2]string{"Go 1.24 is out", "https://example.com/go"})
+	upstream.addFeed("feed/2", "Blog", now-7200, [2]string{"Too old", "https://example.com/old"})
+	if err := ingest.SaveRewriteRules(st, []ingest.RewriteRule{
+		{Feed: "HN", Field: "link", Pattern: `^https://m\.example\.com/(.*)$`, Replacement: "https://example.com/$1"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := ingest.SaveTagRules(st, []ingest.TagRule{{Tag: "db", Keyword: "sqlite"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	in := ingest.New(st, freshrssSource, startTranslateStub(t))
+	in.DefaultOT = strconv.FormatInt(now-3600, 10)
+	in.ItemDelay = 0
+	in.OnInsert(AfterInsert)
+
+	authToken := freshrssSource.Auth()
+	items := in.FetchNews(authToken)
+	if len(items) != 2 {
+		t.Fatalf("FetchNews() inserted %d items, want 2", len(items))
+	}
+
+	// shin_feed 为空时先从 FreshRSS 同步订阅
+	feeds, err := st.ListFeeds()
+	if err != nil || len(feeds) != 2 {
+		t.Fatalf("ListFeeds() = %d feeds, %v", len(feeds), err)
+	}
+
+	AfterRound(authToken, items)
+	posts, _, err := st.ListPosts(store.Page{Number: 1, Size: 10})
+	if err != nil || len(posts) != 1 {
+		t.Fatalf("ListPosts() = %d posts, %v", len(posts), err)
+	}
+	postItems, err := st.PostItems(posts[0].ID)
+	if err != nil || len(postItems) != 2 {
+		t.Fatalf("PostItems() = %d items, %v", len(postItems), err)
+	}
+	var sqlite store.PostItem
+	for _, item := range postItems {
+		var content store.PostItemContent
+		json.Unmarshal([]byte(item.Content), &content)
+		if content.CnTitle != "译:"+content.Title || item.FeedTitle != "HN" || item.UpstreamID == "" {
+			t.Errorf("item = %+v, content = %+v", item, content)
+		}
+		if content.Title == "SQLite 3.47 released" {
+			sqlite = item
+			if content.Link != "https://example.com/sqlite" || strings.Join(item.Tags, ",") != "db" {
+				t.Errorf("rewrite and tag rules not applied: %+v %v", content, item.Tags)
+			}
+		}
+	}
+	if sqlite.ID == "" {
+		t.Fatal("SQLite item is missing from the digest")
+	}
+
+	if _, queued, err := EnqueueSave(sqlite.ID, "memos", ""); err != nil || !queued {
+		t.Fatalf("EnqueueSave() = %v, %v", queued, err)
+	}
+	processOutbox()
+	contents := memosServer.contents()
+	if len(contents) != 1 || !strings.Contains(contents[0], "译:SQLite 3.47 released") || !strings.Contains(contents[0], "https://example.com/sqlite") {
+		t.Fatalf("memos = %q", contents)
+	}
+	if saved, err := st.GetSavedID(sqlite.ID, "memos"); err != nil || saved != "memos/1" {
+		t.Errorf("GetSavedID() = %q, %v", saved, err)
+	}
+}
@@ -0,0 +1,48 @@
+package web
+
+import (
+	"os"
+	"time"
+
+	"shin/internal/retention"
+)
+
+var (
+	retentionPolicy                         = retention.PolicyFromEnv()
+	retentionSchedule, retentionScheduleErr = parseSchedule(retentionScheduleExpr())
+)
+
+// retentionScheduleExpr 默认每天 04:30 清理一次
+func retentionScheduleExpr() string {
+	if expr := os.Getenv("RETENTION_SCHEDULE"); expr != "" {
+		return expr
+	}
+	return "30 4 * * *"
+}
+
+// RetentionTask 按 RETENTION_SCHEDULE 清理超过 RETENTION_DAYS 的摘要，与 shin prune 相同
+func RetentionTask() {
+	logger.Println("Starting retention schedule:", retentionScheduleExpr(), "days:", retentionPolicy.Days)
+	for {
+		now := time.Now().In(location)
+		next := retentionSchedule.Next(now)
+		if next.IsZero() {
+			logger.Println("No upcoming retention time, retention schedule stopped.")
+			return
+		}
+		time.Sleep(next.Sub(now))
+
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					logger.Printf("Recovered from panic: %v", r)
+				}
+			}()
+			report, err := retention.Run(st, retentionPolicy, false)
+			if err != nil {
+				logger.Println("retention:", err)
+			}
+			logger.Println("retention:", report)
+		}()
+	}
+}
@@ -0,0 +1,60 @@
+package web
+
+import (
+	"errors"
+	"net/http"
+
+	"shin/internal/ingest"
+
+	"github.com/gin-gonic/gin"
+)
+
+func getRewriteRules(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"rules":   ingest.GetRewriteRules(st),
+		"presets": ingest.RewritePresets,
+	})
+}
+
+func updateRewriteRules(c *gin.Context) {
+	var input struct {
+		Rules []ingest.RewriteRule `json:"rules"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := ingest.SaveRewriteRules(st, input.Rules); err != nil {
+		if errors.Is(err, ingest.ErrInvalidRule) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error saving rules"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Rules updated"})
+}
+
+func dryRunRewriteRule(c *gin.Context) {
+	var input struct {
+		Rule  ingest.RewriteRule `json:"rule"`
+		Hours int                `json:"hours"`
+		Limit int                `json:"limit"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	results, err := ingester.DryRunRewriteRule(input.Rule, input.Hours, input.Limit)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, results)
+}
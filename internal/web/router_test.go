@@ -0,0 +1,398 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// routeTester 通过 Router() 发请求，记录访问过的路由，最后检查每个注册的路由都测过
+type routeTester struct {
+	t       *testing.T
+	r       *gin.Engine
+	covered map[string]bool
+}
+
+// useRouter 在仓库根目录创建 Router()，模板和静态文件按相对路径加载
+func useRouter(t *testing.T) *routeTester {
+	t.Helper()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir("../.."); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+
+	oldToken := authToken
+	authToken = "test-token"
+	t.Cleanup(func() { authToken = oldToken })
+
+	gin.SetMode(gin.TestMode)
+	return &routeTester{t: t, r: Router(), covered: map[string]bool{}}
+}
+
+// do 以登录状态请求 path，route 是它命中的路由。body 以 { 开头时按 JSON 发送
+func (rt *routeTester) do(method, route, path, body string, wantStatus int) *httptest.ResponseRecorder {
+	rt.t.Helper()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	switch {
+	case strings.HasPrefix(body, "{"):
+		req.Header.Set("Content-Type", "application/json")
+	case body != "" && method == http.MethodPost && !strings.HasPrefix(path, "/import"):
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	if strings.HasPrefix(path, "/api/") {
+		req.Header.Set("Authorization", "Bearer test-token")
+	} else {
+		req.AddCookie(&http.Cookie{Name: "token", Value: "test-token"})
+	}
+	w := httptest.NewRecorder()
+	rt.r.ServeHTTP(w, req)
+	rt.covered[method+" "+route] = true
+	if w.Code != wantStatus {
+		rt.t.Errorf("%s %s = %d, want %d\n%s", method, path, w.Code, wantStatus, w.Body.String())
+	}
+	return w
+}
+
+// decode 把 JSON 响应解析到 v
+func (rt *routeTester) decode(w *httptest.ResponseRecorder, v interface{}) {
+	rt.t.Helper()
+	if err := json.Unmarshal(w.Body.Bytes(), v); err != nil {
+		rt.t.Fatalf("invalid JSON %q: %v", w.Body.String(), err)
+	}
+}
+
+func (rt *routeTester) checkCoverage() {
+	rt.t.Helper()
+	var missing []string
+	for _, route := range rt.r.Routes() {
+		if key := route.Method + " " + route.Path; !rt.covered[key] {
+			missing = append(missing, key)
+		}
+	}
+	sort.Strings(missing)
+	if len(missing) > 0 {
+		rt.t.Errorf("routes without a test:\n%s", strings.Join(missing, "\n"))
+	}
+}
+
+func TestRouterAuth(t *testing.T) {
+	useTestStore(t)
+	rt := useRouter(t)
+
+	tests := []struct {
+		path, cookie, bearer string
+		wantStatus           int
+	}{
+		{"/home", "", "", http.StatusFound},
+		{"/home", "wrong", "", http.StatusFound},
+		{"/pagePost", "", "", http.StatusFound},
+		{"/api/v1/posts", "", "", http.StatusUnauthorized},
+		{"/api/v1/posts", "", "wrong", http.StatusUnauthorized},
+		{"/api/v1/posts", "test-token", "", http.StatusOK},
+		{"/api/v1/posts", "", "test-token", http.StatusOK},
+		{"/feeds/digests.atom", "", "", http.StatusUnauthorized},
+		{"/api/v1/nope", "", "test-token", http.StatusNotFound},
+		{"/nope", "test-token", "", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", tt.path, nil)
+		if tt.cookie != "" {
+			req.AddCookie(&http.Cookie{Name: "token", Value: tt.cookie})
+		}
+		if tt.bearer != "" {
+			req.Header.Set("Authorization", "Bearer "+tt.bearer)
+		}
+		w := httptest.NewRecorder()
+		rt.r.ServeHTTP(w, req)
+		if w.Code != tt.wantStatus {
+			t.Errorf("GET %s (cookie %q, bearer %q) = %d, want %d", tt.path, tt.cookie, tt.bearer, w.Code, tt.wantStatus)
+		}
+	}
+}
+
+// TestRouterRoutes 依次调用 Router 和 registerAPI 注册的每个路由
+func TestRouterRoutes(t *testing.T) {
+	useTestStore(t)
+	useNotifiers(t)
+	oldImportant := IMPORTANT_FEEDS
+	IMPORTANT_FEEDS = "Feed"
+	t.Cleanup(func() { IMPORTANT_FEEDS = oldImportant })
+	upstream, freshrssSource := startFreshRSSStub(t)
+	upstream.addFeed("feed/1", "Upstream", 1700000000, [2]string{"Upstream item", "https://m.example.com/u"})
+	useSource(t, freshrssSource)
+	memosServer, memosClient := startMemosStub(t)
+	fake := &fakeSink{name: "fake"}
+	useSinks(t, memosClient, fake)
+	stub, addr := startSMTPStub(t)
+	useSMTP(t, addr)
+	chatCompletionStub(t, http.StatusOK, completionBody(`{"summary": "本期要点", "tags": ["AI"]}`))
+
+	page, _ := os.ReadFile("testdata/extract/blog.html")
+	articleServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write(page)
+	}))
+	t.Cleanup(articleServer.Close)
+	useArticleClient(t, articleServer.Client())
+
+	item := insertTestItem(t, "Feed", "Title", articleServer.URL+"/post")
+	if _, err := st.CutPost("p1", "Digest"); err != nil {
+		t.Fatal(err)
+	}
+	other := insertTestItem(t, "Other", "Other title", "https://example.com/other")
+	itemID := item.ID
+	rt := useRouter(t)
+
+	// 页面和静态文件
+	for _, path := range []string{"/login_page", "/", "/home", "/detail", "/tools", "/reader", "/subscriptions"} {
+		rt.do("GET", path, path, "", http.StatusOK)
+	}
+	if w := rt.do("GET", "/tag/*name", "/tag/lang/go", "", http.StatusOK); !strings.Contains(w.Body.String(), "lang/go") {
+		t.Errorf("tag page does not show the tag:\n%s", w.Body.String())
+	}
+	rt.do("GET", "/static/*filepath", "/static/css/styles.css", "", http.StatusOK)
+	rt.do("HEAD", "/static/*filepath", "/static/js/items.js", "", http.StatusOK)
+
+	// 登录
+	if w := rt.do("POST", "/login", "/login", "token=test-token", http.StatusFound); w.Header().Get("Location") != "/home" {
+		t.Errorf("login redirects to %q", w.Header().Get("Location"))
+	}
+	if w := rt.do("POST", "/login", "/login", "token=wrong", http.StatusOK); !strings.Contains(w.Body.String(), "Invalid token") {
+		t.Errorf("failed login page:\n%s", w.Body.String())
+	}
+
+	// 摘要
+	rt.do("GET", "/pagePost", "/pagePost?page=1&page_size=10", "", http.StatusOK)
+	if w := rt.do("GET", "/getDetail", "/getDetail?id=p1", "", http.StatusOK); !strings.Contains(w.Body.String(), itemID) {
+		t.Errorf("getDetail does not contain the item:\n%s", w.Body.String())
+	}
+	rt.do("GET", "/getDetail", "/getDetail?id=missing", "", http.StatusNotFound)
+	rt.do("POST", "/summarizePost", "/summarizePost", `{"post_id": "p1"}`, http.StatusOK)
+	rt.do("POST", "/sendDigestEmail", "/sendDigestEmail", `{"post_id": "p1"}`, http.StatusOK)
+	rt.do("POST", "/markRead", "/markRead", `{"post_id": "p1"}`, http.StatusOK)
+	rt.do("GET", "/search", "/search?keyword=Title", "", http.StatusOK)
+	if w := rt.do("GET", "/getImportant", "/getImportant", "", http.StatusOK); !strings.Contains(w.Body.String(), itemID) || strings.Contains(w.Body.String(), other.ID) {
+		t.Errorf("getImportant:\n%s", w.Body.String())
+	}
+	rt.do("GET", "/getArticle", "/getArticle?id="+itemID, "", http.StatusOK)
+
+	// 条目状态
+	rt.do("POST", "/markItemRead", "/markItemRead", `{"item_id": "`+itemID+`", "read": true}`, http.StatusOK)
+	rt.do("POST", "/starItem", "/starItem", `{"item_id": "`+itemID+`", "starred": true}`, http.StatusOK)
+
+	// 笔记
+	var note struct {
+		ID string `json:"id"`
+	}
+	rt.decode(rt.do("POST", "/createNote", "/createNote", `{"item_id": "`+itemID+`", "body": "first"}`, http.StatusOK), &note)
+	rt.do("POST", "/updateNote", "/updateNote", `{"id": "`+note.ID+`", "body": "edited"}`, http.StatusOK)
+	if w := rt.do("GET", "/getNotes", "/getNotes?item_id="+itemID, "", http.StatusOK); !strings.Contains(w.Body.String(), "edited") {
+		t.Errorf("getNotes:\n%s", w.Body.String())
+	}
+
+	// 保存目标
+	rt.do("GET", "/getSinks", "/getSinks", "", http.StatusOK)
+	rt.do("POST", "/createMemo", "/createMemo", `{"postItemID": "`+itemID+`"}`, http.StatusAccepted)
+	rt.do("POST", "/saveItem", "/saveItem", `{"postItemID": "`+itemID+`", "sink": "fake"}`, http.StatusAccepted)
+	processOutbox()
+	if len(memosServer.contents()) != 1 || fake.savedCount() != 1 {
+		t.Fatalf("memos = %q, fake saved %d", memosServer.contents(), fake.savedCount())
+	}
+	rt.do("GET", "/getOutbox", "/getOutbox?item_id="+itemID, "", http.StatusOK)
+	rt.do("POST", "/updateMemo", "/updateMemo", `{"postItemID": "`+itemID+`", "comment": "follow-up"}`, http.StatusOK)
+	rt.do("POST", "/createMemo", "/createMemo", `{"postItemID": "`+itemID+`", "noteID": "`+note.ID+`"}`, http.StatusOK)
+	rt.do("POST", "/deleteNote", "/deleteNote", `{"id": "`+note.ID+`"}`, http.StatusOK)
+	rt.do("POST", "/unsaveItem", "/unsaveItem", `{"postItemID": "`+itemID+`", "sink": "fake"}`, http.StatusOK)
+	rt.do("POST", "/deleteMemo", "/deleteMemo", `{"postItemID": "`+itemID+`"}`, http.StatusOK)
+	if len(memosServer.contents()) != 0 {
+		t.Errorf("memos after deleteMemo = %q", memosServer.contents())
+	}
+
+	// 邮件中的保存链接
+	rt.do("GET", "/save", "/save?id="+other.ID, "", http.StatusOK)
+	rt.do("POST", "/save", "/save", url.Values{"id": {other.ID}, "sink": {"fake"}}.Encode(), http.StatusSeeOther)
+
+	// 标签和订阅地址
+	rt.do("POST", "/addItemTag", "/addItemTag", `{"item_id": "`+itemID+`", "tag": "lang/go"}`, http.StatusOK)
+	rt.do("GET", "/getTags", "/getTags", "", http.StatusOK)
+	if w := rt.do("GET", "/getTaggedItems", "/getTaggedItems?tag=lang/go", "", http.StatusOK); !strings.Contains(w.Body.String(), itemID) {
+		t.Errorf("getTaggedItems:\n%s", w.Body.String())
+	}
+	var feedURLs []feedURL
+	rt.decode(rt.do("GET", "/getFeedURLs", "/getFeedURLs", "", http.StatusOK), &feedURLs)
+	routes := map[string]string{"digests": "/feeds/digests.atom", "important": "/feeds/important.atom", "tag/lang/go": "/feeds/tag/*name"}
+	for _, feed := range feedURLs {
+		u, err := url.Parse(feed.URL)
+		if err != nil || routes[feed.Feed] == "" {
+			t.Fatalf("feed URL %+v", feed)
+		}
+		if w := rt.do("GET", routes[feed.Feed], u.RequestURI(), "", http.StatusOK); !strings.Contains(w.Body.String(), "<feed") {
+			t.Errorf("%s is not an Atom feed:\n%s", feed.URL, w.Body.String())
+		}
+	}
+	rt.do("POST", "/rotateFeedToken", "/rotateFeedToken", `{"feed": "digests"}`, http.StatusOK)
+	rt.do("POST", "/rotateFeedToken", "/rotateFeedToken", `{"feed": "nope"}`, http.StatusBadRequest)
+	rt.do("POST", "/removeItemTag", "/removeItemTag", `{"item_id": "`+itemID+`", "tag": "lang/go"}`, http.StatusOK)
+
+	// 规则
+	rt.do("POST", "/updateRewriteRules", "/updateRewriteRules", `{"rules": [{"feed": "*", "field": "link", "pattern": "^https://m\\.(.*)$", "replacement": "https://$1"}]}`, http.StatusOK)
+	rt.do("POST", "/updateRewriteRules", "/updateRewriteRules", `{"rules": [{"feed": "*", "field": "link", "pattern": "("}]}`, http.StatusBadRequest)
+	rt.do("GET", "/getRewriteRules", "/getRewriteRules", "", http.StatusOK)
+	if w := rt.do("POST", "/dryRunRewriteRule", "/dryRunRewriteRule", `{"rule": {"feed": "*", "field": "link", "pattern": "^https://m\\.(.*)$", "replacement": "https://$1"}, "hours": 100000, "limit": 5}`, http.StatusOK); !strings.Contains(w.Body.String(), "https://example.com/u") {
+		t.Errorf("dryRunRewriteRule:\n%s", w.Body.String())
+	}
+	rt.do("POST", "/updateTagRules", "/updateTagRules", `{"rules": [{"tag": "ai", "keyword": "title"}]}`, http.StatusOK)
+	rt.do("GET", "/getTagRules", "/getTagRules", "", http.StatusOK)
+
+	// 订阅管理
+	var feed struct {
+		ID string `json:"id"`
+	}
+	rt.decode(rt.do("POST", "/addSubscription", "/addSubscription", `{"url": "https://blog.example.com/feed.xml", "title": "Blog", "translate": "none"}`, http.StatusOK), &feed)
+	rt.do("POST", "/addSubscription", "/addSubscription", `{"url": "https://blog.example.com/feed.xml"}`, http.StatusConflict)
+	rt.do("POST", "/updateSubscription", "/updateSubscription", `{"id": "`+feed.ID+`", "title": "Renamed", "enabled": false, "translate": "none"}`, http.StatusOK)
+	rt.do("POST", "/syncSubscriptions", "/syncSubscriptions", "", http.StatusOK)
+	if w := rt.do("GET", "/getSubscriptions", "/getSubscriptions", "", http.StatusOK); !strings.Contains(w.Body.String(), "Renamed") || !strings.Contains(w.Body.String(), "Upstream") {
+		t.Errorf("getSubscriptions:\n%s", w.Body.String())
+	}
+	opml := rt.do("GET", "/exportOPML", "/exportOPML", "", http.StatusOK).Body.String()
+	rt.do("POST", "/deleteSubscription", "/deleteSubscription", `{"id": "`+feed.ID+`"}`, http.StatusOK)
+	if w := rt.do("POST", "/importOPML", "/importOPML", opml, http.StatusOK); !strings.Contains(w.Body.String(), `"added":1`) {
+		t.Errorf("importOPML:\n%s", w.Body.String())
+	}
+
+	// 导出再导入
+	export := rt.do("GET", "/export", "/export", "", http.StatusOK).Body.String()
+	rt.do("POST", "/import", "/import", export, http.StatusOK)
+	rt.do("POST", "/import", "/import", "not json", http.StatusBadRequest)
+
+	testAPIRoutes(t, rt, itemID, other.ID)
+
+	stub.mu.Lock()
+	if len(stub.messages) != 2 {
+		t.Errorf("sent %d emails, want 2", len(stub.messages))
+	}
+	stub.mu.Unlock()
+	rt.checkCoverage()
+}
+
+// testAPIRoutes 调用 /api/v1 下的每个路由
+func testAPIRoutes(t *testing.T, rt *routeTester, itemID, otherID string) {
+	const v1 = "/api/v1"
+	var doc struct {
+		Paths map[string]map[string]struct {
+			RequestBody struct {
+				Content map[string]any `json:"content"`
+			} `json:"requestBody"`
+			Responses map[string]struct {
+				Content map[string]any `json:"content"`
+			} `json:"responses"`
+		} `json:"paths"`
+	}
+	rt.decode(rt.do("GET", v1+"/openapi.json", v1+"/openapi.json", "", http.StatusOK), &doc)
+	if doc.Paths["/export"]["get"].Responses["200"].Content["application/x-ndjson"] == nil ||
+		doc.Paths["/import"]["post"].RequestBody.Content["application/x-ndjson"] == nil {
+		t.Errorf("openapi.json is missing /export or /import: %+v", doc.Paths["/export"])
+	}
+
+	var posts struct {
+		Data       []APIPost      `json:"data"`
+		Pagination map[string]any `json:"pagination"`
+	}
+	rt.decode(rt.do("GET", v1+"/posts", v1+"/posts?page_size=1", "", http.StatusOK), &posts)
+	if len(posts.Data) != 1 || posts.Data[0].ID != "p1" {
+		t.Errorf("GET /posts = %+v", posts)
+	}
+	rt.do("GET", v1+"/posts/:id", v1+"/posts/p1", "", http.StatusOK)
+	rt.do("GET", v1+"/posts/:id", v1+"/posts/missing", "", http.StatusNotFound)
+	rt.do("POST", v1+"/posts/:id/read", v1+"/posts/p1/read", "", http.StatusOK)
+	rt.do("POST", v1+"/posts/:id/summarize", v1+"/posts/p1/summarize", "", http.StatusOK)
+	rt.do("POST", v1+"/posts/:id/email", v1+"/posts/p1/email", "", http.StatusOK)
+
+	rt.do("GET", v1+"/items", v1+"/items?q=Title", "", http.StatusOK)
+	rt.do("GET", v1+"/items", v1+"/items?important=true&page_size=1", "", http.StatusOK)
+	rt.do("GET", v1+"/items", v1+"/items", "", http.StatusBadRequest)
+	rt.do("GET", v1+"/items/:id", v1+"/items/"+itemID, "", http.StatusOK)
+	rt.do("GET", v1+"/items/:id", v1+"/items/missing", "", http.StatusNotFound)
+	rt.do("PATCH", v1+"/items/:id", v1+"/items/"+itemID, `{"read": false, "starred": false}`, http.StatusOK)
+	rt.do("GET", v1+"/items/:id/article", v1+"/items/"+itemID+"/article?refresh=true", "", http.StatusOK)
+
+	rt.do("POST", v1+"/items/:id/saves", v1+"/items/"+itemID+"/saves", `{"sink": "memos"}`, http.StatusAccepted)
+	rt.do("POST", v1+"/items/:id/saves", v1+"/items/"+itemID+"/saves", `{"sink": "nope"}`, http.StatusBadRequest)
+	processOutbox()
+	rt.do("POST", v1+"/items/:id/saves/:sink/comments", v1+"/items/"+itemID+"/saves/memos/comments", `{"text": "from the API"}`, http.StatusOK)
+	rt.do("DELETE", v1+"/items/:id/saves/:sink", v1+"/items/"+itemID+"/saves/memos", "", http.StatusOK)
+
+	var note struct {
+		Data struct {
+			ID string `json:"id"`
+		} `json:"data"`
+	}
+	rt.decode(rt.do("POST", v1+"/items/:id/notes", v1+"/items/"+itemID+"/notes", `{"body": "api note"}`, http.StatusCreated), &note)
+	rt.do("POST", v1+"/items/:id/notes", v1+"/items/"+itemID+"/notes", `{"body": " "}`, http.StatusBadRequest)
+	rt.do("GET", v1+"/items/:id/notes", v1+"/items/"+itemID+"/notes", "", http.StatusOK)
+	rt.do("PUT", v1+"/notes/:id", v1+"/notes/"+note.Data.ID, `{"body": "api note, edited"}`, http.StatusOK)
+	rt.do("POST", v1+"/notes/:id/promote", v1+"/notes/"+note.Data.ID+"/promote", "", http.StatusAccepted)
+	rt.do("DELETE", v1+"/notes/:id", v1+"/notes/"+note.Data.ID, "", http.StatusOK)
+
+	rt.do("POST", v1+"/items/:id/tags", v1+"/items/"+otherID+"/tags", `{"tag": "a/b"}`, http.StatusOK)
+	if w := rt.do("GET", v1+"/items", v1+"/items?tag=a/b", "", http.StatusOK); !strings.Contains(w.Body.String(), otherID) {
+		t.Errorf("GET /items?tag=a/b:\n%s", w.Body.String())
+	}
+	rt.do("DELETE", v1+"/items/:id/tags/*tag", v1+"/items/"+otherID+"/tags/a/b", "", http.StatusOK)
+
+	insertTestItem(t, "Renamed", "Blog post", "https://blog.example.com/1")
+	var feeds struct {
+		Data []APIFeed `json:"data"`
+	}
+	rt.decode(rt.do("GET", v1+"/feeds", v1+"/feeds", "", http.StatusOK), &feeds)
+	byTitle := map[string]APIFeed{}
+	for _, feed := range feeds.Data {
+		byTitle[feed.Title] = feed
+	}
+	if upstream := byTitle["Upstream"]; upstream.ID == "" || upstream.UpstreamID != "feed/1" {
+		t.Errorf("GET /feeds = %+v", feeds.Data)
+	}
+	if blog := byTitle["Renamed"]; blog.URL != "https://blog.example.com/feed.xml" || blog.ItemCount != 1 {
+		t.Errorf("GET /feeds = %+v", feeds.Data)
+	}
+	rt.do("GET", v1+"/tags", v1+"/tags", "", http.StatusOK)
+	rt.do("GET", v1+"/sinks", v1+"/sinks", "", http.StatusOK)
+
+	rt.do("GET", v1+"/rules/rewrite", v1+"/rules/rewrite", "", http.StatusOK)
+	rt.do("PUT", v1+"/rules/rewrite", v1+"/rules/rewrite", `{"rules": []}`, http.StatusOK)
+	if w := rt.do("POST", v1+"/rules/rewrite/dry-run", v1+"/rules/rewrite/dry-run", `{"rule": {"feed": "Upstream", "field": "title", "pattern": "(.*)", "replacement": "$1"}, "hours": 100000, "limit": 1}`, http.StatusOK); !strings.Contains(w.Body.String(), `"rewritten":"Upstream item"`) {
+		t.Errorf("POST /rules/rewrite/dry-run:\n%s", w.Body.String())
+	}
+	rt.do("GET", v1+"/rules/tag", v1+"/rules/tag", "", http.StatusOK)
+	rt.do("PUT", v1+"/rules/tag", v1+"/rules/tag", `{"rules": [{"tag": ""}]}`, http.StatusBadRequest)
+
+	export := rt.do("GET", v1+"/export", v1+"/export", "", http.StatusOK)
+	if ct := export.Header().Get("Content-Type"); ct != "application/x-ndjson" {
+		t.Errorf("GET /export Content-Type = %q", ct)
+	}
+	var imported struct {
+		Data struct {
+			Items int `json:"items"`
+			Feeds int `json:"feeds"`
+		} `json:"data"`
+	}
+	rt.decode(rt.do("POST", v1+"/import", v1+"/import", export.Body.String(), http.StatusOK), &imported)
+	if imported.Data.Items == 0 || imported.Data.Feeds != len(feeds.Data) {
+		t.Errorf("POST /import = %+v", imported.Data)
+	}
+	if w := rt.do("POST", v1+"/import", v1+"/import", `{"type": "post"}`, http.StatusBadRequest); !strings.Contains(w.Body.String(), "invalid_request") {
+		t.Errorf("POST /import with a bad file:\n%s", w.Body.String())
+	}
+}
@@ -0,0 +1,75 @@
+package web
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"shin/internal/store"
+)
+
+// useTestStore 打开测试独占的内存 SQLite 库并设为处理函数使用的 st，测试结束后恢复
+func useTestStore(t *testing.T) *store.SQLStore {
+	t.Helper()
+	name := strings.NewReplacer("/", "_", " ", "_").Replace(t.Name())
+	s, err := store.OpenSQLite(fmt.Sprintf("file:web_%s?mode=memory&cache=shared", name))
+	if err != nil {
+		t.Fatalf("OpenSQLite: %v", err)
+	}
+	old := st
+	st = s
+	t.Cleanup(func() {
+		st = old
+		s.Close()
+	})
+	return s
+}
+
+// useSinks 替换启用的保存目标，测试结束后恢复
+func useSinks(t *testing.T, list ...Sink) {
+	t.Helper()
+	old := sinks
+	sinks = list
+	t.Cleanup(func() { sinks = old })
+}
+
+// insertTestItem 写入一个还未归入摘要的条目
+func insertTestItem(t *testing.T, feedTitle, title, link string) store.PostItem {
+	t.Helper()
+	content, _ := json.Marshal(store.PostItemContent{CnTitle: title, Title: title, Link: link})
+	item := store.PostItem{ID: store.NewID(), FeedTitle: feedTitle, Content: string(content)}
+	if err := st.InsertPostItems([]store.PostItem{item}); err != nil {
+		t.Fatalf("InsertPostItems: %v", err)
+	}
+	return item
+}
+
+// fakeSink 记录保存过的条目，err 不为空时保存失败
+type fakeSink struct {
+	mu    sync.Mutex
+	name  string
+	err   error
+	saved []string
+}
+
+func (s *fakeSink) Name() string {
+	return s.name
+}
+
+func (s *fakeSink) Save(item store.PostItem) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return "", s.err
+	}
+	s.saved = append(s.saved, item.ID)
+	return fmt.Sprintf("%s-%d", s.name, len(s.saved)), nil
+}
+
+func (s *fakeSink) savedCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.saved)
+}
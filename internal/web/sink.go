@@ -0,0 +1,438 @@
+package web
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"shin/internal/memos"
+	"shin/internal/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Sink 是条目的保存目标，Save 返回目标系统中的 ID
+type Sink interface {
+	Name() string
+	Save(item store.PostItem) (string, error)
+}
+
+// Appender 是支持在已保存的条目上追加内容的目标
+type Appender interface {
+	Append(externalID, text string) (string, error)
+}
+
+// Deleter 是支持删除已保存条目的目标
+type Deleter interface {
+	Delete(externalID string) error
+}
+
+var (
+	errUnknownSink     = errors.New("unknown sink")
+	errUnsupported     = errors.New("operation not supported by this sink")
+	errInvalidResponse = errors.New("invalid response")
+	sinks              = loadSinks()
+)
+
+const defaultMarkdownTemplate = "- [{{.CnTitle}}]({{.Link}}) {{.Title}} · {{.FeedTitle}} {{.Hashtags}}\n"
+
+// loadSinks 按环境变量启用保存目标，Memos 始终排在第一位
+func loadSinks() []Sink {
+	var list []Sink
+	if apiURL := os.Getenv("MEMOS_CREATE_API"); apiURL != "" {
+		list = append(list, memos.Client{APIURL: apiURL, Token: os.Getenv("MEMO_API_TOKEN")})
+	}
+	if baseURL := os.Getenv("LINKDING_URL"); baseURL != "" {
+		list = append(list, linkdingSink{baseURL: strings.TrimSuffix(baseURL, "/"), token: os.Getenv("LINKDING_TOKEN")})
+	}
+	if baseURL := os.Getenv("WALLABAG_URL"); baseURL != "" {
+		list = append(list, &wallabagSink{
+			baseURL:      strings.TrimSuffix(baseURL, "/"),
+			clientID:     os.Getenv("WALLABAG_CLIENT_ID"),
+			clientSecret: os.Getenv("WALLABAG_CLIENT_SECRET"),
+			username:     os.Getenv("WALLABAG_USERNAME"),
+			password:     os.Getenv("WALLABAG_PASSWORD"),
+		})
+	}
+	if path := os.Getenv("MARKDOWN_VAULT_FILE"); path != "" {
+		text := os.Getenv("MARKDOWN_TEMPLATE")
+		if text == "" {
+			text = defaultMarkdownTemplate
+		}
+		tmpl, err := template.New("markdown").Funcs(template.FuncMap{"join": strings.Join}).Parse(text)
+		if err != nil {
+			logger.Println("Invalid MARKDOWN_TEMPLATE, fallback to default:", err)
+			tmpl = template.Must(template.New("markdown").Parse(defaultMarkdownTemplate))
+		}
+		list = append(list, &markdownSink{path: path, tmpl: tmpl})
+	}
+	if webhookURL := os.Getenv("WEBHOOK_URL"); webhookURL != "" {
+		list = append(list, webhookSink{url: webhookURL, secret: os.Getenv("WEBHOOK_SECRET")})
+	}
+	return list
+}
+
+func getSink(name string) (Sink, error) {
+	for _, sink := range sinks {
+		if sink.Name() == name {
+			return sink, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", errUnknownSink, name)
+}
+
+// sendJSON 发送 JSON 请求并解析 JSON 响应，非 2xx 时返回错误
+func sendJSON(req *http.Request, out interface{}) error {
+	req.Header.Set("Accept", "application/json")
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("non-2xx response: %d %s", resp.StatusCode, string(body))
+	}
+	if out != nil && len(body) > 0 {
+		if err := json.Unmarshal(body, out); err != nil {
+			return fmt.Errorf("%w: %v", errInvalidResponse, err)
+		}
+	}
+	return nil
+}
+
+// linkdingSink 使用 Linkding 兼容的书签 API
+type linkdingSink struct {
+	baseURL string
+	token   string
+}
+
+func (s linkdingSink) Name() string {
+	return "linkding"
+}
+
+func (s linkdingSink) Save(item store.PostItem) (string, error) {
+	data, err := memos.DataFor(item)
+	if err != nil {
+		return "", err
+	}
+
+	reqData, _ := json.Marshal(map[string]interface{}{
+		"url":         data.Link,
+		"title":       data.Title,
+		"description": data.CnTitle,
+		"tag_names":   data.Tags,
+	})
+	req, err := http.NewRequest("POST", s.baseURL+"/api/bookmarks/", bytes.NewBuffer(reqData))
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", fmt.Sprintf("Token %s", s.token))
+
+	var respData struct {
+		ID int64 `json:"id"`
+	}
+	if err := sendJSON(req, &respData); err != nil {
+		return "", fmt.Errorf("linkding: %w", err)
+	}
+	return strconv.FormatInt(respData.ID, 10), nil
+}
+
+// wallabagSink 使用 Wallabag 兼容的 API，access token 过期前复用
+type wallabagSink struct {
+	baseURL      string
+	clientID     string
+	clientSecret string
+	username     string
+	password     string
+
+	mu          sync.Mutex
+	accessToken string
+	expiresAt   time.Time
+}
+
+func (s *wallabagSink) Name() string {
+	return "wallabag"
+}
+
+func (s *wallabagSink) token() (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.accessToken != "" && time.Now().Before(s.expiresAt) {
+		return s.accessToken, nil
+	}
+
+	form := url.Values{
+		"grant_type":    {"password"},
+		"client_id":     {s.clientID},
+		"client_secret": {s.clientSecret},
+		"username":      {s.username},
+		"password":      {s.password},
+	}
+	req, err := http.NewRequest("POST", s.baseURL+"/oauth/v2/token", strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	var respData struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := sendJSON(req, &respData); err != nil {
+		return "", fmt.Errorf("wallabag auth: %w", err)
+	}
+	s.accessToken = respData.AccessToken
+	// 提前一分钟刷新
+	s.expiresAt = time.Now().Add(time.Duration(respData.ExpiresIn-60) * time.Second)
+	return s.accessToken, nil
+}
+
+func (s *wallabagSink) Save(item store.PostItem) (string, error) {
+	data, err := memos.DataFor(item)
+	if err != nil {
+		return "", err
+	}
+	accessToken, err := s.token()
+	if err != nil {
+		return "", err
+	}
+
+	reqData, _ := json.Marshal(map[string]interface{}{
+		"url":   data.Link,
+		"title": data.Title,
+		"tags":  strings.Join(data.Tags, ","),
+	})
+	req, err := http.NewRequest("POST", s.baseURL+"/api/entries.json", bytes.NewBuffer(reqData))
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", accessToken))
+
+	var respData struct {
+		ID int64 `json:"id"`
+	}
+	if err := sendJSON(req, &respData); err != nil {
+		return "", fmt.Errorf("wallabag: %w", err)
+	}
+	return strconv.FormatInt(respData.ID, 10), nil
+}
+
+// markdownSink 把条目追加到本地 Markdown / Obsidian 笔记文件，ID 为 "文件:行号"
+type markdownSink struct {
+	path string
+	tmpl *template.Template
+	mu   sync.Mutex
+}
+
+func (s *markdownSink) Name() string {
+	return "markdown"
+}
+
+func (s *markdownSink) Save(item store.PostItem) (string, error) {
+	data, err := memos.DataFor(item)
+	if err != nil {
+		return "", err
+	}
+	var sb strings.Builder
+	if err := s.tmpl.Execute(&sb, data); err != nil {
+		return "", fmt.Errorf("failed to render markdown: %w", err)
+	}
+	entry := sb.String()
+	if !strings.HasSuffix(entry, "\n") {
+		entry += "\n"
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return "", fmt.Errorf("failed to create vault directory: %w", err)
+	}
+	existing, err := os.ReadFile(s.path)
+	if err != nil && !os.IsNotExist(err) {
+		return "", fmt.Errorf("failed to read vault file: %w", err)
+	}
+	if len(existing) > 0 && !bytes.HasSuffix(existing, []byte("\n")) {
+		entry = "\n" + entry
+	}
+	line := bytes.Count(existing, []byte("\n")) + 1
+	if strings.HasPrefix(entry, "\n") {
+		line++
+	}
+
+	f, err := os.OpenFile(s.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return "", fmt.Errorf("failed to open vault file: %w", err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(entry); err != nil {
+		return "", fmt.Errorf("failed to write vault file: %w", err)
+	}
+	return fmt.Sprintf("%s:%d", filepath.Base(s.path), line), nil
+}
+
+// webhookSink 把条目以 JSON POST 到任意地址，响应中的 id 字段作为外部 ID
+type webhookSink struct {
+	url    string
+	secret string
+}
+
+func (s webhookSink) Name() string {
+	return "webhook"
+}
+
+func (s webhookSink) Save(item store.PostItem) (string, error) {
+	data, err := memos.DataFor(item)
+	if err != nil {
+		return "", err
+	}
+
+	reqData, _ := json.Marshal(map[string]interface{}{
+		"item_id":    data.PostItemID,
+		"post_id":    data.PostID,
+		"feed_title": data.FeedTitle,
+		"cn_title":   data.CnTitle,
+		"title":      data.Title,
+		"link":       data.Link,
+		"tags":       data.Tags,
+		"saved_at":   time.Now().Unix(),
+	})
+	req, err := http.NewRequest("POST", s.url, bytes.NewBuffer(reqData))
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.secret != "" {
+		req.Header.Set("X-Shin-Secret", s.secret)
+	}
+
+	var respData struct {
+		ID interface{} `json:"id"`
+	}
+	if err := sendJSON(req, &respData); err != nil {
+		// 响应不是 JSON 时也视为成功
+		if !errors.Is(err, errInvalidResponse) {
+			return "", fmt.Errorf("webhook: %w", err)
+		}
+	}
+	if respData.ID == nil {
+		return "", nil
+	}
+	return fmt.Sprint(respData.ID), nil
+}
+
+// AppendToSaved 在已保存的条目上追加内容，例如给 memo 添加评论
+func AppendToSaved(postItemID, sinkName, text string) (string, error) {
+	sink, err := getSink(sinkName)
+	if err != nil {
+		return "", err
+	}
+	appender, ok := sink.(Appender)
+	if !ok {
+		return "", fmt.Errorf("%w: %s", errUnsupported, sinkName)
+	}
+	externalID, err := st.GetSavedID(postItemID, sinkName)
+	if err != nil {
+		return "", err
+	}
+	return appender.Append(externalID, text)
+}
+
+// UnsaveItem 从目标中删除条目（目标支持时）并清除保存记录
+func UnsaveItem(postItemID, sinkName string) error {
+	sink, err := getSink(sinkName)
+	if err != nil {
+		return err
+	}
+	externalID, err := st.GetSavedID(postItemID, sinkName)
+	if err != nil {
+		return err
+	}
+	if deleter, ok := sink.(Deleter); ok {
+		if err := deleter.Delete(externalID); err != nil {
+			logger.Println("UnsaveItem:", sinkName, postItemID, err)
+			return err
+		}
+	}
+	return st.DeleteSaved(postItemID, sinkName)
+}
+
+func saveErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, store.ErrNotFound), errors.Is(err, store.ErrNotSaved):
+		return http.StatusNotFound
+	case errors.Is(err, errUnknownSink), errors.Is(err, errUnsupported):
+		return http.StatusBadRequest
+	default:
+		return http.StatusBadGateway
+	}
+}
+
+func getSinks(c *gin.Context) {
+	names := []string{}
+	for _, sink := range sinks {
+		names = append(names, sink.Name())
+	}
+	c.JSON(http.StatusOK, names)
+}
+
+func saveItem(c *gin.Context) {
+	var input struct {
+		PostItemID string `json:"postItemID"`
+		Sink       string `json:"sink"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	externalID, queued, err := EnqueueSave(input.PostItemID, input.Sink, "")
+	if err != nil {
+		c.JSON(saveErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	if !queued {
+		c.JSON(http.StatusOK, gin.H{"message": "Already saved to " + input.Sink, "external_id": externalID})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"message": "Queued for " + input.Sink})
+}
+
+func unsaveItem(c *gin.Context) {
+	var input struct {
+		PostItemID string `json:"postItemID"`
+		Sink       string `json:"sink"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := UnsaveItem(input.PostItemID, input.Sink); err != nil {
+		c.JSON(saveErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Removed from " + input.Sink})
+}
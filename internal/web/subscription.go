@@ -0,0 +1,178 @@
+package web
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"shin/internal/backup"
+	"shin/internal/ingest"
+	"shin/internal/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+// getSubscriptions 返回 shin_feed 中的全部订阅，按分类和标题排序
+func getSubscriptions(c *gin.Context) {
+	feeds, err := st.ListFeeds()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, feeds)
+}
+
+// validTranslate 检查翻译策略，为空时使用默认的翻译标题
+func validTranslate(translate string) (string, bool) {
+	switch translate {
+	case "":
+		return store.TranslateTitle, true
+	case store.TranslateTitle, store.TranslateNone:
+		return translate, true
+	}
+	return "", false
+}
+
+func addSubscription(c *gin.Context) {
+	var input struct {
+		URL       string `json:"url"`
+		Title     string `json:"title"`
+		Category  string `json:"category"`
+		Translate string `json:"translate"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	input.URL = strings.TrimSpace(input.URL)
+	if u, err := url.Parse(input.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "url must be an http or https address"})
+		return
+	}
+	translate, ok := validTranslate(input.Translate)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "translate must be translate or none"})
+		return
+	}
+	title := strings.TrimSpace(input.Title)
+	if title == "" {
+		title = input.URL
+	}
+
+	feed := store.Feed{
+		ID:        store.NewID(),
+		URL:       input.URL,
+		Title:     title,
+		Category:  strings.TrimSpace(input.Category),
+		Enabled:   true,
+		Translate: translate,
+	}
+	created, err := st.AddFeed(feed)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if !created {
+		c.JSON(http.StatusConflict, gin.H{"error": "Feed already exists"})
+		return
+	}
+
+	added, err := st.GetFeed(feed.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, added)
+}
+
+// updateSubscription 修改标题、分类、开关和翻译策略，URL 不可修改
+func updateSubscription(c *gin.Context) {
+	var input struct {
+		ID        string `json:"id"`
+		Title     string `json:"title"`
+		Category  string `json:"category"`
+		Enabled   bool   `json:"enabled"`
+		Translate string `json:"translate"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	translate, ok := validTranslate(input.Translate)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "translate must be translate or none"})
+		return
+	}
+
+	feed, err := st.GetFeed(input.ID)
+	if err != nil {
+		c.JSON(rowErrorStatus(err), gin.H{"error": "Feed not found"})
+		return
+	}
+	if title := strings.TrimSpace(input.Title); title != "" {
+		feed.Title = title
+	}
+	feed.Category = strings.TrimSpace(input.Category)
+	feed.Enabled = input.Enabled
+	feed.Translate = translate
+	if err := st.UpdateFeed(*feed); err != nil {
+		c.JSON(rowErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, feed)
+}
+
+func deleteSubscription(c *gin.Context) {
+	var input struct {
+		ID string `json:"id"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := st.DeleteFeed(input.ID); err != nil {
+		c.JSON(rowErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Feed deleted"})
+}
+
+// importOPML 导入 OPML 订阅列表，请求体就是文件内容
+func importOPML(c *gin.Context) {
+	added, total, err := backup.ImportOPML(st, c.Request.Body)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, backup.ErrInvalidExport) {
+			status = http.StatusBadRequest
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Imported", "added": added, "total": total})
+}
+
+// syncSubscriptions 把 FreshRSS 的订阅同步到 shin_feed，已删除的订阅会重新加入
+func syncSubscriptions(c *gin.Context) {
+	syncer, ok := source.(ingest.FeedSyncer)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Source does not support syncing subscriptions"})
+		return
+	}
+
+	added, err := syncer.SyncFeeds(st, source.Auth(), true)
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Synced", "added": added})
+}
@@ -0,0 +1,124 @@
+package web
+
+import (
+	"errors"
+	"net/http"
+
+	"shin/internal/ingest"
+	"shin/internal/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+func getTags(c *gin.Context) {
+	tags, err := st.TagCounts()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to execute query"})
+		return
+	}
+
+	c.JSON(http.StatusOK, tags)
+}
+
+type itemTagRequest struct {
+	ItemID string `json:"item_id"`
+	Tag    string `json:"tag"`
+}
+
+func bindItemTagRequest(c *gin.Context) (itemTagRequest, bool) {
+	var input itemTagRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return input, false
+	}
+	tag, err := ingest.NormalizeTag(input.Tag)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return input, false
+	}
+	input.Tag = tag
+	return input, true
+}
+
+func addItemTagHandler(c *gin.Context) {
+	input, ok := bindItemTagRequest(c)
+	if !ok {
+		return
+	}
+	if _, err := st.GetPostItem(input.ItemID); err != nil {
+		c.JSON(rowErrorStatus(err), gin.H{"error": "Item not found"})
+		return
+	}
+
+	if err := st.AddItemTag(input.ItemID, input.Tag); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Tag added"})
+}
+
+func removeItemTagHandler(c *gin.Context) {
+	input, ok := bindItemTagRequest(c)
+	if !ok {
+		return
+	}
+
+	if err := st.RemoveItemTag(input.ItemID, input.Tag); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Tag removed"})
+}
+
+func queryTaggedItems(tag string, page store.Page) ([]store.PostItem, store.PageInfo, error) {
+	return st.ItemsByTag(tag, page)
+}
+
+// getTaggedItems 返回所有摘要中带有该标签的条目，格式与 /getImportant 相同
+func getTaggedItems(c *gin.Context) {
+	page, err := parsePage(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	items, info, err := queryTaggedItems(c.Query("tag"), page)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to execute query"})
+		logger.Println("getTaggedItems:", err)
+		return
+	}
+	if err := attachNotes(items); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load notes"})
+		return
+	}
+
+	c.JSON(http.StatusOK, pageResponse(items, info))
+}
+
+func getTagRules(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"rules": ingest.GetTagRules(st)})
+}
+
+func updateTagRules(c *gin.Context) {
+	var input struct {
+		Rules []ingest.TagRule `json:"rules"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := ingest.SaveTagRules(st, input.Rules); err != nil {
+		if errors.Is(err, ingest.ErrInvalidRule) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error saving rules"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Rules updated"})
+}
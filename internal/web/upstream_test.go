@@ -0,0 +1,179 @@
+package web
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"shin/internal/ingest"
+	"shin/internal/memos"
+	"shin/internal/translate"
+)
+
+// freshRSSStub 模拟 FreshRSS 的登录、订阅列表和条目内容接口，items 按订阅 ID 存放 Google Reader 格式的条目
+type freshRSSStub struct {
+	mu            sync.Mutex
+	subscriptions []map[string]interface{}
+	items         map[string][]map[string]interface{}
+	nextItem      int
+}
+
+func startFreshRSSStub(t *testing.T) (*freshRSSStub, *ingest.FreshRSS) {
+	t.Helper()
+	f := &freshRSSStub{items: map[string][]map[string]interface{}{}}
+	server := httptest.NewServer(f)
+	t.Cleanup(server.Close)
+	api := server.URL + "/api/greader.php"
+	return f, &ingest.FreshRSS{
+		AuthURL:          api + "/accounts/ClientLogin?Email=u&Passwd=p",
+		ListURL:          api + "/reader/api/0/subscription/list?output=json",
+		ContentURLPrefix: api + "/reader/api/0/stream/contents/",
+		Client:           server.Client(),
+	}
+}
+
+// addFeed 添加订阅和它的条目，条目的 published 和 crawlTimeMsec 取 published（Unix 秒）
+func (f *freshRSSStub) addFeed(id, title string, published int64, entries ...[2]string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.subscriptions = append(f.subscriptions, map[string]interface{}{
+		"id":         id,
+		"title":      title,
+		"url":        "https://" + title + ".example.com/feed",
+		"categories": []interface{}{map[string]interface{}{"id": "user/-/label/News", "label": "News"}},
+	})
+	for _, entry := range entries {
+		f.nextItem++
+		f.items[id] = append(f.items[id], map[string]interface{}{
+			"id":            fmt.Sprintf("tag:google.com,2005:reader/item/%016x", f.nextItem),
+			"title":         entry[0],
+			"canonical":     []interface{}{map[string]interface{}{"href": entry[1]}},
+			"published":     float64(published),
+			"crawlTimeMsec": strconv.FormatInt(published*1000, 10),
+		})
+	}
+}
+
+func (f *freshRSSStub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	path := strings.TrimPrefix(r.URL.Path, "/api/greader.php")
+	if path == "/accounts/ClientLogin" {
+		fmt.Fprint(w, "SID=u/sid\nLSID=\nAuth=u/sid\n")
+		return
+	}
+	if r.Header.Get("Authorization") != "GoogleLogin auth=u/sid" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	switch {
+	case path == "/reader/api/0/subscription/list":
+		json.NewEncoder(w).Encode(map[string]interface{}{"subscriptions": f.subscriptions})
+	case strings.HasPrefix(path, "/reader/api/0/stream/contents/"):
+		feedID := strings.TrimPrefix(path, "/reader/api/0/stream/contents/")
+		ot, _ := strconv.ParseInt(r.URL.Query().Get("ot"), 10, 64)
+		items := []map[string]interface{}{}
+		for _, item := range f.items[feedID] {
+			if int64(item["published"].(float64)) >= ot {
+				items = append(items, item)
+			}
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"items": items})
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// startTranslateStub 模拟 Google 翻译的免费接口，把文本翻译成 "译:" 加原文
+func startTranslateStub(t *testing.T) *translate.Google {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if q.Get("client") != "gtx" || q.Get("tl") != "zh" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode([]interface{}{[]interface{}{[]interface{}{"译:" + q.Get("q"), q.Get("q")}}})
+	}))
+	t.Cleanup(server.Close)
+	return &translate.Google{BaseURL: server.URL + "/translate_a/single", Client: server.Client()}
+}
+
+// memosStub 模拟 Memos 的创建、评论和删除接口，memos 按资源名记录内容
+type memosStub struct {
+	mu       sync.Mutex
+	memos    map[string]string
+	comments map[string][]string
+	nextID   int
+}
+
+func startMemosStub(t *testing.T) (*memosStub, memos.Client) {
+	t.Helper()
+	t.Setenv("MEMOS_API_URL", "")
+	m := &memosStub{memos: map[string]string{}, comments: map[string][]string{}}
+	server := httptest.NewServer(m)
+	t.Cleanup(server.Close)
+	return m, memos.Client{APIURL: server.URL + "/api/v1/memos", Token: "memos-token"}
+}
+
+func (m *memosStub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if r.Header.Get("Authorization") != "Bearer memos-token" {
+		http.Error(w, `{"message": "unauthenticated"}`, http.StatusUnauthorized)
+		return
+	}
+	var req memos.Request
+	json.NewDecoder(r.Body).Decode(&req)
+	name := strings.TrimPrefix(r.URL.Path, "/api/v1/")
+	switch {
+	case r.Method == http.MethodPost && name == "memos":
+		m.nextID++
+		name = "memos/" + strconv.Itoa(m.nextID)
+		m.memos[name] = req.Content
+		json.NewEncoder(w).Encode(map[string]string{"name": name})
+	case r.Method == http.MethodPost && strings.HasSuffix(name, "/comments"):
+		name = strings.TrimSuffix(name, "/comments")
+		if _, ok := m.memos[name]; !ok {
+			http.NotFound(w, r)
+			return
+		}
+		m.nextID++
+		m.comments[name] = append(m.comments[name], req.Content)
+		json.NewEncoder(w).Encode(map[string]string{"name": "memos/" + strconv.Itoa(m.nextID)})
+	case r.Method == http.MethodDelete:
+		if _, ok := m.memos[name]; !ok {
+			http.NotFound(w, r)
+			return
+		}
+		delete(m.memos, name)
+		w.Write([]byte(`{}`))
+	default:
+		http.Error(w, "unexpected request", http.StatusBadRequest)
+	}
+}
+
+func (m *memosStub) contents() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var list []string
+	for _, content := range m.memos {
+		list = append(list, content)
+	}
+	return list
+}
+
+// useSource 替换处理函数使用的上游和 st 上的拉取流程，需要在 useTestStore 之后调用，测试结束后恢复
+func useSource(t *testing.T, src ingest.Source) {
+	t.Helper()
+	oldSource, oldFreshRSS, oldIngester := source, freshrss, ingester
+	Setup(st, src, ingest.New(st, src, nil))
+	t.Cleanup(func() { source, freshrss, ingester = oldSource, oldFreshRSS, oldIngester })
+}
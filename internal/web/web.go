@@ -0,0 +1,429 @@
+// Package web 是 Shin 的页面和 HTTP 接口，所有数据通过 store.Store 访问
+package web
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"shin/internal/config"
+	"shin/internal/ingest"
+	"shin/internal/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	logger          = config.Logger
+	authToken       = os.Getenv("AUTH_TOKEN")
+	IMPORTANT_FEEDS = os.Getenv("IMPORTANT_FEEDS")
+	httpClient      = &http.Client{Timeout: 60 * time.Second}
+
+	// 由 Setup 设置
+	st     store.Store
+	source ingest.Source
+	// source 是 FreshRSS 时才能同步已读和星标
+	freshrss *ingest.FreshRSS
+	// 改写规则试运行和拉取流程使用同一份订阅列表
+	ingester *ingest.Ingester
+)
+
+// Setup 设置处理函数和后台任务使用的存储、上游和拉取流程，需要在 StartTasks 和 Router 之前调用
+func Setup(s store.Store, src ingest.Source, in *ingest.Ingester) {
+	st = s
+	source = src
+	freshrss, _ = src.(*ingest.FreshRSS)
+	ingester = in
+}
+
+// AfterInsert 是拉取流程的入库回调：排队通知，开启时把条目交给后台抽取正文
+func AfterInsert(items []store.PostItem) {
+	queueNotifications(items)
+	if articleExtractEnabled {
+		queueExtraction(items)
+	}
+}
+
+// AfterRound 是每轮拉取后的回调：拉取 FreshRSS 的状态，没有配置 DIGEST_SCHEDULE 时有更新就生成一期摘要
+func AfterRound(authToken string, items []store.PostItem) {
+	if freshrssSyncEnabled && freshrss != nil {
+		if err := freshrss.PullState(st, authToken); err != nil {
+			logger.Println("pullUpstreamState:", err)
+		}
+	}
+	if len(items) > 0 && digestSchedule == nil {
+		if _, err := cutDigest(); err != nil {
+			logger.Println("cutDigest:", err)
+		}
+	}
+}
+
+// StartTasks 启动摘要、投递、正文抽取和通知的后台任务
+func StartTasks() error {
+	if digestScheduleErr != nil {
+		return fmt.Errorf("invalid DIGEST_SCHEDULE: %w", digestScheduleErr)
+	}
+	if digestSchedule != nil {
+		go DigestTask()
+	}
+	if retentionScheduleErr != nil {
+		return fmt.Errorf("invalid RETENTION_SCHEDULE: %w", retentionScheduleErr)
+	}
+	if retentionPolicy.Enabled() {
+		go RetentionTask()
+	}
+	go OutboxTask()
+	if articleExtractEnabled {
+		ExtractTask()
+	}
+	if len(notifiers) > 0 {
+		go NotifyTask()
+	}
+	return nil
+}
+
+// 鉴权中间件
+func authMiddleware(c *gin.Context) {
+	if c.Request.URL.Path == "/login_page" || c.Request.URL.Path == "/login" {
+		c.Next() // 继续处理，不拦截
+		return
+	}
+
+	// 订阅地址用 URL 中的 token 鉴权，由各自的处理函数校验
+	if strings.HasPrefix(c.Request.URL.Path, "/feeds/") {
+		c.Next()
+		return
+	}
+
+	// API 也接受 Authorization: Bearer，未登录时返回 JSON 而不是跳转
+	if strings.HasPrefix(c.Request.URL.Path, "/api/") {
+		token, err := c.Cookie("token")
+		if bearer, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer "); ok {
+			token, err = bearer, nil
+		}
+		if err != nil || subtle.ConstantTimeCompare([]byte(token), []byte(authToken)) != 1 {
+			apiAbort(c, http.StatusUnauthorized, "unauthorized", "missing or invalid token")
+			return
+		}
+		c.Next()
+		return
+	}
+
+	// 获取 cookie 中的 token
+	token, err := c.Cookie("token")
+	if err != nil {
+		// 如果 token 不存在或无效，重定向到登录页面
+		c.Redirect(http.StatusFound, "/login_page")
+		c.Abort() // 终止请求
+		return
+	}
+
+	// 验证 token
+	if token != authToken {
+		logger.Println("no token in cookie")
+		c.Redirect(http.StatusFound, "/login_page")
+		c.Abort()
+		return
+	}
+
+	// 继续处理请求
+	c.Next()
+}
+
+// postDetail 是 getDetail 的响应，在摘要上附加聚类
+type postDetail struct {
+	store.Post
+	Clusters []ItemCluster `json:"clusters,omitempty"`
+}
+
+func getPostItemsGroupedByFeedTitle(postID string) (map[string][]store.PostItem, error) {
+	items, err := st.PostItems(postID)
+	if err != nil {
+		return nil, err
+	}
+	if err := attachNotes(items); err != nil {
+		return nil, err
+	}
+
+	// 用于存储分组结果
+	groupedItems := make(map[string][]store.PostItem)
+
+	for _, item := range items {
+		// 将 content 字符串解析为 JSON 对象
+		var content store.PostItemContent
+		if err := json.Unmarshal([]byte(item.Content), &content); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal content: %w", err)
+		}
+
+		// 按 feed_title 分组
+		groupedItems[item.FeedTitle] = append(groupedItems[item.FeedTitle], item)
+	}
+
+	return groupedItems, nil
+}
+
+func getGroupedPostItemsAsJSON(postID string) (string, error) {
+	// 获取按 feed_title 分组的内容
+	groupedItems, err := getPostItemsGroupedByFeedTitle(postID)
+	if err != nil {
+		return "", err
+	}
+
+	// 将分组后的数据转换为 JSON 字符串
+	jsonBytes, err := json.Marshal(groupedItems)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal grouped items to JSON: %w", err)
+	}
+
+	return string(jsonBytes), nil
+}
+
+// MarkPostRead 标记一期摘要及其条目为已读，并同步到 FreshRSS
+func MarkPostRead(postID string) error {
+	upstreamIDs, err := st.MarkPostRead(postID)
+	if err != nil {
+		return err
+	}
+	syncUpstream(upstreamIDs, ingest.StateRead, "")
+	return nil
+}
+
+func markRead(c *gin.Context) {
+	var input struct {
+		PostID string `json:"post_id"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := MarkPostRead(input.PostID); err != nil {
+		c.JSON(rowErrorStatus(err), gin.H{"error": "Error updating post"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Post marked as read"})
+}
+
+func getDetail(c *gin.Context) {
+	postID := c.Query("id")
+	logger.Println("getDetail: ", postID)
+	post, err := st.GetPost(postID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Post not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching post"})
+		}
+		return
+	}
+
+	content, _ := getGroupedPostItemsAsJSON(postID)
+	post.Content = content
+	detail := postDetail{Post: *post}
+	if clusterEnabled {
+		clusters, err := getPostClusters(*post)
+		if err != nil {
+			logger.Println("getPostClusters:", err)
+		}
+		detail.Clusters = clusters
+	}
+	c.JSON(http.StatusOK, detail)
+}
+
+// pagePost handler
+func pagePost(c *gin.Context) {
+	page, err := parsePage(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	posts, info, err := st.ListPosts(page)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching posts"})
+		return
+	}
+
+	c.JSON(http.StatusOK, pageResponse(posts, info))
+}
+
+// SearchItems 通过全文索引匹配标题、正文和笔记，新的在前
+func SearchItems(keyword string, page store.Page) ([]store.PostItem, store.PageInfo, error) {
+	items, info, err := st.SearchItems(keyword, page)
+	if err != nil {
+		return nil, store.PageInfo{}, err
+	}
+	if err := attachNotes(items); err != nil {
+		return nil, store.PageInfo{}, err
+	}
+	return items, info, nil
+}
+
+func searchPostItems(c *gin.Context) {
+	page, err := parsePage(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	keyword := c.Query("keyword")
+	logger.Println("search keyword:", keyword)
+	postItems, info, err := SearchItems(keyword, page)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to execute query"})
+		return
+	}
+
+	c.JSON(http.StatusOK, pageResponse(postItems, info))
+}
+
+// queryImportantItems 返回 IMPORTANT_FEEDS 中订阅的最新条目
+func queryImportantItems(page store.Page) ([]store.PostItem, store.PageInfo, error) {
+	return st.ItemsByFeeds(importantFeedTitles(), page)
+}
+
+func getImportantFeeds(c *gin.Context) {
+	page, err := parsePage(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	postItems, info, err := queryImportantItems(page)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to execute query"})
+		logger.Println("Failed to execute query:", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, pageResponse(postItems, info))
+}
+
+func processLogin(c *gin.Context) {
+	var input struct {
+		Token string `form:"token"`
+	}
+
+	if err := c.ShouldBind(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	logger.Printf("clientToken: %s envToken: %s", input.Token, authToken)
+
+	// 验证 token
+	if input.Token == authToken {
+		// 设置客户端的 token
+		c.SetCookie("token", input.Token, 3600*24*365, "/", "", false, true)
+
+		// 登录成功，重定向到首页
+		c.Redirect(http.StatusFound, "/home")
+	} else {
+		// token 不匹配，返回登录页面
+		c.HTML(http.StatusOK, "login.html", gin.H{
+			"error": "Invalid token",
+		})
+	}
+}
+
+// Router 返回注册了页面、旧接口和 /api/v1 的路由
+func Router() *gin.Engine {
+	r := gin.Default()
+
+	// 应用认证中间件到所有路由
+	r.Use(authMiddleware)
+
+	// Serve static files (CSS, JS, images, etc.)
+	r.Static("/static", "./static")
+
+	r.LoadHTMLGlob("templates/*")
+
+	r.GET("/login_page", func(c *gin.Context) {
+		c.HTML(http.StatusOK, "login.html", gin.H{})
+	})
+
+	r.GET("/", func(c *gin.Context) {
+		c.HTML(http.StatusOK, "home.html", gin.H{})
+	})
+
+	r.GET("/home", func(c *gin.Context) {
+		c.HTML(http.StatusOK, "home.html", gin.H{})
+	})
+
+	r.GET("/detail", func(c *gin.Context) {
+		c.HTML(http.StatusOK, "detail.html", gin.H{})
+	})
+
+	r.GET("/tools", func(c *gin.Context) {
+		c.HTML(http.StatusOK, "tools.html", gin.H{})
+	})
+
+	r.GET("/reader", func(c *gin.Context) {
+		c.HTML(http.StatusOK, "reader.html", gin.H{})
+	})
+
+	// 标签名可以带 /
+	r.GET("/tag/*name", func(c *gin.Context) {
+		c.HTML(http.StatusOK, "tag.html", gin.H{"Tag": strings.TrimPrefix(c.Param("name"), "/")})
+	})
+
+	r.GET("/subscriptions", func(c *gin.Context) {
+		c.HTML(http.StatusOK, "subscriptions.html", gin.H{})
+	})
+
+	// REST API routes
+	r.POST("/login", processLogin)
+	r.POST("/markRead", markRead)
+	r.GET("/pagePost", pagePost)
+	r.GET("/getDetail", getDetail)
+	r.POST("/createMemo", CreateMemo)
+	r.GET("/search", searchPostItems)
+	r.GET("/getImportant", getImportantFeeds)
+	r.GET("/getRewriteRules", getRewriteRules)
+	r.POST("/updateRewriteRules", updateRewriteRules)
+	r.POST("/dryRunRewriteRule", dryRunRewriteRule)
+	r.GET("/getArticle", getArticle)
+	r.POST("/summarizePost", resummarizePost)
+	r.GET("/getSinks", getSinks)
+	r.POST("/saveItem", saveItem)
+	r.POST("/unsaveItem", unsaveItem)
+	r.POST("/updateMemo", UpdateMemo)
+	r.POST("/deleteMemo", DeleteMemo)
+	r.GET("/getOutbox", getOutbox)
+	r.GET("/getNotes", getNotes)
+	r.POST("/createNote", createNote)
+	r.POST("/updateNote", updateNote)
+	r.POST("/deleteNote", deleteNote)
+	r.GET("/getTags", getTags)
+	r.GET("/getTaggedItems", getTaggedItems)
+	r.POST("/addItemTag", addItemTagHandler)
+	r.POST("/removeItemTag", removeItemTagHandler)
+	r.GET("/getTagRules", getTagRules)
+	r.POST("/updateTagRules", updateTagRules)
+	r.GET("/feeds/digests.atom", digestsFeed)
+	r.GET("/feeds/important.atom", importantFeed)
+	r.GET("/feeds/tag/*name", tagFeed)
+	r.GET("/getFeedURLs", getFeedURLs)
+	r.POST("/rotateFeedToken", rotateFeedToken)
+	r.GET("/save", confirmSaveFromLink)
+	r.POST("/save", saveFromLink)
+	r.POST("/sendDigestEmail", resendDigestEmail)
+	r.POST("/markItemRead", markItemRead)
+	r.POST("/starItem", starItem)
+	r.GET("/export", exportData)
+	r.GET("/exportOPML", exportOPML)
+	r.POST("/import", importData)
+	r.GET("/getSubscriptions", getSubscriptions)
+	r.POST("/addSubscription", addSubscription)
+	r.POST("/updateSubscription", updateSubscription)
+	r.POST("/deleteSubscription", deleteSubscription)
+	r.POST("/importOPML", importOPML)
+	r.POST("/syncSubscriptions", syncSubscriptions)
+	registerAPI(r)
+	return r
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"shin/internal/retention"
+	"shin/internal/store"
+)
+
+// runPrune 按 RETENTION_* 配置清理旧摘要，--dry-run 只报告将要清理的内容
+// 用法: shin prune [--dry-run] [-days N]
+func runPrune(args []string) error {
+	policy := retention.PolicyFromEnv()
+	fs := flag.NewFlagSet("prune", flag.ExitOnError)
+	dryRun := fs.Bool("dry-run", false, "report what would be removed without changing anything")
+	fs.IntVar(&policy.Days, "days", policy.Days, "keep posts newer than this many days (RETENTION_DAYS)")
+	fs.Parse(args)
+
+	st, err := store.Open(DB_PATH)
+	if err != nil {
+		return err
+	}
+	defer st.Close()
+
+	report, err := retention.Run(st, policy, *dryRun)
+	if err != nil {
+		return err
+	}
+	fmt.Println(report)
+	return nil
+}
@@ -1,481 +1,66 @@
 package main
 
 import (
-	"database/sql"
-	"encoding/json"
+	"crypto/tls"
 	"fmt"
 	"net/http"
 	"os"
-	"strconv"
-	"strings"
-	"time"
+	_ "time/tzdata"
 
-	"github.com/gin-gonic/gin"
-	_ "modernc.org/sqlite" // SQLite driver
+	"shin/internal/ingest"
+	"shin/internal/store"
+	"shin/internal/translate"
+	"shin/internal/web"
 )
 
-type Post struct {
-	ID        string `json:"id"`
-	Title     string `json:"title"`
-	Content   string `json:"content"`
-	CreatedAt string `json:"created_at"`
-	ReadAt    string `json:"read_at"`
-}
-
-type PostItem struct {
-	ID        string `json:"id"`
-	PostID    string `json:"post_id"`
-	FeedTitle string `json:"feed_title"`
-	Content   string `json:"content"`
-	MemoID    string `json:"memo_id"`
-}
-
-type PostItemContent struct {
-	CnTitle string `json:"cnTitle"`
-	Title   string `json:"title"`
-	Link    string `json:"link"`
-}
-
-type KeyValue struct {
-	ID        string `json:"id"`
-	Key       string `json:"key"`
-	Value     string `json:"value"`
-	CreatedAt string `json:"created_at"`
-}
-
-var db *sql.DB
-var authToken string
-
-func initDB() {
-	authToken = os.Getenv("AUTH_TOKEN")
-	var err error
-	db, err = sql.Open("sqlite", DB_PATH)
-	if err != nil {
-		panic("failed to connect database")
-	}
-
-	// Create the table if it doesn't exist
-	createPostTableSQL := `CREATE TABLE IF NOT EXISTS shin_post (
-		id TEXT PRIMARY KEY,
-		title TEXT,
-		created_at TEXT,
-		read_at TEXT
-	);`
-	if _, err := db.Exec(createPostTableSQL); err != nil {
-		panic("failed to create shin_post")
-	}
-
-	createPostItemTableSQL := `CREATE TABLE IF NOT EXISTS shin_post_item (
-		id TEXT PRIMARY KEY,
-		post_id TEXT,
-		feed_title TEXT,
-		content TEXT,
-		memo_id TEXT
-	);`
-	if _, err := db.Exec(createPostItemTableSQL); err != nil {
-		panic("failed to create shin_post_item")
-	}
-
-	createKeyValueTableSQL := `CREATE TABLE IF NOT EXISTS shin_key_value (
-		id TEXT PRIMARY KEY,
-		key TEXT,
-		value TEXT,
-		created_at TEXT
-	);`
-	if _, err := db.Exec(createKeyValueTableSQL); err != nil {
-		panic("failed to create shin_key_value")
-	}
-}
-func GetOtMap() map[string]string {
-	var otMapJSON string
-	err := db.QueryRow("SELECT value FROM shin_key_value WHERE key = ?", OT_MAP_KEY).Scan(&otMapJSON)
-	if err != nil || otMapJSON == "" {
-		logger.Println("GetOtMap:", err)
-		return make(map[string]string)
-	}
-
-	var otMap map[string]string
-	err = json.Unmarshal([]byte(otMapJSON), &otMap)
-	if err != nil {
-		logger.Println("Unmarshl otMap err:", err)
-		return make(map[string]string)
-	}
-	return otMap
-}
-func UpdateOtMap(otMap map[string]string) {
-	now := strconv.FormatInt(time.Now().Unix(), 10)
-	valueJSON, err := json.Marshal(otMap)
-	if err != nil {
-		logger.Println("valueJSON err:", err)
-		return
-	}
-	logger.Println("UpdateOtMap:", string(valueJSON))
-	db.Exec(`DELETE FROM shin_key_value WHERE key = 'otMap';`)
-	_, err = db.Exec(`INSERT INTO shin_key_value (id, key, value, created_at) VALUES (?, ?, ?, ?);`,
-		now, "otMap", string(valueJSON), now)
-	if err != nil {
-		logger.Println("failed to execute statement:", err)
-	}
-}
-
-// 鉴权中间件
-func authMiddleware(c *gin.Context) {
-	if c.Request.URL.Path == "/login_page" || c.Request.URL.Path == "/login" {
-		c.Next() // 继续处理，不拦截
-		return
-	}
-
-	// 获取 cookie 中的 token
-	token, err := c.Cookie("token")
-	if err != nil {
-		// 如果 token 不存在或无效，重定向到登录页面
-		c.Redirect(http.StatusFound, "/login_page")
-		c.Abort() // 终止请求
-		return
-	}
-
-	// 验证 token
-	if token != authToken {
-		logger.Println("no token in cookie")
-		c.Redirect(http.StatusFound, "/login_page")
-		c.Abort()
-		return
-	}
-
-	// 继续处理请求
-	c.Next()
-}
-
-func InsertPost(postID, title string) {
-	post := Post{
-		ID:        postID,
-		Title:     title,
-		CreatedAt: strconv.FormatInt(time.Now().Unix(), 10),
-		ReadAt:    "0", // initially unread
-	}
-
-	_, err := db.Exec("INSERT INTO shin_post (id, title, created_at, read_at) VALUES (?, ?, ?, ?)",
-		post.ID, post.Title, post.CreatedAt, post.ReadAt)
-	if err != nil {
-		panic(err) // Handle error appropriately in production
-	}
-}
-
-func InsertPostItems(items []PostItem) error {
-	// 启动事务
-	tx, err := db.Begin()
-	if err != nil {
-		return fmt.Errorf("failed to begin transaction: %w", err)
-	}
-
-	// 准备插入SQL
-	stmt, err := tx.Prepare(`INSERT INTO shin_post_item (id, post_id, feed_title, content, memo_id) VALUES (?, ?, ?, ?, ?)`)
-	if err != nil {
-		tx.Rollback()
-		return fmt.Errorf("failed to prepare insert statement: %w", err)
-	}
-	defer stmt.Close()
-
-	// 批量插入
-	for _, item := range items {
-		// TODO query before insert
-		_, err := stmt.Exec(item.ID, item.PostID, item.FeedTitle, item.Content, item.MemoID)
-		if err != nil {
-			tx.Rollback()
-			return fmt.Errorf("failed to execute insert statement: %w", err)
-		}
-	}
-
-	// 提交事务
-	if err := tx.Commit(); err != nil {
-		return fmt.Errorf("failed to commit transaction: %w", err)
-	}
-
-	return nil
-}
-
-func getPostItemsGroupedByFeedTitle(postID string) (map[string][]PostItem, error) {
-	// 查询 shin_post_item 表的所有记录
-	rows, err := db.Query(`SELECT id, feed_title, content, memo_id FROM shin_post_item WHERE post_id = ?`, postID)
-	if err != nil {
-		return nil, fmt.Errorf("failed to query post items: %w", err)
-	}
-	defer rows.Close()
-
-	// 用于存储分组结果
-	groupedItems := make(map[string][]PostItem)
-
-	for rows.Next() {
-		var postItemID string
-		var feedTitle string
-		var contentStr string
-		var memoID string
-		if err := rows.Scan(&postItemID, &feedTitle, &contentStr, &memoID); err != nil {
-			return nil, fmt.Errorf("failed to scan row: %w", err)
-		}
-
-		// 将 content 字符串解析为 JSON 对象
-		var content PostItemContent
-		if err := json.Unmarshal([]byte(contentStr), &content); err != nil {
-			return nil, fmt.Errorf("failed to unmarshal content: %w", err)
-		}
-
-		// 按 feed_title 分组
-		groupedItems[feedTitle] = append(groupedItems[feedTitle], PostItem{
-			ID:        postItemID,
-			PostID:    postID,
-			FeedTitle: feedTitle,
-			Content:   contentStr,
-			MemoID:    memoID,
-		})
-	}
-
-	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("error occurred during row iteration: %w", err)
-	}
-
-	return groupedItems, nil
-}
-
-func getGroupedPostItemsAsJSON(postID string) (string, error) {
-	// 获取按 feed_title 分组的内容
-	groupedItems, err := getPostItemsGroupedByFeedTitle(postID)
-	if err != nil {
-		return "", err
-	}
-
-	// 将分组后的数据转换为 JSON 字符串
-	jsonBytes, err := json.Marshal(groupedItems)
-	if err != nil {
-		return "", fmt.Errorf("failed to marshal grouped items to JSON: %w", err)
-	}
-
-	return string(jsonBytes), nil
-}
-
-func markRead(c *gin.Context) {
-	var input struct {
-		PostID string `json:"post_id"`
-	}
+var DB_PATH = "data/shin_v3.db"
 
-	if err := c.ShouldBindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
+func init() {
+	// DB_DSN 可以是 SQLite 文件路径，也可以是 postgres:// 连接串
+	if dsn := os.Getenv("DB_DSN"); dsn != "" {
+		DB_PATH = dsn
 	}
 
-	// 更新 read_at 字段
-	readAt := strconv.FormatInt(time.Now().Unix(), 10)
-	_, err := db.Exec("UPDATE shin_post SET read_at = ? WHERE id = ?", readAt, input.PostID)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error updating post"})
-		return
+	// 设置全局的 http.DefaultTransport，跳过 TLS 验证
+	http.DefaultTransport = &http.Transport{
+		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
 	}
-
-	c.JSON(http.StatusOK, gin.H{"message": "Post marked as read"})
-}
-
-func UpdateMemoID(postItemID, memoID string) {
-	logger.Println("UpdateMemoID: ", postItemID, memoID)
-	_, err := db.Exec("UPDATE shin_post_item SET memo_id = ? WHERE id = ?", memoID, postItemID)
-	if err != nil {
-		logger.Println("failed to update memo_id")
-		panic(err)
-	}
-}
-
-func getDetail(c *gin.Context) {
-	postID := c.Query("id")
-	logger.Println("getDetail: ", postID)
-	var post Post
-	err := db.QueryRow("SELECT id, title, created_at, read_at FROM shin_post WHERE id = ?", postID).Scan(&post.ID, &post.Title, &post.CreatedAt, &post.ReadAt)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Post not found"})
-		} else {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching post"})
-		}
-		return
-	}
-
-	content, _ := getGroupedPostItemsAsJSON(postID)
-	post.Content = content
-	c.JSON(http.StatusOK, post)
 }
 
-// pagePost handler
-func pagePost(c *gin.Context) {
-	pageNumber, _ := strconv.Atoi(c.Query("page_number"))
-	pageSize, _ := strconv.Atoi(c.Query("page_size"))
-
-	var posts []Post
-	var totalPosts int64
-
-	err := db.QueryRow("SELECT COUNT(*) FROM shin_post").Scan(&totalPosts)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error counting posts"})
-		return
-	}
-	totalPage := (totalPosts + int64(pageSize) - 1) / int64(pageSize)
-
-	rows, err := db.Query("SELECT id, title, created_at, read_at FROM shin_post ORDER BY created_at DESC LIMIT ? OFFSET ?", pageSize, (pageNumber-1)*pageSize)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching posts"})
-		return
-	}
-	defer rows.Close()
-
-	for rows.Next() {
-		var post Post
-		if err := rows.Scan(&post.ID, &post.Title, &post.CreatedAt, &post.ReadAt); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error scanning post"})
-			return
+func main() {
+	if len(os.Args) > 1 {
+		commands := map[string]func([]string) error{
+			"bench":  runBench,
+			"export": runExport,
+			"import": runImport,
+			"prune":  runPrune,
 		}
-		posts = append(posts, post)
-	}
-
-	c.JSON(http.StatusOK, gin.H{
-		"total_page": totalPage,
-		"data":       posts,
-	})
-}
-
-func searchPostItems(c *gin.Context) {
-	keyword := c.Query("keyword")
-	logger.Println("search keyword:", keyword)
-	query := fmt.Sprintf("SELECT id, post_id, feed_title, content, memo_id FROM shin_post_item WHERE content LIKE '%%%s%%'", keyword)
-	rows, err := db.Query(query)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to execute query"})
-		return
-	}
-	defer rows.Close()
-
-	var postItems []PostItem
-	for rows.Next() {
-		var item PostItem
-		if err := rows.Scan(&item.ID, &item.PostID, &item.FeedTitle, &item.Content, &item.MemoID); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to scan result"})
+		if command, ok := commands[os.Args[1]]; ok {
+			if err := command(os.Args[2:]); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
 			return
 		}
-		postItems = append(postItems, item)
-	}
-
-	if err := rows.Err(); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error after scanning results"})
-		return
-	}
-
-	c.JSON(http.StatusOK, postItems)
-}
-
-func getImportantFeeds(c *gin.Context) {
-	params := strings.Split(IMPORTANT_FEEDS, ",")
-	for i := range params {
-		params[i] = fmt.Sprintf("'%s'", strings.TrimSpace(params[i]))
 	}
 
-	query := fmt.Sprintf("SELECT id, post_id, feed_title, content, memo_id FROM shin_post_item WHERE feed_title IN (%s) ORDER BY id DESC LIMIT 1000", strings.Join(params, ","))
-
-	logger.Println("getImportantFeeds:", query)
-
-	rows, err := db.Query(query)
+	// Initialize the database
+	st, err := store.Open(DB_PATH)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to execute query"})
-		logger.Println("Failed to execute query:", err)
-		return
-	}
-	defer rows.Close()
-
-	var postItems []PostItem
-	for rows.Next() {
-		var item PostItem
-		if err := rows.Scan(&item.ID, &item.PostID, &item.FeedTitle, &item.Content, &item.MemoID); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to scan result"})
-			logger.Println("Failed to scan result:", err)
-			return
-		}
-		postItems = append(postItems, item)
-	}
-
-	if err := rows.Err(); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error after scanning results"})
-		return
-	}
-
-	c.JSON(http.StatusOK, postItems)
-}
-
-func processLogin(c *gin.Context) {
-	var input struct {
-		Token string `form:"token"`
-	}
-
-	if err := c.ShouldBind(&input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
+		panic(err)
 	}
 
-	logger.Printf("clientToken: %s envToken: %s", input.Token, authToken)
+	source := ingest.NewFreshRSS()
+	ingester := ingest.New(st, source, translate.NewGoogle())
+	web.Setup(st, source, ingester)
 
-	// 验证 token
-	if input.Token == authToken {
-		// 设置客户端的 token
-		c.SetCookie("token", input.Token, 3600*24*365, "/", "", false, true)
+	ingester.OnInsert(web.AfterInsert)
+	ingester.AfterRound(web.AfterRound)
+	go ingester.Run()
 
-		// 登录成功，重定向到首页
-		c.Redirect(http.StatusFound, "/home")
-	} else {
-		// token 不匹配，返回登录页面
-		c.HTML(http.StatusOK, "login.html", gin.H{
-			"error": "Invalid token",
-		})
+	if err := web.StartTasks(); err != nil {
+		panic(err)
 	}
-}
-
-func main() {
-
-	go AsyncTask()
-
-	r := gin.Default()
-
-	// Initialize the database
-	initDB()
-
-	// 应用认证中间件到所有路由
-	r.Use(authMiddleware)
-
-	// Serve static files (CSS, JS, images, etc.)
-	r.Static("/static", "./static")
-
-	r.LoadHTMLGlob("templates/*")
-
-	r.GET("/login_page", func(c *gin.Context) {
-		c.HTML(http.StatusOK, "login.html", gin.H{})
-	})
-
-	r.GET("/", func(c *gin.Context) {
-		c.HTML(http.StatusOK, "home.html", gin.H{})
-	})
-
-	r.GET("/home", func(c *gin.Context) {
-		c.HTML(http.StatusOK, "home.html", gin.H{})
-	})
-
-	r.GET("/detail", func(c *gin.Context) {
-		c.HTML(http.StatusOK, "detail.html", gin.H{})
-	})
-
-	r.GET("/tools", func(c *gin.Context) {
-		c.HTML(http.StatusOK, "tools.html", gin.H{})
-	})
 
-	// REST API routes
-	r.POST("/login", processLogin)
-	r.POST("/markRead", markRead)
-	r.GET("/pagePost", pagePost)
-	r.GET("/getDetail", getDetail)
-	r.POST("/createMemo", CreateMemo)
-	r.GET("/search", searchPostItems)
-	r.GET("/getImportant", getImportantFeeds)
-	r.Run(":8777")
+	web.Router().Run(":8777")
 }
@@ -0,0 +1,297 @@
+// 渲染单个条目，home.html 和 detail.html 共用
+function createPostItemElement(newsItem) {
+    const li = document.createElement('li');
+    const newsItemContent = JSON.parse(newsItem["content"])
+    li.innerText = newsItemContent.cnTitle + " ";
+
+    const memoID = newsItem["memo_id"];
+    li.setAttribute('post-item-id', newsItem["id"]); // TODO
+
+    // 创建链接
+    const link = document.createElement('a');
+    link.href = newsItemContent.link;
+    link.innerText = `${newsItemContent.title}`;
+    link.target = "_blank"; // 在新标签页打开链接
+    // 打开原文即标记为已读
+    link.addEventListener('click', () => {
+        if (!newsItem["read"]) {
+            postJSON('/markItemRead', { item_id: newsItem["id"] });
+            li.classList.add('item-read');
+        }
+    });
+    if (newsItem["read"]) {
+        li.classList.add('item-read');
+    }
+
+    li.appendChild(link);
+    li.append(" ");
+
+    // 阅读模式
+    const reader = document.createElement('a');
+    reader.href = `/reader?id=${newsItem["id"]}`;
+    reader.innerText = "📖";
+    reader.title = "Reader view";
+    li.appendChild(reader);
+    li.append(" ");
+
+    // 星标
+    const starButton = document.createElement('button');
+    starButton.innerText = newsItem["starred"] ? "★" : "☆";
+    starButton.title = newsItem["starred"] ? "Unstar" : "Star";
+    starButton.onclick = async function () {
+        const response = await postJSON('/starItem', { item_id: newsItem["id"], starred: !newsItem["starred"] });
+        if (!response.ok) {
+            const result = await response.json();
+            alert('Failed: ' + result.error);
+            return;
+        }
+        newsItem["starred"] = !newsItem["starred"];
+        starButton.innerText = newsItem["starred"] ? "★" : "☆";
+        starButton.title = newsItem["starred"] ? "Unstar" : "Star";
+    };
+    li.appendChild(starButton);
+
+    const button = document.createElement('button');
+    button.innerText = "💾";
+    button.onclick = async function () {
+        const sinks = await getSinks();
+        if (sinks.length === 0) {
+            alert('No save targets configured');
+            return;
+        }
+        if (sinks.length === 1) {
+            saveToSink(li.getAttribute('post-item-id'), sinks[0]);
+            return;
+        }
+        toggleSinkMenu(li, sinks);
+    };
+
+    li.appendChild(button);
+
+    // 添加笔记
+    const noteButton = document.createElement('button');
+    noteButton.innerText = "🗒";
+    noteButton.title = "Add note";
+    noteButton.onclick = () => toggleNoteEditor(li, '', body => postJSON('/createNote', { item_id: newsItem["id"], body: body }));
+    li.appendChild(noteButton);
+
+    // 添加标签
+    li.appendChild(createMemoButton("🏷", "Add tag", async () => {
+        const tag = prompt('Tag');
+        if (!tag) {
+            return null;
+        }
+        return postJSON('/addItemTag', { item_id: newsItem["id"], tag: tag });
+    }));
+
+    const pending = newsItem["pending"] || [];
+    if (pending.length > 0) {
+        const pendingIcon = document.createElement('span');
+        pendingIcon.textContent = ' ⏳';
+        pendingIcon.title = `Saving to ${pending.join(', ')}`;
+        li.appendChild(pendingIcon);
+    }
+    const saved = newsItem["saved"] || [];
+    if (memoID || saved.length > 0) {
+        const emojiIcon = document.createElement('span');
+        emojiIcon.textContent = ' ✅';
+        emojiIcon.title = saved.join(', ');
+        li.appendChild(emojiIcon);
+    }
+    if (memoID) {
+        li.append(" ");
+        li.appendChild(createMemoButton("💬", "Add comment to memo", async () => {
+            const comment = prompt('Comment');
+            if (!comment) {
+                return null;
+            }
+            return fetch('/updateMemo', {
+                method: 'POST',
+                headers: { 'Content-Type': 'application/json' },
+                body: JSON.stringify({ postItemID: newsItem["id"], comment: comment })
+            });
+        }));
+        li.appendChild(createMemoButton("🗑", "Delete memo", async () => {
+            if (!confirm('Delete this memo?')) {
+                return null;
+            }
+            return fetch('/deleteMemo', {
+                method: 'POST',
+                headers: { 'Content-Type': 'application/json' },
+                body: JSON.stringify({ postItemID: newsItem["id"] })
+            });
+        }));
+    }
+
+    const tags = newsItem["tags"] || [];
+    if (tags.length > 0) {
+        li.appendChild(createItemTagsElement(newsItem["id"], tags));
+    }
+
+    const notes = newsItem["notes"] || [];
+    if (notes.length > 0) {
+        const notesElement = document.createElement('div');
+        notesElement.className = 'notes';
+        notes.forEach(note => notesElement.appendChild(createNoteElement(newsItem["id"], note)));
+        li.appendChild(notesElement);
+    }
+    return li;
+}
+
+// 标签链接到 /tag/:name，× 移除标签
+function createItemTagsElement(postItemID, tags) {
+    const tagsElement = document.createElement('span');
+    tagsElement.className = 'item-tags';
+    tags.forEach(tag => {
+        tagsElement.append(" ");
+        const link = document.createElement('a');
+        link.href = `/tag/${encodeURIComponent(tag)}`;
+        link.innerText = `#${tag}`;
+        tagsElement.appendChild(link);
+
+        const remove = createMemoButton("×", "Remove tag", async () => {
+            return postJSON('/removeItemTag', { item_id: postItemID, tag: tag });
+        });
+        remove.className = 'tag-remove';
+        tagsElement.appendChild(remove);
+    });
+    return tagsElement;
+}
+
+function postJSON(url, data) {
+    return fetch(url, {
+        method: 'POST',
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify(data)
+    });
+}
+
+// 笔记正文按纯文本显示，保留换行
+function createNoteElement(postItemID, note) {
+    const noteElement = document.createElement('div');
+    noteElement.className = 'note';
+
+    const body = document.createElement('div');
+    body.className = 'note-body';
+    body.textContent = note.body;
+    noteElement.appendChild(body);
+
+    const editButton = document.createElement('button');
+    editButton.innerText = "✏️";
+    editButton.title = "Edit note";
+    editButton.onclick = () => toggleNoteEditor(noteElement, note.body, text => postJSON('/updateNote', { id: note.id, body: text }));
+    noteElement.appendChild(editButton);
+
+    noteElement.appendChild(createMemoButton("🗑", "Delete note", async () => {
+        if (!confirm('Delete this note?')) {
+            return null;
+        }
+        return postJSON('/deleteNote', { id: note.id });
+    }));
+    noteElement.appendChild(createMemoButton("📤", "Save note to memo", async () => {
+        return postJSON('/createMemo', { postItemID: postItemID, noteID: note.id });
+    }));
+    return noteElement;
+}
+
+// 在 parent 下方显示笔记编辑框，保存后刷新页面
+function toggleNoteEditor(parent, text, save) {
+    const existing = parent.querySelector(':scope > .note-editor');
+    if (existing) {
+        existing.remove();
+        return;
+    }
+
+    const editor = document.createElement('div');
+    editor.className = 'note-editor';
+    const textarea = document.createElement('textarea');
+    textarea.value = text;
+    textarea.placeholder = 'Markdown';
+    editor.appendChild(textarea);
+
+    const saveButton = document.createElement('button');
+    saveButton.innerText = "Save";
+    saveButton.onclick = async function () {
+        if (!textarea.value.trim()) {
+            return;
+        }
+        const response = await save(textarea.value);
+        if (response.ok) {
+            location.reload();
+        } else {
+            const result = await response.json();
+            alert('Failed: ' + result.error);
+        }
+    };
+    editor.appendChild(saveButton);
+    parent.appendChild(editor);
+    textarea.focus();
+}
+
+function createMemoButton(text, title, request) {
+    const button = document.createElement('button');
+    button.innerText = text;
+    button.title = title;
+    button.onclick = async function () {
+        const response = await request();
+        if (!response) {
+            return;
+        }
+        const result = await response.json();
+        if (response.ok) {
+            alert(result.message);
+            location.reload();
+        } else {
+            alert('Failed: ' + result.error);
+        }
+    };
+    return button;
+}
+
+let sinksPromise = null;
+
+// 已配置的保存目标，只请求一次
+function getSinks() {
+    if (!sinksPromise) {
+        sinksPromise = fetch('/getSinks').then(response => response.json());
+    }
+    return sinksPromise;
+}
+
+// 有多个保存目标时，在条目下方显示选择按钮
+function toggleSinkMenu(li, sinks) {
+    const existing = li.querySelector('.sink-menu');
+    if (existing) {
+        existing.remove();
+        return;
+    }
+
+    const menu = document.createElement('div');
+    menu.className = 'sink-menu';
+    sinks.forEach(sink => {
+        const sinkButton = document.createElement('button');
+        sinkButton.innerText = sink;
+        sinkButton.onclick = () => saveToSink(li.getAttribute('post-item-id'), sink);
+        menu.appendChild(sinkButton);
+    });
+    li.appendChild(menu);
+}
+
+async function saveToSink(postItemID, sink) {
+    const response = await fetch('/saveItem', {
+        method: 'POST',
+        headers: {
+            'Content-Type': 'application/json'
+        },
+        body: JSON.stringify({ postItemID: postItemID, sink: sink })
+    });
+
+    // 根据返回结果提示成功或失败
+    const result = await response.json();
+    if (response.ok) {
+        alert(result.message);
+        location.reload();
+    } else {
+        alert(`Failed to save to ${sink}: ` + result.error);
+    }
+}